@@ -1,15 +1,91 @@
 package database
 
 import (
+	"fmt"
 	"log"
+	"sort"
 	"flux/models"
+	"flux/utils"
+
+	"gorm.io/gorm"
 )
 
 // Migrate runs database migrations
 func Migrate() {
-	err := DB.AutoMigrate(&models.User{}, &models.Task{}, &models.PasswordReset{})
+	err := DB.AutoMigrate(&models.User{}, &models.Task{}, &models.PasswordReset{}, &models.Label{}, &models.TaskDependency{}, &models.Project{}, &models.Organization{}, &models.OrganizationMember{}, &models.Comment{}, &models.Mention{}, &models.Notification{}, &models.Attachment{}, &models.TaskEvent{}, &models.CalendarFeed{}, &models.TimeEntry{}, &models.CustomField{}, &models.TaskFieldValue{})
 	if err != nil {
 		log.Fatalf("Failed to migrate database: %v", err)
 	}
+	// creator_id 追加前のタスクは所有者を作成者とみなす
+	if err := DB.Model(&models.Task{}).Where("creator_id IS NULL OR creator_id = 0").Update("creator_id", gorm.Expr("user_id")).Error; err != nil {
+		log.Fatalf("Failed to backfill task creators: %v", err)
+	}
+	// 組織のラベル追加前の (user_id, name) の一意制約は、個人のラベルだけを対象にした制約に置き換える
+	if DB.Migrator().HasIndex(&models.Label{}, "idx_labels_user_name") {
+		if err := DB.Migrator().DropIndex(&models.Label{}, "idx_labels_user_name"); err != nil {
+			log.Fatalf("Failed to drop the old label index: %v", err)
+		}
+	}
+	if err := backfillTaskRanks(); err != nil {
+		log.Fatalf("Failed to backfill task ranks: %v", err)
+	}
 	log.Println("Database migration completed successfully")
 }
+
+// backfillTaskRanks 並び順の導入前に作られたランクの無いタスクがある列や、末尾に固定長の
+// ランクを追加する余地が無くなった列を、現在の並び（ランクの無いタスクは ID 順で末尾）の
+// まま等間隔の固定長ランクに振り直す。該当する列が無ければ何もしない
+func backfillTaskRanks() error {
+	var tasks []models.Task
+	err := DB.Unscoped().Select("id", "user_id", "organization_id", "project_id", "status", "rank").
+		Order("id").Find(&tasks).Error
+	if err != nil {
+		return err
+	}
+	var keys []string
+	boards := make(map[string][]models.Task)
+	for _, t := range tasks {
+		key := t.Status
+		if t.OrganizationID != nil {
+			key += fmt.Sprintf("/org:%d", *t.OrganizationID)
+		} else {
+			key += fmt.Sprintf("/user:%d", t.UserID)
+		}
+		if t.ProjectID != nil {
+			key += fmt.Sprintf("/project:%d", *t.ProjectID)
+		}
+		if _, ok := boards[key]; !ok {
+			keys = append(keys, key)
+		}
+		boards[key] = append(boards[key], t)
+	}
+
+	for _, key := range keys {
+		board := boards[key]
+		// ランクの無いタスクは ID 順のまま末尾に並べる
+		sort.SliceStable(board, func(i, j int) bool {
+			if (board[i].Rank == "") != (board[j].Rank == "") {
+				return board[j].Rank == ""
+			}
+			return board[i].Rank < board[j].Rank
+		})
+		last := board[len(board)-1].Rank
+		if last != "" {
+			if _, err := utils.RankAfter(last); err == nil {
+				continue
+			}
+		}
+		ranks := utils.RankSequence(len(board))
+		for i, t := range board {
+			if ranks[i] == t.Rank {
+				continue
+			}
+			err := DB.Unscoped().Model(&models.Task{}).Where("id = ?", t.ID).
+				UpdateColumns(map[string]interface{}{"rank": ranks[i], "version": gorm.Expr("version + 1")}).Error
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
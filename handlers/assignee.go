@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+
+	"flux/database"
+	"flux/middleware"
+	"flux/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// AssigneeIDsRequest 担当者追加リクエスト
+type AssigneeIDsRequest struct {
+	UserIDs []uint `json:"user_ids" binding:"required,min=1"`
+}
+
+// GetAssignedTasks retrieves the tasks assigned to the caller
+func GetAssignedTasks(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "認証が必要です"})
+		return
+	}
+
+	query, err := applyTaskFilters(c, database.DB.Model(&models.Task{}))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query = query.Where("id IN (SELECT task_id FROM task_assignees WHERE user_id = ?)", userID)
+	var tasks []models.Task
+	respondList(c, excludeArchivedProjects(c, visibleTasks(c, query)), taskListSpec, &tasks)
+}
+
+// AssignTask adds assignees to a task
+func AssignTask(c *gin.Context) {
+	task, ok := findAuthorizedTask(c, taskActionEdit)
+	if !ok {
+		return
+	}
+
+	var req AssigneeIDsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var users []models.User
+	if err := database.DB.Where("id IN ?", req.UserIDs).Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(users) != len(uniqueIDs(req.UserIDs)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+	// 組織のタスクはメンバーにしか割り当てられない
+	if task.OrganizationID != nil {
+		for _, u := range users {
+			if _, ok := findMember(database.DB, *task.OrganizationID, u.ID); !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "assignee must be a member of the organization"})
+				return
+			}
+		}
+	}
+
+	userID, _ := middleware.GetUserID(c)
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		return changeAssignees(tx, &task, userID, models.EventAssigned, func(a *gorm.Association) error {
+			return a.Append(&users)
+		})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respondTaskAssignees(c, task)
+}
+
+// UnassignTask removes an assignee from a task. Assignees may remove themselves.
+func UnassignTask(c *gin.Context) {
+	assigneeID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	action := taskActionEdit
+	callerID, _ := middleware.GetUserID(c)
+	if callerID == uint(assigneeID) {
+		action = taskActionView
+	}
+	task, ok := findAuthorizedTask(c, action)
+	if !ok {
+		return
+	}
+
+	user := models.User{ID: uint(assigneeID)}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		return changeAssignees(tx, &task, callerID, models.EventUnassigned, func(a *gorm.Association) error {
+			return a.Delete(&user)
+		})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respondTaskAssignees(c, task)
+}
+
+// changeAssignees applies change to the task's assignees and records the before/after
+// assignee IDs as a history event when they differ
+func changeAssignees(tx *gorm.DB, task *models.Task, actorID uint, eventType string, change func(*gorm.Association) error) error {
+	before, err := assigneeIDs(tx, task.ID)
+	if err != nil {
+		return err
+	}
+	if err := change(tx.Model(task).Association("Assignees")); err != nil {
+		return err
+	}
+	after, err := assigneeIDs(tx, task.ID)
+	if err != nil {
+		return err
+	}
+	// 人数が同じでも入れ替わっていれば変更として扱う
+	if slices.Equal(before, after) {
+		return nil
+	}
+	if err := bumpTaskVersion(tx, task.ID); err != nil {
+		return err
+	}
+	changes := map[string]models.FieldChange{"assignees": {From: before, To: after}}
+	return recordTaskEvent(tx, task.ID, actorID, eventType, changes)
+}
+
+func assigneeIDs(db *gorm.DB, taskID uint) ([]uint, error) {
+	ids := []uint{}
+	err := db.Table("task_assignees").Where("task_id = ?", taskID).Order("user_id").Pluck("user_id", &ids).Error
+	return ids, err
+}
+
+func respondTaskAssignees(c *gin.Context, task models.Task) {
+	if err := database.DB.Preload("Assignees").First(&task, task.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, task)
+}
+
+// isAssignee reports whether userID is assigned to the task
+func isAssignee(db *gorm.DB, taskID, userID uint) bool {
+	var count int64
+	db.Table("task_assignees").Where("task_id = ? AND user_id = ?", taskID, userID).Count(&count)
+	return count > 0
+}
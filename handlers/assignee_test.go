@@ -0,0 +1,122 @@
+package handlers
+
+import (
+    "encoding/json"
+    "net/http"
+    "strconv"
+    "testing"
+
+    "flux/database"
+    "flux/models"
+    "github.com/gin-gonic/gin"
+)
+
+func TestAssignees_StatusOnlyForAssignees(t *testing.T) {
+    setupTaskDB(t)
+    for i := 1; i <= 3; i++ {
+        database.DB.Create(&models.User{Name: "u" + strconv.Itoa(i), Email: "u" + strconv.Itoa(i) + "@example.com"})
+    }
+    task := models.Task{Title: "Hand off", UserID: 1, Status: models.StatusPending}
+    if err := database.DB.Create(&task).Error; err != nil { t.Fatal(err) }
+    if task.CreatorID != 1 { t.Fatalf("expected creator to default to owner, got %d", task.CreatorID) }
+    id := strconv.Itoa(int(task.ID))
+
+    // 他人は担当者を追加できない
+    w, c := performJSONRequest(AssignTask, http.MethodPost, AssigneeIDsRequest{UserIDs: []uint{2}})
+    c.Params = []gin.Param{{Key: "id", Value: id}}
+    c.Set("user_id", uint(3))
+    AssignTask(c)
+    if w.Code != http.StatusForbidden { t.Fatalf("expected 403, got %d", w.Code) }
+
+    w, c = performJSONRequest(AssignTask, http.MethodPost, AssigneeIDsRequest{UserIDs: []uint{2, 99}})
+    c.Params = []gin.Param{{Key: "id", Value: id}}
+    c.Set("user_id", uint(1))
+    AssignTask(c)
+    if w.Code != http.StatusBadRequest { t.Fatalf("expected 400 for unknown user, got %d", w.Code) }
+
+    w, c = performJSONRequest(AssignTask, http.MethodPost, AssigneeIDsRequest{UserIDs: []uint{2}})
+    c.Params = []gin.Param{{Key: "id", Value: id}}
+    c.Set("user_id", uint(1))
+    AssignTask(c)
+    if w.Code != http.StatusOK { t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String()) }
+    var got models.Task
+    if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil { t.Fatal(err) }
+    if len(got.Assignees) != 1 || got.Assignees[0].ID != 2 { t.Fatalf("unexpected assignees: %+v", got.Assignees) }
+
+    // 担当者はステータスを変更できるが、他の項目は変更できない
+    w, c = performJSONRequest(UpdateTask, http.MethodPut, models.Task{Title: "Hand off", Status: models.StatusInProgress})
+    c.Params = []gin.Param{{Key: "id", Value: id}}
+    c.Set("user_id", uint(2))
+    UpdateTask(c)
+    if w.Code != http.StatusOK { t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String()) }
+
+    w, c = performJSONRequest(UpdateTask, http.MethodPut, models.Task{Title: "Renamed", Status: models.StatusInProgress})
+    c.Params = []gin.Param{{Key: "id", Value: id}}
+    c.Set("user_id", uint(2))
+    UpdateTask(c)
+    if w.Code != http.StatusForbidden { t.Fatalf("expected 403, got %d", w.Code) }
+
+    w, c = performJSONRequest(DeleteTask, http.MethodDelete, nil)
+    c.Params = []gin.Param{{Key: "id", Value: id}}
+    c.Set("user_id", uint(2))
+    DeleteTask(c)
+    if w.Code != http.StatusForbidden { t.Fatalf("expected 403, got %d", w.Code) }
+
+    w, c = performJSONRequest(GetAssignedTasks, http.MethodGet, nil)
+    c.Set("user_id", uint(2))
+    GetAssignedTasks(c)
+    var res struct {
+        Data []models.Task `json:"data"`
+    }
+    if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil { t.Fatal(err) }
+    if len(res.Data) != 1 || res.Data[0].ID != task.ID { t.Fatalf("unexpected assigned tasks: %+v", res.Data) }
+
+    // 担当者は自分で担当を外せる
+    w, c = performJSONRequest(UnassignTask, http.MethodDelete, nil)
+    c.Params = []gin.Param{{Key: "id", Value: id}, {Key: "user_id", Value: "2"}}
+    c.Set("user_id", uint(2))
+    UnassignTask(c)
+    if w.Code != http.StatusOK { t.Fatalf("expected 200, got %d", w.Code) }
+    if isAssignee(database.DB, task.ID, 2) { t.Fatal("expected assignee to be removed") }
+}
+
+func TestAssignees_SwapRecordsHistoryAndBumpsVersion(t *testing.T) {
+    setupTaskDB(t)
+    for i := 1; i <= 3; i++ {
+        database.DB.Create(&models.User{Name: "u" + strconv.Itoa(i), Email: "swap" + strconv.Itoa(i) + "@example.com"})
+    }
+    task := models.Task{Title: "Swap", UserID: 1}
+    if err := database.DB.Create(&task).Error; err != nil { t.Fatal(err) }
+    if err := database.DB.Model(&task).Association("Assignees").Append(&models.User{ID: 2}); err != nil { t.Fatal(err) }
+    var before models.Task
+    database.DB.First(&before, task.ID)
+
+    // 担当者 2 を 3 に置き換える（人数は変わらない）
+    code, resp := runBulk(t, 1, BulkTaskRequest{Action: bulkReassign, IDs: []uint{task.ID}, AssigneeIDs: []uint{3}})
+    if code != http.StatusOK || resp.Succeeded != 1 { t.Fatalf("expected success, got %d %+v", code, resp) }
+    if isAssignee(database.DB, task.ID, 2) || !isAssignee(database.DB, task.ID, 3) { t.Fatal("expected assignee 2 to be replaced by 3") }
+
+    var after models.Task
+    database.DB.First(&after, task.ID)
+    if after.Version <= before.Version { t.Fatalf("expected version to be bumped, got %d -> %d", before.Version, after.Version) }
+    var count int64
+    database.DB.Model(&models.TaskEvent{}).Where("task_id = ? AND type = ?", task.ID, models.EventAssigned).Count(&count)
+    if count != 1 { t.Fatalf("expected one assigned event, got %d", count) }
+}
+
+func TestAssignees_OrganizationMembersOnly(t *testing.T) {
+    setupTaskDB(t)
+    for i := 1; i <= 3; i++ {
+        database.DB.Create(&models.User{Name: "u" + strconv.Itoa(i), Email: "u" + strconv.Itoa(i) + "@example.com"})
+    }
+    org := createOrganizationAs(t, 1, "Acme")
+    addMemberAs(t, 1, org.ID, 2, models.RoleMember)
+    task := models.Task{Title: "Shared", UserID: 1, OrganizationID: &org.ID}
+    if err := database.DB.Create(&task).Error; err != nil { t.Fatal(err) }
+
+    w, c := performJSONRequest(AssignTask, http.MethodPost, AssigneeIDsRequest{UserIDs: []uint{3}})
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(task.ID))}}
+    c.Set("user_id", uint(1))
+    AssignTask(c)
+    if w.Code != http.StatusBadRequest { t.Fatalf("expected 400, got %d", w.Code) }
+}
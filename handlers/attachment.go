@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"flux/database"
+	"flux/middleware"
+	"flux/models"
+	"flux/storage"
+	"flux/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// attachmentStorage 添付ファイルの保存先
+var attachmentStorage storage.Storage = storage.NewFSStorage("uploads")
+
+// defaultAttachmentTypes ATTACHMENT_ALLOWED_TYPES 未設定時に許可する MIME タイプ
+var defaultAttachmentTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp", "application/pdf", "text/plain"}
+
+// SetStorage sets the storage backend for attachments
+func SetStorage(s storage.Storage) {
+	attachmentStorage = s
+}
+
+// maxAttachmentSize アップロードできるファイルの最大サイズ（ATTACHMENT_MAX_SIZE_MB、既定10MB）
+func maxAttachmentSize() int64 {
+	return int64(utils.GetEnvInt("ATTACHMENT_MAX_SIZE_MB", 10)) << 20
+}
+
+// allowedAttachmentTypes 許可する MIME タイプ（ATTACHMENT_ALLOWED_TYPES、カンマ区切り）
+func allowedAttachmentTypes() map[string]bool {
+	types := defaultAttachmentTypes
+	if v := os.Getenv("ATTACHMENT_ALLOWED_TYPES"); v != "" {
+		types = strings.Split(v, ",")
+	}
+	allowed := make(map[string]bool, len(types))
+	for _, t := range types {
+		allowed[strings.TrimSpace(t)] = true
+	}
+	return allowed
+}
+
+// GetTaskAttachments lists the attachments of a task
+func GetTaskAttachments(c *gin.Context) {
+	task, ok := findVisibleTask(c)
+	if !ok {
+		return
+	}
+
+	attachments := []models.Attachment{}
+	if err := database.DB.Where("task_id = ?", task.ID).Order("id").Find(&attachments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, attachments)
+}
+
+// UploadTaskAttachment stores the multipart "file" field as an attachment. The type is
+// sniffed from the content rather than trusted from the client.
+func UploadTaskAttachment(c *gin.Context) {
+	task, ok := findAuthorizedTask(c, taskActionEdit)
+	if !ok {
+		return
+	}
+
+	// マルチパートのヘッダー分の余裕を持たせて本文の大きさを制限する
+	maxSize := maxAttachmentSize()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+1<<20)
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("file must be at most %d bytes", maxSize)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	if header.Size > maxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("file must be at most %d bytes", maxSize)})
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	head = head[:n]
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	if !allowedAttachmentTypes()[contentType] {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": fmt.Sprintf("file type %s is not allowed", contentType)})
+		return
+	}
+
+	userID, _ := middleware.GetUserID(c)
+	attachment := models.Attachment{
+		TaskID:      task.ID,
+		UserID:      userID,
+		FileName:    attachmentFileName(header.Filename),
+		ContentType: contentType,
+		StorageKey:  fmt.Sprintf("tasks/%d/%s", task.ID, utils.GenerateRandomString(32)),
+	}
+	if attachment.Size, err = attachmentStorage.Save(attachment.StorageKey, io.MultiReader(bytes.NewReader(head), file)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := database.DB.Create(&attachment).Error; err != nil {
+		attachmentStorage.Delete(attachment.StorageKey)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, attachment)
+}
+
+// DownloadTaskAttachment streams an attachment's content
+func DownloadTaskAttachment(c *gin.Context) {
+	task, ok := findVisibleTask(c)
+	if !ok {
+		return
+	}
+	attachment, ok := findTaskAttachment(c, task.ID)
+	if !ok {
+		return
+	}
+
+	r, err := attachmentStorage.Open(attachment.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer r.Close()
+
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, r, nil)
+}
+
+// DeleteTaskAttachment removes an attachment; its uploader or anyone who can edit the task may
+func DeleteTaskAttachment(c *gin.Context) {
+	task, ok := findAuthorizedTask(c, taskActionView)
+	if !ok {
+		return
+	}
+	attachment, ok := findTaskAttachment(c, task.ID)
+	if !ok {
+		return
+	}
+
+	userID, _ := middleware.GetUserID(c)
+	if attachment.UserID != userID && !canAccessTask(database.DB, &task, userID, taskActionEdit) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "権限がありません"})
+		return
+	}
+	if err := database.DB.Delete(&attachment).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	removeAttachmentFiles([]models.Attachment{attachment})
+	c.JSON(http.StatusOK, gin.H{"message": "Attachment deleted successfully"})
+}
+
+// findTaskAttachment loads the attachment in :attachment_id belonging to the task.
+// On failure the error response has already been written.
+func findTaskAttachment(c *gin.Context, taskID uint) (models.Attachment, bool) {
+	var attachment models.Attachment
+	if err := database.DB.Where("task_id = ?", taskID).First(&attachment, c.Param("attachment_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+		return attachment, false
+	}
+	return attachment, true
+}
+
+// deleteTaskAttachments removes the attachment rows of the given tasks inside tx and
+// returns them so the stored files can be removed once the transaction has committed
+func deleteTaskAttachments(tx *gorm.DB, taskIDs []uint) ([]models.Attachment, error) {
+	var attachments []models.Attachment
+	if err := tx.Where("task_id IN ?", taskIDs).Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+	if len(attachments) == 0 {
+		return nil, nil
+	}
+	ids := make([]uint, len(attachments))
+	for i, a := range attachments {
+		ids[i] = a.ID
+	}
+	return attachments, tx.Delete(&models.Attachment{}, ids).Error
+}
+
+// removeAttachmentFiles deletes stored files; failures only leave orphaned files, so they are logged
+func removeAttachmentFiles(attachments []models.Attachment) {
+	for _, a := range attachments {
+		if err := attachmentStorage.Delete(a.StorageKey); err != nil {
+			log.Printf("failed to delete attachment file %s: %v", a.StorageKey, err)
+		}
+	}
+}
+
+// attachmentFileName keeps only the base name of a client supplied file name
+func attachmentFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	if name == "." || name == "/" || name == "" {
+		return "file"
+	}
+	if len(name) > 255 {
+		// マルチバイト文字の途中で切らない
+		cut := 255
+		for cut > 0 && !utf8.RuneStart(name[cut]) {
+			cut--
+		}
+		name = name[:cut]
+	}
+	return name
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+    "bytes"
+    "encoding/json"
+    "mime/multipart"
+    "net/http"
+    "net/http/httptest"
+    "strconv"
+    "strings"
+    "testing"
+    "unicode/utf8"
+
+    "flux/database"
+    "flux/models"
+    "flux/storage"
+    "github.com/gin-gonic/gin"
+)
+
+// pngHeader http.DetectContentType が image/png と判定する最小限の先頭バイト
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func uploadAttachment(t *testing.T, userID uint, taskID uint, name string, content []byte) *httptest.ResponseRecorder {
+    t.Helper()
+    var buf bytes.Buffer
+    mw := multipart.NewWriter(&buf)
+    fw, err := mw.CreateFormFile("file", name)
+    if err != nil { t.Fatal(err) }
+    fw.Write(content)
+    mw.Close()
+
+    gin.SetMode(gin.TestMode)
+    w := httptest.NewRecorder()
+    c, _ := gin.CreateTestContext(w)
+    c.Request, _ = http.NewRequest(http.MethodPost, "/", &buf)
+    c.Request.Header.Set("Content-Type", mw.FormDataContentType())
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(taskID))}}
+    c.Set("user_id", userID)
+    UploadTaskAttachment(c)
+    return w
+}
+
+func setupAttachmentStorage(t *testing.T) {
+    t.Helper()
+    prev := attachmentStorage
+    SetStorage(storage.NewFSStorage(t.TempDir()))
+    t.Cleanup(func() { SetStorage(prev) })
+}
+
+func TestAttachments_UploadDownloadAndCleanup(t *testing.T) {
+    setupTaskDB(t)
+    setupAttachmentStorage(t)
+    task := models.Task{Title: "Bug", UserID: 1}
+    if err := database.DB.Create(&task).Error; err != nil { t.Fatal(err) }
+
+    // 拡張子ではなく内容で判定する
+    content := append(append([]byte{}, pngHeader...), []byte("rest of image")...)
+    w := uploadAttachment(t, 1, task.ID, "../../screenshot.txt", content)
+    if w.Code != http.StatusCreated { t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String()) }
+    var a models.Attachment
+    if err := json.Unmarshal(w.Body.Bytes(), &a); err != nil { t.Fatal(err) }
+    if a.ContentType != "image/png" || a.FileName != "screenshot.txt" || a.Size != int64(len(content)) {
+        t.Fatalf("unexpected attachment: %+v", a)
+    }
+    if err := database.DB.First(&a, a.ID).Error; err != nil { t.Fatal(err) }
+
+    w, c := performJSONRequest(DownloadTaskAttachment, http.MethodGet, nil)
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(task.ID))}, {Key: "attachment_id", Value: strconv.Itoa(int(a.ID))}}
+    DownloadTaskAttachment(c)
+    if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), content) { t.Fatalf("unexpected download: %d %q", w.Code, w.Body.String()) }
+    if ct := w.Header().Get("Content-Type"); ct != "image/png" { t.Fatalf("unexpected content type %q", ct) }
+
+    // 他人のタスクには添付できない
+    if w := uploadAttachment(t, 2, task.ID, "x.png", content); w.Code != http.StatusForbidden { t.Fatalf("expected 403, got %d", w.Code) }
+
+    // ゴミ箱に入れただけでは添付ファイルを残し、完全削除で消す
+    w, c = performJSONRequest(DeleteTask, http.MethodDelete, nil)
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(task.ID))}}
+    c.Set("user_id", uint(1))
+    DeleteTask(c)
+    if w.Code != http.StatusOK { t.Fatalf("expected 200, got %d", w.Code) }
+    if r, err := attachmentStorage.Open(a.StorageKey); err != nil { t.Fatalf("expected file to be kept in trash, got %v", err) } else { r.Close() }
+
+    w, c = performJSONRequest(PurgeTask, http.MethodDelete, nil)
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(task.ID))}}
+    c.Set("user_id", uint(1))
+    PurgeTask(c)
+    if w.Code != http.StatusOK { t.Fatalf("expected 200, got %d", w.Code) }
+    var count int64
+    database.DB.Model(&models.Attachment{}).Count(&count)
+    if count != 0 { t.Fatalf("expected attachments to be removed, got %d", count) }
+    if _, err := attachmentStorage.Open(a.StorageKey); err != storage.ErrNotFound { t.Fatalf("expected stored file to be removed, got %v", err) }
+}
+
+func TestAttachments_Limits(t *testing.T) {
+    setupTaskDB(t)
+    setupAttachmentStorage(t)
+    t.Setenv("ATTACHMENT_MAX_SIZE_MB", "1")
+    task := models.Task{Title: "Bug", UserID: 1}
+    if err := database.DB.Create(&task).Error; err != nil { t.Fatal(err) }
+
+    // 実行ファイルは拡張子を偽っても拒否する
+    if w := uploadAttachment(t, 1, task.ID, "image.png", []byte("MZ\x90\x00\x03\x00\x00\x00\x04\x00\x00\x00\xff\xff")); w.Code != http.StatusUnsupportedMediaType {
+        t.Fatalf("expected 415, got %d", w.Code)
+    }
+    big := append(append([]byte{}, pngHeader...), make([]byte, 1<<20)...)
+    if w := uploadAttachment(t, 1, task.ID, "big.png", big); w.Code != http.StatusRequestEntityTooLarge {
+        t.Fatalf("expected 413, got %d", w.Code)
+    }
+}
+
+func TestAttachmentFileName(t *testing.T) {
+    cases := []struct {
+        name string
+        want string
+    }{
+        {`C:\Users\me\report.pdf`, "report.pdf"},
+        {"../../etc/passwd", "passwd"},
+        {"", "file"},
+        {strings.Repeat("a", 300), strings.Repeat("a", 255)},
+        // 3バイトの文字は 255 バイトに収まる 85 文字で切る
+        {strings.Repeat("あ", 100), strings.Repeat("あ", 85)},
+        {"a" + strings.Repeat("あ", 100), "a" + strings.Repeat("あ", 84)},
+    }
+    for _, tc := range cases {
+        got := attachmentFileName(tc.name)
+        if got != tc.want || !utf8.ValidString(got) {
+            t.Errorf("attachmentFileName(%q) = %q, want %q", tc.name, got, tc.want)
+        }
+    }
+}
@@ -0,0 +1,310 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"flux/database"
+	"flux/middleware"
+	"flux/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 一括操作の種類
+const (
+	bulkUpdateStatus  = "update-status"
+	bulkDelete        = "delete"
+	bulkReassign      = "reassign"
+	bulkRelabel       = "relabel"
+	bulkMoveToProject = "move-to-project"
+)
+
+// bulkPermissions 一括操作ごとに必要なタスクへの権限
+var bulkPermissions = map[string]int{
+	bulkUpdateStatus:  taskActionStatus,
+	bulkDelete:        taskActionDelete,
+	bulkReassign:      taskActionEdit,
+	bulkRelabel:       taskActionEdit,
+	bulkMoveToProject: taskActionEdit,
+}
+
+// bulkMaxTasks 一度に操作できるタスクの上限
+const bulkMaxTasks = 500
+
+// BulkTaskRequest タスク一括操作リクエスト
+// 対象は ids か filter（GET /tasks と同じクエリパラメータ）のどちらか一方で指定する
+type BulkTaskRequest struct {
+	Action      string            `json:"action" binding:"required"`
+	IDs         []uint            `json:"ids"`
+	Filter      map[string]string `json:"filter"`
+	Atomic      bool              `json:"atomic"`       // true なら1件でも失敗すると全件ロールバック
+	Status      string            `json:"status"`       // update-status
+	AssigneeIDs []uint            `json:"assignee_ids"` // reassign（担当者を置き換える）
+	LabelIDs    []uint            `json:"label_ids"`    // relabel（ラベルを置き換える）
+	ProjectID   *uint             `json:"project_id"`   // move-to-project（null または 0 でプロジェクトから外す）
+}
+
+// BulkTaskResult 一括操作の各タスクの結果
+type BulkTaskResult struct {
+	ID    uint   `json:"id"`
+	OK    bool   `json:"ok"`
+	Code  int    `json:"code"`
+	Error string `json:"error,omitempty"`
+}
+
+// bulkItemError 一括操作で個別のタスクに返すエラー
+type bulkItemError struct {
+	code int
+	msg  string
+}
+
+func (e *bulkItemError) Error() string { return e.msg }
+
+// BulkTasks applies one action to many tasks in a single transaction. Each task is
+// checked and changed independently and reported in the results; with atomic=true
+// any failure rolls back the whole batch.
+func BulkTasks(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "認証が必要です"})
+		return
+	}
+
+	var req BulkTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	apply, err := bulkAction(c, &req, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ids, err := bulkTargetIDs(c, &req, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results := make([]BulkTaskResult, 0, len(ids))
+	failed := 0
+	errAtomic := errors.New("bulk operation rolled back")
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		for _, id := range ids {
+			// タスクごとにセーブポイントを作り、失敗したタスクの変更だけを取り消す
+			err := tx.Transaction(func(tx *gorm.DB) error {
+				var task models.Task
+				if err := tx.First(&task, id).Error; err != nil {
+					return &bulkItemError{code: http.StatusNotFound, msg: "Task not found"}
+				}
+				return apply(tx, &task)
+			})
+			result := BulkTaskResult{ID: id, OK: err == nil, Code: http.StatusOK}
+			if err != nil {
+				result.Code, result.Error = bulkErrorStatus(err), err.Error()
+				failed++
+			}
+			results = append(results, result)
+		}
+		if req.Atomic && failed > 0 {
+			return errAtomic
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errAtomic) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	status := http.StatusOK
+	if errors.Is(err, errAtomic) {
+		status = http.StatusUnprocessableEntity
+	}
+	c.JSON(status, gin.H{
+		"action":    req.Action,
+		"results":   results,
+		"succeeded": len(results) - failed,
+		"failed":    failed,
+	})
+}
+
+// bulkAction validates the action's parameters and returns the change to apply to each task
+func bulkAction(c *gin.Context, req *BulkTaskRequest, userID uint) (func(tx *gorm.DB, task *models.Task) error, error) {
+	// 各タスクの権限を確認してから変更する
+	authorized := func(action int, change func(tx *gorm.DB, task *models.Task) error) func(*gorm.DB, *models.Task) error {
+		return func(tx *gorm.DB, task *models.Task) error {
+			if !canAccessTask(tx, task, userID, action) {
+				if !canAccessTask(tx, task, userID, taskActionView) {
+					return &bulkItemError{code: http.StatusNotFound, msg: "Task not found"}
+				}
+				return &bulkItemError{code: http.StatusForbidden, msg: "権限がありません"}
+			}
+			return change(tx, task)
+		}
+	}
+
+	switch req.Action {
+	case bulkUpdateStatus:
+		if req.Status == "" {
+			return nil, errors.New("status is required")
+		}
+		force, cascade := c.Query("force") == "true", c.Query("cascade") == "true"
+		return authorized(taskActionStatus, func(tx *gorm.DB, task *models.Task) error {
+			before := *task
+			completing := req.Status == models.StatusCompleted && task.Status != models.StatusCompleted
+			starting := req.Status == models.StatusInProgress && task.Status != models.StatusInProgress
+			if err := task.SetStatus(req.Status, time.Now()); err != nil {
+				return err
+			}
+			if starting {
+				if err := checkNotBlocked(tx, task, force); err != nil {
+					return err
+				}
+			}
+			if completing {
+				if err := completeSubtasks(tx, task, cascade, time.Now()); err != nil {
+					return err
+				}
+				if err := spawnNextOccurrence(tx, task, time.Now()); err != nil {
+					return err
+				}
+			}
+			return saveBulkUpdate(tx, &before, task, userID)
+		}), nil
+
+	case bulkDelete:
+		return authorized(taskActionDelete, func(tx *gorm.DB, task *models.Task) error {
+			result := tx.Where("version = ?", task.Version).Delete(task)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return errVersionConflict
+			}
+			if err := bumpRelatedVersions(tx, nil, task); err != nil {
+				return err
+			}
+			return recordTaskEvent(tx, task.ID, userID, models.EventDeleted, nil)
+		}), nil
+
+	case bulkReassign:
+		var users []models.User
+		if err := database.DB.Where("id IN ?", req.AssigneeIDs).Find(&users).Error; err != nil {
+			return nil, err
+		}
+		if len(users) != len(uniqueIDs(req.AssigneeIDs)) {
+			return nil, errors.New("User not found")
+		}
+		return authorized(taskActionEdit, func(tx *gorm.DB, task *models.Task) error {
+			// 組織のタスクはメンバーにしか割り当てられない
+			if task.OrganizationID != nil {
+				for _, u := range users {
+					if _, ok := findMember(tx, *task.OrganizationID, u.ID); !ok {
+						return &bulkItemError{code: http.StatusBadRequest, msg: "assignee must be a member of the organization"}
+					}
+				}
+			}
+			return changeAssignees(tx, task, userID, models.EventAssigned, func(a *gorm.Association) error {
+				return a.Replace(&users)
+			})
+		}), nil
+
+	case bulkRelabel:
+		// 付与できるのは操作しているユーザー自身のラベルと、タスクと同じ組織のラベルのみ
+		var labels []models.Label
+		if err := usableLabels(database.DB, userID).Where("id IN ?", req.LabelIDs).Find(&labels).Error; err != nil {
+			return nil, err
+		}
+		if len(labels) != len(uniqueIDs(req.LabelIDs)) {
+			return nil, errors.New("Label not found")
+		}
+		return authorized(taskActionEdit, func(tx *gorm.DB, task *models.Task) error {
+			if err := checkLabelScope(labels, task); err != nil {
+				return &bulkItemError{code: http.StatusBadRequest, msg: err.Error()}
+			}
+			if err := tx.Model(task).Association("Labels").Replace(&labels); err != nil {
+				return err
+			}
+			return bumpTaskVersion(tx, task.ID)
+		}), nil
+
+	case bulkMoveToProject:
+		return authorized(taskActionEdit, func(tx *gorm.DB, task *models.Task) error {
+			before := *task
+			if req.ProjectID == nil || *req.ProjectID == 0 {
+				task.ProjectID = nil
+			} else if err := validateProject(tx, task, *req.ProjectID, userID); err != nil {
+				return err
+			} else {
+				task.ProjectID = req.ProjectID
+			}
+			return saveBulkUpdate(tx, &before, task, userID)
+		}), nil
+	}
+	return nil, errors.New("action must be one of update-status, delete, reassign, relabel, move-to-project")
+}
+
+// saveBulkUpdate saves a task changed by a bulk action and records the change in its history
+func saveBulkUpdate(tx *gorm.DB, before, task *models.Task, userID uint) error {
+	if err := saveTaskVersioned(tx, task); err != nil {
+		return err
+	}
+	if err := bumpRelatedVersions(tx, before, task); err != nil {
+		return err
+	}
+	return recordTaskUpdate(tx, before, task, userID)
+}
+
+// bulkTargetIDs resolves the tasks a bulk request targets, either the given IDs in
+// order or the tasks matching the filter that the caller may perform the action on
+func bulkTargetIDs(c *gin.Context, req *BulkTaskRequest, userID uint) ([]uint, error) {
+	if (len(req.IDs) > 0) == (req.Filter != nil) {
+		return nil, errors.New("specify either ids or filter")
+	}
+
+	var ids []uint
+	if len(req.IDs) > 0 {
+		ids = uniqueIDs(req.IDs)
+	} else {
+		fc := filterContext(c, req.Filter)
+		query, err := applyTaskFilters(fc, database.DB.Model(&models.Task{}))
+		if err != nil {
+			return nil, err
+		}
+		// フィルターで選ぶのは、操作する権限があるタスクだけ
+		query = excludeArchivedProjects(fc, actableTasks(query, userID, bulkPermissions[req.Action]))
+		if err := query.Order("id").Limit(bulkMaxTasks+1).Pluck("id", &ids).Error; err != nil {
+			return nil, err
+		}
+	}
+	if len(ids) > bulkMaxTasks {
+		return nil, errors.New("too many tasks; narrow the filter or split the request")
+	}
+	return ids, nil
+}
+
+// filterContext returns a copy of c whose query string is filter, so that the
+// list filters can be reused for a filter given in a request body
+func filterContext(c *gin.Context, filter map[string]string) *gin.Context {
+	values := url.Values{}
+	for k, v := range filter {
+		values.Set(k, v)
+	}
+	fc := c.Copy()
+	fc.Request = c.Request.Clone(c.Request.Context())
+	fc.Request.URL.RawQuery = values.Encode()
+	return fc
+}
+
+// bulkErrorStatus returns the per-task status code for an error from a bulk action
+func bulkErrorStatus(err error) int {
+	var ierr *bulkItemError
+	if errors.As(err, &ierr) {
+		return ierr.code
+	}
+	return taskErrorStatus(err)
+}
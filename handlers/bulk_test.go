@@ -0,0 +1,135 @@
+package handlers
+
+import (
+    "encoding/json"
+    "net/http"
+    "testing"
+
+    "flux/database"
+    "flux/models"
+)
+
+type bulkResponse struct {
+    Results   []BulkTaskResult `json:"results"`
+    Succeeded int              `json:"succeeded"`
+    Failed    int              `json:"failed"`
+}
+
+func runBulk(t *testing.T, userID uint, req BulkTaskRequest) (int, bulkResponse) {
+    t.Helper()
+    w, c := performJSONRequest(BulkTasks, http.MethodPost, req)
+    c.Set("user_id", userID)
+    BulkTasks(c)
+    var resp bulkResponse
+    if w.Code == http.StatusOK || w.Code == http.StatusUnprocessableEntity {
+        if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil { t.Fatal(err) }
+    }
+    return w.Code, resp
+}
+
+func TestBulkTasks_UpdateStatusReportsEachTask(t *testing.T) {
+    setupTaskDB(t)
+    a := models.Task{Title: "A", UserID: 1}
+    b := models.Task{Title: "B", UserID: 1}
+    other := models.Task{Title: "Other", UserID: 2}
+    for _, task := range []*models.Task{&a, &b, &other} {
+        if err := database.DB.Create(task).Error; err != nil { t.Fatal(err) }
+    }
+
+    code, resp := runBulk(t, 1, BulkTaskRequest{Action: bulkUpdateStatus, Status: models.StatusInProgress, IDs: []uint{a.ID, b.ID, other.ID, 999}})
+    if code != http.StatusOK { t.Fatalf("expected 200, got %d", code) }
+    if resp.Succeeded != 2 || resp.Failed != 2 { t.Fatalf("unexpected counts: %+v", resp) }
+    want := []int{http.StatusOK, http.StatusOK, http.StatusForbidden, http.StatusNotFound}
+    for i, r := range resp.Results {
+        if r.Code != want[i] { t.Fatalf("result %d: expected %d, got %+v", i, want[i], r) }
+    }
+
+    var saved models.Task
+    database.DB.First(&saved, b.ID)
+    if saved.Status != models.StatusInProgress || saved.Version != 2 { t.Fatalf("unexpected task: %s v%d", saved.Status, saved.Version) }
+    var untouched models.Task
+    database.DB.First(&untouched, other.ID)
+    if untouched.Status != models.StatusPending { t.Fatalf("other user's task changed: %s", untouched.Status) }
+    var events int64
+    database.DB.Model(&models.TaskEvent{}).Where("task_id = ? AND type = ?", a.ID, models.EventUpdated).Count(&events)
+    if events != 1 { t.Fatalf("expected an update event, got %d", events) }
+}
+
+func TestBulkTasks_AtomicRollsBack(t *testing.T) {
+    setupTaskDB(t)
+    a := models.Task{Title: "A", UserID: 1}
+    other := models.Task{Title: "Other", UserID: 2}
+    database.DB.Create(&a)
+    database.DB.Create(&other)
+
+    code, resp := runBulk(t, 1, BulkTaskRequest{Action: bulkDelete, Atomic: true, IDs: []uint{a.ID, other.ID}})
+    if code != http.StatusUnprocessableEntity || resp.Failed != 1 { t.Fatalf("expected 422 with one failure, got %d %+v", code, resp) }
+    var count int64
+    database.DB.Model(&models.Task{}).Count(&count)
+    if count != 2 { t.Fatalf("expected nothing deleted, got %d tasks", count) }
+}
+
+func TestBulkTasks_FilterRelabelAndMove(t *testing.T) {
+    setupTaskDB(t)
+    done := models.Task{Title: "Done", UserID: 1, Status: models.StatusCompleted}
+    open := models.Task{Title: "Open", UserID: 1}
+    database.DB.Create(&done)
+    database.DB.Create(&open)
+    label := models.Label{Name: "sprint-1", UserID: 1}
+    database.DB.Create(&label)
+    project := models.Project{Name: "Archive", OwnerID: 1}
+    database.DB.Create(&project)
+
+    filter := map[string]string{"status": models.StatusCompleted, "user_id": "1"}
+    if code, resp := runBulk(t, 1, BulkTaskRequest{Action: bulkRelabel, Filter: filter, LabelIDs: []uint{label.ID}}); code != http.StatusOK || resp.Succeeded != 1 || resp.Results[0].ID != done.ID {
+        t.Fatalf("unexpected relabel result: %d %+v", code, resp)
+    }
+    if code, resp := runBulk(t, 1, BulkTaskRequest{Action: bulkMoveToProject, Filter: filter, ProjectID: &project.ID}); code != http.StatusOK || resp.Succeeded != 1 {
+        t.Fatalf("unexpected move result: %d %+v", code, resp)
+    }
+
+    var saved models.Task
+    database.DB.Preload("Labels").First(&saved, done.ID)
+    if len(saved.Labels) != 1 || saved.ProjectID == nil || *saved.ProjectID != project.ID { t.Fatalf("unexpected task: %+v", saved) }
+    var untouched models.Task
+    database.DB.Preload("Labels").First(&untouched, open.ID)
+    if len(untouched.Labels) != 0 || untouched.ProjectID != nil { t.Fatalf("filtered-out task changed: %+v", untouched) }
+
+    // ids と filter の両方、または不明な操作は 400
+    if code, _ := runBulk(t, 1, BulkTaskRequest{Action: bulkDelete, IDs: []uint{open.ID}, Filter: filter}); code != http.StatusBadRequest { t.Fatalf("expected 400, got %d", code) }
+    if code, _ := runBulk(t, 1, BulkTaskRequest{Action: "archive", IDs: []uint{open.ID}}); code != http.StatusBadRequest { t.Fatalf("expected 400, got %d", code) }
+}
+
+func TestBulkTasks_FilterSkipsTasksTheCallerCannotChange(t *testing.T) {
+    setupTaskDB(t)
+    org := models.Organization{Name: "Acme"}
+    database.DB.Create(&org)
+    database.DB.Create(&models.OrganizationMember{OrganizationID: org.ID, UserID: 1, Role: models.RoleMember})
+    database.DB.Create(&models.OrganizationMember{OrganizationID: org.ID, UserID: 2, Role: models.RoleOwner})
+
+    mine := models.Task{Title: "Mine", UserID: 1, CreatorID: 1, Status: models.StatusInProgress}
+    theirs := models.Task{Title: "Theirs", UserID: 2, CreatorID: 2, Status: models.StatusInProgress}
+    shared := models.Task{Title: "Shared", UserID: 2, CreatorID: 2, OrganizationID: &org.ID, Status: models.StatusInProgress}
+    assigned := models.Task{Title: "Assigned", UserID: 2, CreatorID: 2, Status: models.StatusInProgress}
+    for _, task := range []*models.Task{&mine, &theirs, &shared, &assigned} { database.DB.Create(task) }
+    database.DB.Exec("INSERT INTO task_assignees (task_id, user_id) VALUES (?, ?)", assigned.ID, 1)
+
+    ids := func(resp bulkResponse) []uint {
+        var out []uint
+        for _, r := range resp.Results { out = append(out, r.ID) }
+        return out
+    }
+    filter := map[string]string{"status": models.StatusInProgress}
+
+    // 他人の個人タスクは対象にならず、atomic でも成功する
+    code, resp := runBulk(t, 1, BulkTaskRequest{Action: bulkUpdateStatus, Filter: filter, Status: models.StatusPending, Atomic: true})
+    if code != http.StatusOK || resp.Failed != 0 || len(resp.Results) != 3 { t.Fatalf("unexpected status result: %d %+v", code, resp) }
+    for _, id := range ids(resp) {
+        if id == theirs.ID { t.Fatalf("another user's task was targeted: %+v", resp) }
+    }
+
+    // 削除は作成者か管理者のタスクだけ
+    filter = map[string]string{"status": models.StatusPending}
+    code, resp = runBulk(t, 1, BulkTaskRequest{Action: bulkDelete, Filter: filter, Atomic: true})
+    if code != http.StatusOK || len(resp.Results) != 1 || resp.Results[0].ID != mine.ID { t.Fatalf("unexpected delete result: %d %+v", code, resp) }
+}
@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"flux/database"
+	"flux/middleware"
+	"flux/models"
+	"flux/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// calendarFeedPath フィードの URL のパス（末尾にトークンと .ics が付く）
+const calendarFeedPath = "/api/v1/calendar/"
+
+// calendarTokenLength フィードのトークンの長さ
+const calendarTokenLength = 40
+
+// calendarComponents type パラメータごとのタスクの出力形式
+var calendarComponents = map[string]string{
+	"todo":  "VTODO",
+	"event": "VEVENT",
+}
+
+// calendarPriorities 優先度を iCalendar の PRIORITY（1 が最も高い）に変換する
+var calendarPriorities = map[models.Priority]string{
+	models.PriorityUrgent: "1",
+	models.PriorityHigh:   "3",
+	models.PriorityMedium: "5",
+	models.PriorityLow:    "9",
+}
+
+// calendarTodoStatuses ステータスを VTODO の STATUS に変換する
+var calendarTodoStatuses = map[string]string{
+	models.StatusPending:    "NEEDS-ACTION",
+	models.StatusInProgress: "IN-PROCESS",
+	models.StatusCompleted:  "COMPLETED",
+}
+
+// GetCalendarFeed returns the caller's calendar feed URLs, creating the secret token on first use
+func GetCalendarFeed(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "認証が必要です"})
+		return
+	}
+	var feed models.CalendarFeed
+	err := database.DB.Where(models.CalendarFeed{UserID: userID}).
+		Attrs(models.CalendarFeed{Token: utils.GenerateRandomString(calendarTokenLength)}).
+		FirstOrCreate(&feed).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create calendar feed"})
+		return
+	}
+	respondCalendarFeed(c, &feed)
+}
+
+// RegenerateCalendarFeed replaces the caller's feed token so that previously shared URLs stop working
+func RegenerateCalendarFeed(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "認証が必要です"})
+		return
+	}
+	var feed models.CalendarFeed
+	err := database.DB.Where(models.CalendarFeed{UserID: userID}).
+		Assign(models.CalendarFeed{Token: utils.GenerateRandomString(calendarTokenLength)}).
+		FirstOrCreate(&feed).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to regenerate calendar feed"})
+		return
+	}
+	respondCalendarFeed(c, &feed)
+}
+
+// ServeCalendarFeed serves the tasks with a due date as an iCalendar feed. The
+// token in the URL is the only credential, so calendar apps can subscribe to it.
+func ServeCalendarFeed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+	var feed models.CalendarFeed
+	var user models.User
+	if token == "" || database.DB.Where("token = ?", token).First(&feed).Error != nil ||
+		database.DB.First(&user, feed.UserID).Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "calendar feed not found"})
+		return
+	}
+	component, ok := calendarComponents[c.DefaultQuery("type", "todo")]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type must be todo or event"})
+		return
+	}
+
+	tasks, err := calendarTasks(c, database.DB, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load tasks"})
+		return
+	}
+
+	var buf bytes.Buffer
+	iw := utils.NewICalWriter(&buf)
+	iw.Line("BEGIN", "VCALENDAR")
+	iw.Line("VERSION", "2.0")
+	iw.Line("PRODID", "-//Flux//Tasks//EN")
+	iw.Line("CALSCALE", "GREGORIAN")
+	iw.Line("METHOD", "PUBLISH")
+	iw.Text("X-WR-CALNAME", "Flux: "+user.Name)
+	for i := range tasks {
+		writeCalendarTask(iw, component, &tasks[i].task, tasks[i].rrule)
+	}
+	iw.Line("END", "VCALENDAR")
+	if err := iw.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to render calendar feed"})
+		return
+	}
+
+	c.Header("Content-Disposition", `inline; filename="tasks.ics"`)
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", buf.Bytes())
+}
+
+func respondCalendarFeed(c *gin.Context, feed *models.CalendarFeed) {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	url := scheme + "://" + c.Request.Host + calendarFeedPath + feed.Token + ".ics"
+	c.JSON(http.StatusOK, gin.H{
+		"url":        url,
+		"events_url": url + "?type=event",
+		"updated_at": feed.UpdatedAt,
+	})
+}
+
+// calendarTask フィードに出すタスクと、付ける RRULE（単発なら空文字）
+type calendarTask struct {
+	task  models.Task
+	rrule string
+}
+
+// calendarTasks returns the user's own and assigned tasks that have a due date.
+// In a recurring series only the latest open occurrence carries the RRULE, so
+// calendar apps expand the future occurrences once while past ones stay single entries.
+func calendarTasks(c *gin.Context, db *gorm.DB, userID uint) ([]calendarTask, error) {
+	query := db.Model(&models.Task{}).Preload("Labels").
+		Where("due_at IS NOT NULL").
+		Where("(user_id = ? OR id IN (SELECT task_id FROM task_assignees WHERE user_id = ?))", userID, userID).
+		Where("(organization_id IS NULL OR organization_id IN (SELECT organization_id FROM organization_members WHERE user_id = ?))", userID)
+	var tasks []models.Task
+	if err := excludeArchivedProjects(c, query).Order("due_at, id").Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+
+	// シリーズごとに最新の発生を探す（最初の発生はシリーズ ID が未設定のことがある）
+	seriesKey := func(t *models.Task) uint {
+		if t.SeriesID != nil {
+			return *t.SeriesID
+		}
+		return t.ID
+	}
+	latest := map[uint]int{}
+	for i := range tasks {
+		if t := &tasks[i]; t.Recurrence != "" && t.OccurrenceIndex > latest[seriesKey(t)] {
+			latest[seriesKey(t)] = t.OccurrenceIndex
+		}
+	}
+
+	out := make([]calendarTask, len(tasks))
+	for i := range tasks {
+		t := &tasks[i]
+		out[i].task = *t
+		if t.Recurrence != "" && t.Status != models.StatusCompleted && t.OccurrenceIndex == latest[seriesKey(t)] {
+			out[i].rrule = calendarRRule(t)
+		}
+	}
+	return out, nil
+}
+
+// calendarRRule returns the task's recurrence rule starting from this occurrence
+func calendarRRule(task *models.Task) string {
+	rule, err := utils.ParseRRule(task.Recurrence)
+	if err != nil {
+		return ""
+	}
+	if rule.Count > 0 {
+		// COUNT はシリーズ全体の回数なので、この発生以降の残りの回数にする
+		rule.Count -= task.OccurrenceIndex - 1
+		if rule.Count < 1 {
+			return ""
+		}
+	}
+	return rule.String()
+}
+
+func writeCalendarTask(iw *utils.ICalWriter, component string, task *models.Task, rrule string) {
+	iw.Line("BEGIN", component)
+	iw.Line("UID", "task-"+strconv.Itoa(int(task.ID))+"@flux")
+	iw.Time("DTSTAMP", task.UpdatedAt)
+	iw.Time("CREATED", task.CreatedAt)
+	iw.Time("LAST-MODIFIED", task.UpdatedAt)
+	iw.Line("SEQUENCE", strconv.Itoa(task.Version-1))
+	iw.Text("SUMMARY", task.Title)
+	if task.Description != "" {
+		iw.Text("DESCRIPTION", task.Description)
+	}
+	if len(task.Labels) > 0 {
+		names := make([]string, len(task.Labels))
+		for i, l := range task.Labels {
+			names[i] = utils.EscapeICalText(l.Name)
+		}
+		iw.Line("CATEGORIES", strings.Join(names, ","))
+	}
+	if p, ok := calendarPriorities[task.Priority]; ok {
+		iw.Line("PRIORITY", p)
+	}
+
+	start := task.DueAt
+	if task.StartAt != nil {
+		start = task.StartAt
+	}
+	if component == "VEVENT" {
+		// 開始日時が無ければ期限の時点の予定にする
+		iw.Time("DTSTART", *start)
+		if task.StartAt != nil {
+			iw.Time("DTEND", *task.DueAt)
+		}
+	} else {
+		// RRULE には DTSTART が必要なので、開始日時が無い場合は期限を使う
+		if task.StartAt != nil || rrule != "" {
+			iw.Time("DTSTART", *start)
+		}
+		iw.Time("DUE", *task.DueAt)
+		if status, ok := calendarTodoStatuses[task.Status]; ok {
+			iw.Line("STATUS", status)
+		}
+		if task.Status == models.StatusCompleted && task.CompletedAt != nil {
+			iw.Time("COMPLETED", *task.CompletedAt)
+		}
+	}
+	if rrule != "" {
+		iw.Line("RRULE", rrule)
+	}
+	iw.Line("END", component)
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "flux/database"
+    "flux/models"
+
+    "github.com/gin-gonic/gin"
+)
+
+func calendarFeedURL(t *testing.T, h gin.HandlerFunc, userID uint) string {
+    t.Helper()
+    w, c := performJSONRequest(h, http.MethodGet, nil)
+    c.Request.Host = "flux.example.com"
+    c.Set("user_id", userID)
+    h(c)
+    if w.Code != http.StatusOK { t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String()) }
+    var resp struct {
+        URL       string `json:"url"`
+        EventsURL string `json:"events_url"`
+    }
+    if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil { t.Fatal(err) }
+    if resp.EventsURL != resp.URL+"?type=event" { t.Fatalf("unexpected events url: %s", resp.EventsURL) }
+    return resp.URL
+}
+
+func serveCalendarFeed(url, query string) *httptest.ResponseRecorder {
+    w, c := performJSONRequest(ServeCalendarFeed, http.MethodGet, nil)
+    c.Request.URL.RawQuery = query
+    c.Params = []gin.Param{{Key: "token", Value: url[strings.LastIndex(url, "/")+1:]}}
+    ServeCalendarFeed(c)
+    return w
+}
+
+func TestCalendarFeed_TokenAndRegeneration(t *testing.T) {
+    setupTaskDB(t)
+    u := models.User{Name: "U", Email: "u@example.com", Password: "Password1!"}
+    database.DB.Create(&u)
+
+    url := calendarFeedURL(t, GetCalendarFeed, u.ID)
+    if !strings.HasPrefix(url, "http://flux.example.com/api/v1/calendar/") || !strings.HasSuffix(url, ".ics") { t.Fatalf("unexpected url: %s", url) }
+    if again := calendarFeedURL(t, GetCalendarFeed, u.ID); again != url { t.Fatalf("expected a stable url, got %s and %s", url, again) }
+    if w := serveCalendarFeed(url, ""); w.Code != http.StatusOK { t.Fatalf("expected 200, got %d", w.Code) }
+
+    // 再発行すると古い URL は使えなくなる
+    renewed := calendarFeedURL(t, RegenerateCalendarFeed, u.ID)
+    if renewed == url { t.Fatal("expected a new url") }
+    if w := serveCalendarFeed(url, ""); w.Code != http.StatusNotFound { t.Fatalf("expected 404 for the revoked url, got %d", w.Code) }
+    if w := serveCalendarFeed(renewed, ""); w.Code != http.StatusOK { t.Fatalf("expected 200, got %d", w.Code) }
+    if w := serveCalendarFeed(renewed, "type=agenda"); w.Code != http.StatusBadRequest { t.Fatalf("expected 400, got %d", w.Code) }
+}
+
+func TestCalendarFeed_Entries(t *testing.T) {
+    setupTaskDB(t)
+    u := models.User{Name: "U", Email: "u@example.com", Password: "Password1!"}
+    other := models.User{Name: "O", Email: "o@example.com", Password: "Password1!"}
+    database.DB.Create(&u)
+    database.DB.Create(&other)
+
+    due := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+    start := due.Add(-2 * time.Hour)
+    label := models.Label{Name: "billing", UserID: u.ID}
+    database.DB.Create(&label)
+    mine := models.Task{Title: "Invoice, March", Description: "line1\nline2", UserID: u.ID, DueAt: &due, StartAt: &start, Priority: models.PriorityHigh, Labels: []models.Label{label}}
+    database.DB.Create(&mine)
+    undated := models.Task{Title: "Someday", UserID: u.ID}
+    database.DB.Create(&undated)
+    assigned := models.Task{Title: "Review", UserID: other.ID, DueAt: &due, Assignees: []models.User{u}}
+    database.DB.Create(&assigned)
+    foreign := models.Task{Title: "Not mine", UserID: other.ID, DueAt: &due}
+    database.DB.Create(&foreign)
+
+    // 完了済みの1回目は単発、未完了の2回目が残りの回数の RRULE を持つ
+    completedAt := due.AddDate(0, 0, -7)
+    first := models.Task{Title: "Standup", UserID: u.ID, DueAt: &completedAt, Recurrence: "FREQ=WEEKLY;COUNT=5", Status: models.StatusCompleted, CompletedAt: &completedAt}
+    database.DB.Create(&first)
+    second := models.Task{Title: "Standup", UserID: u.ID, DueAt: &due, Recurrence: "FREQ=WEEKLY;COUNT=5", SeriesID: &first.ID, OccurrenceIndex: 2}
+    database.DB.Create(&second)
+    database.DB.Model(&first).Update("series_id", first.ID)
+
+    url := calendarFeedURL(t, GetCalendarFeed, u.ID)
+    w := serveCalendarFeed(url, "")
+    if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/calendar") { t.Fatalf("unexpected content type: %s", ct) }
+    body := w.Body.String()
+    if !strings.HasPrefix(body, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n") || !strings.HasSuffix(body, "END:VCALENDAR\r\n") { t.Fatalf("unexpected calendar: %q", body) }
+    if n := strings.Count(body, "BEGIN:VTODO"); n != 4 { t.Fatalf("expected 4 todos, got %d: %s", n, body) }
+    for _, want := range []string{
+        "SUMMARY:Invoice\\, March", "DESCRIPTION:line1\\nline2", "CATEGORIES:billing", "PRIORITY:3",
+        "DTSTART:20260302T070000Z", "DUE:20260302T090000Z", "STATUS:NEEDS-ACTION",
+        "SUMMARY:Review", "STATUS:COMPLETED\r\nCOMPLETED:20260223T090000Z", "RRULE:FREQ=WEEKLY;COUNT=4",
+    } {
+        if !strings.Contains(body, want) { t.Fatalf("missing %q in %s", want, body) }
+    }
+    if strings.Contains(body, "Someday") || strings.Contains(body, "Not mine") { t.Fatalf("unexpected tasks in feed: %s", body) }
+    if strings.Count(body, "RRULE:") != 1 { t.Fatalf("expected only the open occurrence to recur: %s", body) }
+
+    events := serveCalendarFeed(url, "type=event").Body.String()
+    if strings.Contains(events, "VTODO") || strings.Count(events, "BEGIN:VEVENT") != 4 { t.Fatalf("unexpected events: %s", events) }
+    if !strings.Contains(events, "DTSTART:20260302T070000Z\r\nDTEND:20260302T090000Z") { t.Fatalf("expected the start and due as the event span: %s", events) }
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"flux/database"
+	"flux/middleware"
+	"flux/models"
+	"flux/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CommentRequest コメント投稿・編集リクエスト
+type CommentRequest struct {
+	Body string `json:"body" binding:"required,max=10000"`
+}
+
+var commentListSpec = listSpec{
+	sortFields: map[string]string{
+		"id":         "id",
+		"created_at": "created_at",
+	},
+	defaultSort: "id",
+	preloads:    []string{"User"},
+}
+
+// commentEditWindow 投稿後にコメントを編集できる期間（COMMENT_EDIT_WINDOW_MINUTES、既定15分）
+func commentEditWindow() time.Duration {
+	return time.Duration(utils.GetEnvInt("COMMENT_EDIT_WINDOW_MINUTES", 15)) * time.Minute
+}
+
+// GetTaskComments retrieves the comments of a task, oldest first
+func GetTaskComments(c *gin.Context) {
+	task, ok := findVisibleTask(c)
+	if !ok {
+		return
+	}
+
+	var comments []models.Comment
+	respondList(c, database.DB.Model(&models.Comment{}).Where("task_id = ?", task.ID), commentListSpec, &comments)
+}
+
+// CreateTaskComment adds a comment to a task the caller can see
+func CreateTaskComment(c *gin.Context) {
+	task, ok := findAuthorizedTask(c, taskActionView)
+	if !ok {
+		return
+	}
+
+	var req CommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	body := strings.TrimSpace(req.Body)
+	if body == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "body must not be empty"})
+		return
+	}
+
+	userID, _ := middleware.GetUserID(c)
+	comment := models.Comment{TaskID: task.ID, UserID: userID, Body: body}
+	var notifications []models.Notification
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&comment).Error; err != nil {
+			return err
+		}
+		event := models.TaskEvent{TaskID: task.ID, ActorID: &userID, Type: models.EventCommented, CommentID: &comment.ID}
+		if err := tx.Create(&event).Error; err != nil {
+			return err
+		}
+		// コメント数が変わる
+		if err := bumpTaskVersion(tx, task.ID); err != nil {
+			return err
+		}
+		var err error
+		notifications, err = recordMentions(tx, &task, &comment.ID, userID, comment.Body)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	sendMentionEmails(&task, notifications)
+	respondComment(c, http.StatusCreated, comment)
+}
+
+// UpdateTaskComment edits a comment; only its author may, within the edit window
+func UpdateTaskComment(c *gin.Context) {
+	task, comment, ok := findTaskComment(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := middleware.GetUserID(c)
+	if comment.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "権限がありません"})
+		return
+	}
+	now := time.Now()
+	if now.Sub(comment.CreatedAt) > commentEditWindow() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "edit window has expired"})
+		return
+	}
+
+	var req CommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	body := strings.TrimSpace(req.Body)
+	if body == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "body must not be empty"})
+		return
+	}
+
+	comment.Body, comment.EditedAt = body, &now
+	var notifications []models.Notification
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&comment).Select("body", "edited_at").Updates(&comment).Error; err != nil {
+			return err
+		}
+		var err error
+		notifications, err = recordMentions(tx, &task, &comment.ID, userID, comment.Body)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	sendMentionEmails(&task, notifications)
+	respondComment(c, http.StatusOK, comment)
+}
+
+// DeleteTaskComment soft deletes a comment; its author or anyone who may delete the task can
+func DeleteTaskComment(c *gin.Context) {
+	task, comment, ok := findTaskComment(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := middleware.GetUserID(c)
+	if comment.UserID != userID && !canAccessTask(database.DB, &task, userID, taskActionDelete) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "権限がありません"})
+		return
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&comment).Error; err != nil {
+			return err
+		}
+		return bumpTaskVersion(tx, task.ID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted successfully"})
+}
+
+// findTaskComment loads the task in :id and its comment in :comment_id for an
+// authenticated caller who can see the task. On failure the error response has already been written.
+func findTaskComment(c *gin.Context) (models.Task, models.Comment, bool) {
+	var comment models.Comment
+	task, ok := findAuthorizedTask(c, taskActionView)
+	if !ok {
+		return task, comment, false
+	}
+	if err := database.DB.Where("task_id = ?", task.ID).First(&comment, c.Param("comment_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return task, comment, false
+	}
+	return task, comment, true
+}
+
+func respondComment(c *gin.Context, status int, comment models.Comment) {
+	if err := database.DB.Preload("User").First(&comment, comment.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(status, comment)
+}
+
+// commentCounts returns the number of live comments per task
+func commentCounts(db *gorm.DB, taskIDs []uint) (map[uint]int64, error) {
+	var rows []struct {
+		TaskID uint
+		Count  int64
+	}
+	err := db.Model(&models.Comment{}).Select("task_id, COUNT(*) AS count").
+		Where("task_id IN ?", taskIDs).Group("task_id").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[uint]int64, len(rows))
+	for _, r := range rows {
+		counts[r.TaskID] = r.Count
+	}
+	return counts, nil
+}
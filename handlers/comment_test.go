@@ -0,0 +1,95 @@
+package handlers
+
+import (
+    "encoding/json"
+    "net/http"
+    "strconv"
+    "testing"
+    "time"
+
+    "flux/database"
+    "flux/models"
+    "github.com/gin-gonic/gin"
+)
+
+func postComment(t *testing.T, userID uint, taskID uint, body string) models.Comment {
+    t.Helper()
+    w, c := performJSONRequest(CreateTaskComment, http.MethodPost, CommentRequest{Body: body})
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(taskID))}}
+    c.Set("user_id", userID)
+    CreateTaskComment(c)
+    if w.Code != http.StatusCreated { t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String()) }
+    var comment models.Comment
+    if err := json.Unmarshal(w.Body.Bytes(), &comment); err != nil { t.Fatal(err) }
+    return comment
+}
+
+func TestComments_ThreadAndCount(t *testing.T) {
+    setupTaskDB(t)
+    task := models.Task{Title: "Discuss", UserID: 1}
+    if err := database.DB.Create(&task).Error; err != nil { t.Fatal(err) }
+
+    first := postComment(t, 1, task.ID, "first")
+    postComment(t, 2, task.ID, "second")
+    postComment(t, 1, task.ID, "third")
+
+    w, c := performJSONRequest(GetTaskComments, http.MethodGet, nil)
+    c.Request.URL.RawQuery = "limit=2"
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(task.ID))}}
+    GetTaskComments(c)
+    var res struct {
+        Data       []models.Comment `json:"data"`
+        Total      int64            `json:"total"`
+        NextCursor *string          `json:"next_cursor"`
+    }
+    if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil { t.Fatal(err) }
+    if res.Total != 3 || len(res.Data) != 2 || res.Data[0].Body != "first" || res.NextCursor == nil {
+        t.Fatalf("unexpected page: %+v", res)
+    }
+
+    // 他人のコメントは削除できない
+    w, c = performJSONRequest(DeleteTaskComment, http.MethodDelete, nil)
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(task.ID))}, {Key: "comment_id", Value: strconv.Itoa(int(first.ID))}}
+    c.Set("user_id", uint(2))
+    DeleteTaskComment(c)
+    if w.Code != http.StatusForbidden { t.Fatalf("expected 403, got %d", w.Code) }
+
+    w, c = performJSONRequest(DeleteTaskComment, http.MethodDelete, nil)
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(task.ID))}, {Key: "comment_id", Value: strconv.Itoa(int(first.ID))}}
+    c.Set("user_id", uint(1))
+    DeleteTaskComment(c)
+    if w.Code != http.StatusOK { t.Fatalf("expected 200, got %d", w.Code) }
+
+    w, c = performJSONRequest(GetTask, http.MethodGet, nil)
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(task.ID))}}
+    GetTask(c)
+    var got models.Task
+    if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil { t.Fatal(err) }
+    if got.CommentCount != 2 { t.Fatalf("expected comment_count 2, got %d", got.CommentCount) }
+}
+
+func TestComments_EditWindow(t *testing.T) {
+    setupTaskDB(t)
+    t.Setenv("COMMENT_EDIT_WINDOW_MINUTES", "5")
+    task := models.Task{Title: "Discuss", UserID: 1}
+    if err := database.DB.Create(&task).Error; err != nil { t.Fatal(err) }
+    comment := postComment(t, 1, task.ID, "typo")
+
+    edit := func(userID uint) int {
+        w, c := performJSONRequest(UpdateTaskComment, http.MethodPut, CommentRequest{Body: "fixed"})
+        c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(task.ID))}, {Key: "comment_id", Value: strconv.Itoa(int(comment.ID))}}
+        c.Set("user_id", userID)
+        UpdateTaskComment(c)
+        return w.Code
+    }
+    if code := edit(2); code != http.StatusForbidden { t.Fatalf("expected 403 for non-author, got %d", code) }
+    if code := edit(1); code != http.StatusOK { t.Fatalf("expected 200, got %d", code) }
+
+    var saved models.Comment
+    if err := database.DB.First(&saved, comment.ID).Error; err != nil { t.Fatal(err) }
+    if saved.Body != "fixed" || saved.EditedAt == nil { t.Fatalf("expected edited comment, got %+v", saved) }
+
+    // 編集期間を過ぎると編集できない
+    database.DB.Model(&saved).UpdateColumn("created_at", time.Now().Add(-10*time.Minute))
+    if code := edit(1); code != http.StatusForbidden { t.Fatalf("expected 403 after edit window, got %d", code) }
+}
@@ -0,0 +1,537 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"flux/database"
+	"flux/middleware"
+	"flux/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CustomFieldRequest カスタムフィールドの作成・更新リクエスト
+type CustomFieldRequest struct {
+	Name     string   `json:"name" binding:"required,max=100"`
+	Type     string   `json:"type"`
+	Options  []string `json:"options"`
+	Position *int     `json:"position"` // 省略時は末尾に追加する
+}
+
+// TaskField タスクのプロジェクトのカスタムフィールドと、そのタスクでの値
+type TaskField struct {
+	models.CustomField
+	Value interface{} `json:"value"`
+}
+
+// fieldValueColumns 型ごとに値を保存するカラム
+var fieldValueColumns = map[string]string{
+	models.FieldTypeText:         "text_value",
+	models.FieldTypeNumber:       "number_value",
+	models.FieldTypeDate:         "date_value",
+	models.FieldTypeSingleSelect: "text_value",
+	models.FieldTypeMultiSelect:  "text_value",
+	models.FieldTypeUser:         "user_value",
+}
+
+// GetProjectFields retrieves the custom fields defined on a project
+func GetProjectFields(c *gin.Context) {
+	project, ok := findOwnedProject(c)
+	if !ok {
+		return
+	}
+	fields, err := projectFields(database.DB, project.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, fields)
+}
+
+// CreateProjectField defines a new custom field on a project
+func CreateProjectField(c *gin.Context) {
+	project, ok := findOwnedProject(c)
+	if !ok {
+		return
+	}
+
+	var req CustomFieldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	field := models.CustomField{ProjectID: project.ID, Name: strings.TrimSpace(req.Name), Type: req.Type, Options: req.Options}
+	if field.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+	if err := field.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if fieldNameTaken(project.ID, field.Name, 0) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Custom field name already exists"})
+		return
+	}
+
+	if req.Position != nil {
+		field.Position = *req.Position
+	} else {
+		var last int
+		database.DB.Model(&models.CustomField{}).Where("project_id = ?", project.ID).
+			Select("COALESCE(MAX(position), 0)").Scan(&last)
+		field.Position = last + 1
+	}
+	if err := database.DB.Create(&field).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, field)
+}
+
+// UpdateProjectField renames a custom field or changes its options or position. The type
+// cannot be changed, and values that use a removed option are deleted.
+func UpdateProjectField(c *gin.Context) {
+	project, ok := findOwnedProject(c)
+	if !ok {
+		return
+	}
+	field, ok := findProjectField(c, project.ID)
+	if !ok {
+		return
+	}
+
+	var req CustomFieldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Type != "" && req.Type != field.Type {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type cannot be changed"})
+		return
+	}
+	field.Name = strings.TrimSpace(req.Name)
+	field.Options = req.Options
+	if req.Position != nil {
+		field.Position = *req.Position
+	}
+	if field.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+	if err := field.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if fieldNameTaken(project.ID, field.Name, field.ID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Custom field name already exists"})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&field).Error; err != nil {
+			return err
+		}
+		if !field.IsSelect() {
+			return nil
+		}
+		return tx.Where("field_id = ? AND text_value NOT IN ?", field.ID, field.Options).Delete(&models.TaskFieldValue{}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, field)
+}
+
+// DeleteProjectField deletes a custom field and its values on all tasks
+func DeleteProjectField(c *gin.Context) {
+	project, ok := findOwnedProject(c)
+	if !ok {
+		return
+	}
+	field, ok := findProjectField(c, project.ID)
+	if !ok {
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := bumpTaskVersions(tx, tx.Model(&models.TaskFieldValue{}).Select("task_id").Where("field_id = ?", field.ID)); err != nil {
+			return err
+		}
+		if err := tx.Where("field_id = ?", field.ID).Delete(&models.TaskFieldValue{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&field).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Custom field deleted successfully"})
+}
+
+// GetTaskFields retrieves the custom fields of the task's project with the task's values
+func GetTaskFields(c *gin.Context) {
+	task, ok := findVisibleTask(c)
+	if !ok {
+		return
+	}
+	respondTaskFields(c, task)
+}
+
+// UpdateTaskFields sets custom field values on a task. The body maps field IDs to values;
+// fields that are left out keep their value and null clears it.
+func UpdateTaskFields(c *gin.Context) {
+	task, ok := findAuthorizedTask(c, taskActionEdit)
+	if !ok {
+		return
+	}
+
+	var req map[string]json.RawMessage
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if task.ProjectID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "task is not in a project"})
+		return
+	}
+	fields, err := projectFields(database.DB, *task.ProjectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	byKey := make(map[string]*models.CustomField, len(fields))
+	for i := range fields {
+		byKey[fieldKey(fields[i].ID)] = &fields[i]
+	}
+
+	parsed := make(map[uint][]models.TaskFieldValue, len(req))
+	for key, raw := range req {
+		field, ok := byKey[key]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "custom field not found: " + key})
+			return
+		}
+		rows, err := field.ParseValue(raw)
+		if err == nil && field.Type == models.FieldTypeUser && len(rows) > 0 {
+			err = validateFieldUser(database.DB, &task, *rows[0].UserValue)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		parsed[field.ID] = rows
+	}
+
+	current, err := taskFieldValues(database.DB, []models.Task{task})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	before := current[task.ID]
+
+	userID, _ := middleware.GetUserID(c)
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		changes := make(map[string]models.FieldChange)
+		for i := range fields {
+			field := &fields[i]
+			rows, ok := parsed[field.ID]
+			if !ok {
+				continue
+			}
+			if err := tx.Where("task_id = ? AND field_id = ?", task.ID, field.ID).Delete(&models.TaskFieldValue{}).Error; err != nil {
+				return err
+			}
+			for j := range rows {
+				rows[j].TaskID, rows[j].FieldID = task.ID, field.ID
+			}
+			if len(rows) > 0 {
+				if err := tx.Create(&rows).Error; err != nil {
+					return err
+				}
+			}
+			key := fieldKey(field.ID)
+			if from, to := before[key], field.Value(rows); !reflect.DeepEqual(from, to) {
+				changes["custom_fields."+key] = models.FieldChange{From: from, To: to}
+			}
+		}
+		if len(changes) == 0 {
+			return nil
+		}
+		if err := bumpTaskVersion(tx, task.ID); err != nil {
+			return err
+		}
+		return recordTaskEvent(tx, task.ID, userID, models.EventUpdated, changes)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respondTaskFields(c, task)
+}
+
+func respondTaskFields(c *gin.Context, task models.Task) {
+	out := []TaskField{}
+	if task.ProjectID != nil {
+		fields, err := projectFields(database.DB, *task.ProjectID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		values, err := taskFieldValues(database.DB, []models.Task{task})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		for _, f := range fields {
+			out = append(out, TaskField{CustomField: f, Value: values[task.ID][fieldKey(f.ID)]})
+		}
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// findProjectField loads the custom field in the :field_id path parameter from the project.
+// On failure the error response has already been written.
+func findProjectField(c *gin.Context, projectID uint) (models.CustomField, bool) {
+	var field models.CustomField
+	if err := database.DB.Where("project_id = ?", projectID).First(&field, c.Param("field_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Custom field not found"})
+		return field, false
+	}
+	return field, true
+}
+
+func projectFields(db *gorm.DB, projectID uint) ([]models.CustomField, error) {
+	fields := []models.CustomField{}
+	err := db.Where("project_id = ?", projectID).Order("position, id").Find(&fields).Error
+	return fields, err
+}
+
+func fieldNameTaken(projectID uint, name string, excludeID uint) bool {
+	var count int64
+	database.DB.Model(&models.CustomField{}).
+		Where("project_id = ? AND LOWER(name) = ? AND id <> ?", projectID, strings.ToLower(name), excludeID).
+		Count(&count)
+	return count > 0
+}
+
+// fieldKey is the key of a field in a task's custom_fields and in the cf[...] parameters
+func fieldKey(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+// validateFieldUser checks that a user field refers to an existing user who could be assigned to the task
+func validateFieldUser(db *gorm.DB, task *models.Task, userID uint) error {
+	var user models.User
+	if err := db.First(&user, userID).Error; err != nil {
+		return errors.New("User not found")
+	}
+	if task.OrganizationID != nil {
+		if _, ok := findMember(db, *task.OrganizationID, userID); !ok {
+			return errors.New("user must be a member of the organization")
+		}
+	}
+	return nil
+}
+
+// taskFieldValues loads the custom field values of tasks keyed by task ID. Values are kept
+// when a task moves to another project, but only those of its current project are returned.
+func taskFieldValues(db *gorm.DB, tasks []models.Task) (map[uint]models.FieldValues, error) {
+	out := make(map[uint]models.FieldValues)
+	projects := make(map[uint]uint, len(tasks))
+	var ids []uint
+	for _, t := range tasks {
+		if t.ProjectID != nil {
+			projects[t.ID] = *t.ProjectID
+			ids = append(ids, t.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return out, nil
+	}
+
+	var rows []models.TaskFieldValue
+	if err := db.Where("task_id IN ?", ids).Order("id").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return out, nil
+	}
+	fieldIDs := make([]uint, len(rows))
+	for i, r := range rows {
+		fieldIDs[i] = r.FieldID
+	}
+	var fields []models.CustomField
+	if err := db.Where("id IN ?", uniqueIDs(fieldIDs)).Find(&fields).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*models.CustomField, len(fields))
+	for i := range fields {
+		byID[fields[i].ID] = &fields[i]
+	}
+
+	grouped := make(map[uint]map[uint][]models.TaskFieldValue)
+	for _, r := range rows {
+		if f, ok := byID[r.FieldID]; !ok || f.ProjectID != projects[r.TaskID] {
+			continue
+		}
+		if grouped[r.TaskID] == nil {
+			grouped[r.TaskID] = make(map[uint][]models.TaskFieldValue)
+		}
+		grouped[r.TaskID][r.FieldID] = append(grouped[r.TaskID][r.FieldID], r)
+	}
+	for taskID, byField := range grouped {
+		values := make(models.FieldValues, len(byField))
+		for fieldID, rs := range byField {
+			values[fieldKey(fieldID)] = byID[fieldID].Value(rs)
+		}
+		out[taskID] = values
+	}
+	return out, nil
+}
+
+// applyCustomFieldFilters applies the cf[<field_id>], cf_from[<field_id>] and cf_to[<field_id>]
+// query parameters. Filtering on a field limits the list to the tasks of the field's project.
+func applyCustomFieldFilters(c *gin.Context, db *gorm.DB) (*gorm.DB, error) {
+	for _, param := range []string{"cf", "cf_from", "cf_to"} {
+		filters := c.QueryMap(param)
+		keys := make([]string, 0, len(filters))
+		for k := range filters {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			field, err := lookupField(db, key)
+			if err != nil {
+				return nil, err
+			}
+			cond, args, err := customFieldCondition(&field, param, filters[key])
+			if err != nil {
+				return nil, err
+			}
+			matches := db.Session(&gorm.Session{NewDB: true}).Model(&models.TaskFieldValue{}).
+				Select("task_id").Where("field_id = ?", field.ID)
+			if cond != "" {
+				matches = matches.Where(cond, args...)
+			}
+			db = db.Where("project_id = ? AND id IN (?)", field.ProjectID, matches)
+		}
+	}
+	return db, nil
+}
+
+// customFieldCondition builds the condition on task_field_values for one filter parameter.
+// An empty cf value matches any task that has a value for the field.
+func customFieldCondition(field *models.CustomField, param, value string) (string, []interface{}, error) {
+	invalid := fmt.Errorf("invalid %s[%d]: %s", param, field.ID, value)
+	if param != "cf" {
+		op := ">="
+		if param == "cf_to" {
+			op = "<="
+		}
+		switch field.Type {
+		case models.FieldTypeNumber:
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return "", nil, invalid
+			}
+			return "number_value " + op + " ?", []interface{}{n}, nil
+		case models.FieldTypeDate:
+			d, err := time.Parse(models.FieldDateLayout, value)
+			if err != nil {
+				return "", nil, invalid
+			}
+			return "date_value " + op + " ?", []interface{}{d}, nil
+		}
+		return "", nil, fmt.Errorf("%s is only supported for number and date fields", param)
+	}
+
+	if value == "" {
+		return "", nil, nil
+	}
+	switch field.Type {
+	case models.FieldTypeText:
+		return "LOWER(text_value) LIKE ?", []interface{}{"%" + strings.ToLower(value) + "%"}, nil
+	case models.FieldTypeSingleSelect, models.FieldTypeMultiSelect:
+		// いずれかの選択肢に一致すれば対象にする
+		return "text_value IN ?", []interface{}{strings.Split(value, ",")}, nil
+	case models.FieldTypeNumber:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "", nil, invalid
+		}
+		return "number_value = ?", []interface{}{n}, nil
+	case models.FieldTypeDate:
+		d, err := time.Parse(models.FieldDateLayout, value)
+		if err != nil {
+			return "", nil, invalid
+		}
+		return "date_value = ?", []interface{}{d}, nil
+	case models.FieldTypeUser:
+		var ids []uint
+		for _, s := range strings.Split(value, ",") {
+			id, err := strconv.ParseUint(s, 10, 64)
+			if err != nil {
+				return "", nil, invalid
+			}
+			ids = append(ids, uint(id))
+		}
+		return "user_value IN ?", []interface{}{ids}, nil
+	}
+	return "", nil, invalid
+}
+
+// customFieldSort resolves sort=cf.<field_id> to a subquery on the task's value. Tasks
+// without a value, including those outside the field's project, are ordered last.
+func customFieldSort(db *gorm.DB, param string) (*listSort, error) {
+	key, ok := strings.CutPrefix(param, "cf.")
+	if !ok {
+		return nil, nil
+	}
+	field, err := lookupField(db, key)
+	if err != nil {
+		return nil, err
+	}
+	if field.Type == models.FieldTypeMultiSelect {
+		return nil, errors.New("cannot sort by a multi_select field")
+	}
+
+	column := fmt.Sprintf("(SELECT %s FROM task_field_values WHERE task_field_values.task_id = tasks.id AND task_field_values.field_id = %d AND tasks.project_id = %d)",
+		fieldValueColumns[field.Type], field.ID, field.ProjectID)
+	key = fieldKey(field.ID)
+	value := func(row reflect.Value) interface{} {
+		v := row.Interface().(models.Task).CustomFields[key]
+		// 日付は文字列で返しているので、比較できるように日時に戻す
+		if s, ok := v.(string); ok && field.Type == models.FieldTypeDate {
+			d, _ := time.Parse(models.FieldDateLayout, s)
+			return d
+		}
+		return v
+	}
+	return &listSort{column: column, value: value}, nil
+}
+
+// lookupField loads a custom field by the ID given in a query parameter
+func lookupField(db *gorm.DB, key string) (models.CustomField, error) {
+	var field models.CustomField
+	id, err := strconv.ParseUint(key, 10, 64)
+	if err != nil {
+		return field, fmt.Errorf("invalid custom field id: %s", key)
+	}
+	if err := db.Session(&gorm.Session{NewDB: true}).First(&field, id).Error; err != nil {
+		return field, fmt.Errorf("unknown custom field: %s", key)
+	}
+	return field, nil
+}
@@ -0,0 +1,253 @@
+package handlers
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "strconv"
+    "testing"
+
+    "flux/database"
+    "flux/models"
+    "github.com/gin-gonic/gin"
+)
+
+func customFieldRequest(h gin.HandlerFunc, userID uint, params map[string]uint, body interface{}) *httptest.ResponseRecorder {
+    w, c := performJSONRequest(h, http.MethodPost, body)
+    for k, v := range params {
+        c.Params = append(c.Params, gin.Param{Key: k, Value: strconv.Itoa(int(v))})
+    }
+    c.Set("user_id", userID)
+    h(c)
+    return w
+}
+
+func createFieldAs(t *testing.T, userID, projectID uint, body map[string]interface{}) models.CustomField {
+    t.Helper()
+    w := customFieldRequest(CreateProjectField, userID, map[string]uint{"id": projectID}, body)
+    if w.Code != http.StatusCreated { t.Fatalf("expected 201 for %v, got %d: %s", body, w.Code, w.Body.String()) }
+    var field models.CustomField
+    if err := json.Unmarshal(w.Body.Bytes(), &field); err != nil { t.Fatal(err) }
+    return field
+}
+
+func setTaskFields(taskID, userID uint, body map[string]interface{}) (*httptest.ResponseRecorder, []TaskField) {
+    w := customFieldRequest(UpdateTaskFields, userID, map[string]uint{"id": taskID}, body)
+    var fields []TaskField
+    json.Unmarshal(w.Body.Bytes(), &fields)
+    return w, fields
+}
+
+func TestCustomFields_Definitions(t *testing.T) {
+    setupTaskDB(t)
+    p := createProjectAs(t, 1, "Clients")
+    params := map[string]uint{"id": p.ID}
+
+    if w := customFieldRequest(CreateProjectField, 2, params, map[string]interface{}{"name": "Customer", "type": "text"}); w.Code != http.StatusForbidden { t.Fatalf("expected 403, got %d", w.Code) }
+    customer := createFieldAs(t, 1, p.ID, map[string]interface{}{"name": "Customer", "type": "text"})
+    for _, body := range []map[string]interface{}{
+        {"name": "Tier", "type": "single_select"},
+        {"name": "Tier", "type": "single_select", "options": []string{"Gold", "Gold"}},
+        {"name": "Tier", "type": "multi_select", "options": []string{"A,B"}},
+        {"name": "Points", "type": "number", "options": []string{"1"}},
+        {"name": "Color", "type": "color"},
+        {"name": " ", "type": "text"},
+    } {
+        if w := customFieldRequest(CreateProjectField, 1, params, body); w.Code != http.StatusBadRequest { t.Fatalf("expected 400 for %v, got %d", body, w.Code) }
+    }
+    if w := customFieldRequest(CreateProjectField, 1, params, map[string]interface{}{"name": "customer", "type": "number"}); w.Code != http.StatusConflict { t.Fatalf("expected 409, got %d", w.Code) }
+
+    tier := createFieldAs(t, 1, p.ID, map[string]interface{}{"name": "Tier", "type": "single_select", "options": []string{"Gold", " Silver ", "Bronze"}})
+    if tier.Position != customer.Position+1 || tier.Options[1] != "Silver" { t.Fatalf("unexpected field: %+v", tier) }
+
+    w := customFieldRequest(GetProjectFields, 1, params, nil)
+    var fields []models.CustomField
+    json.Unmarshal(w.Body.Bytes(), &fields)
+    if len(fields) != 2 || fields[0].ID != customer.ID || fields[1].ID != tier.ID { t.Fatalf("unexpected fields: %s", w.Body.String()) }
+
+    // 選択肢を削除すると、その選択肢を使っていた値も消える
+    task := models.Task{Title: "T", UserID: 1, ProjectID: &p.ID}
+    database.DB.Create(&task)
+    if w, _ := setTaskFields(task.ID, 1, map[string]interface{}{fieldKey(tier.ID): "Silver", fieldKey(customer.ID): "Acme"}); w.Code != http.StatusOK { t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String()) }
+    fieldParams := map[string]uint{"id": p.ID, "field_id": tier.ID}
+    if w = customFieldRequest(UpdateProjectField, 1, fieldParams, map[string]interface{}{"name": "Tier", "type": "text"}); w.Code != http.StatusBadRequest { t.Fatalf("expected 400 for a type change, got %d", w.Code) }
+    if w = customFieldRequest(UpdateProjectField, 1, fieldParams, map[string]interface{}{"name": "Level", "options": []string{"Gold", "Bronze"}}); w.Code != http.StatusOK { t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String()) }
+    var count int64
+    database.DB.Model(&models.TaskFieldValue{}).Where("field_id = ?", tier.ID).Count(&count)
+    if count != 0 { t.Fatalf("expected the Silver value to be removed, got %d", count) }
+
+    if w = customFieldRequest(DeleteProjectField, 1, map[string]uint{"id": p.ID, "field_id": customer.ID}, nil); w.Code != http.StatusOK { t.Fatalf("expected 200, got %d", w.Code) }
+    database.DB.Model(&models.TaskFieldValue{}).Where("field_id = ?", customer.ID).Count(&count)
+    if count != 0 { t.Fatalf("expected values to be deleted with the field, got %d", count) }
+    if w = customFieldRequest(DeleteProjectField, 1, map[string]uint{"id": p.ID, "field_id": customer.ID}, nil); w.Code != http.StatusNotFound { t.Fatalf("expected 404, got %d", w.Code) }
+}
+
+func TestCustomFields_TaskValues(t *testing.T) {
+    setupTaskDB(t)
+    owner := models.User{Name: "Owner", Email: "owner@example.com", Password: "Password1!"}
+    alice := models.User{Name: "Alice", Email: "alice@example.com", Password: "Password1!"}
+    database.DB.Create(&owner)
+    database.DB.Create(&alice)
+    p := createProjectAs(t, owner.ID, "Clients")
+    text := createFieldAs(t, owner.ID, p.ID, map[string]interface{}{"name": "Customer", "type": "text"})
+    number := createFieldAs(t, owner.ID, p.ID, map[string]interface{}{"name": "Story points", "type": "number"})
+    date := createFieldAs(t, owner.ID, p.ID, map[string]interface{}{"name": "Signed", "type": "date"})
+    single := createFieldAs(t, owner.ID, p.ID, map[string]interface{}{"name": "Tier", "type": "single_select", "options": []string{"Gold", "Silver"}})
+    multi := createFieldAs(t, owner.ID, p.ID, map[string]interface{}{"name": "Tags", "type": "multi_select", "options": []string{"a", "b", "c"}})
+    user := createFieldAs(t, owner.ID, p.ID, map[string]interface{}{"name": "Reviewer", "type": "user"})
+
+    task := models.Task{Title: "Contract", UserID: owner.ID, ProjectID: &p.ID}
+    loose := models.Task{Title: "Loose", UserID: owner.ID}
+    database.DB.Create(&task)
+    database.DB.Create(&loose)
+
+    for _, body := range []map[string]interface{}{
+        {fieldKey(number.ID): "five"},
+        {fieldKey(date.ID): "03/02/2026"},
+        {fieldKey(single.ID): "Platinum"},
+        {fieldKey(multi.ID): []string{"a", "z"}},
+        {fieldKey(multi.ID): "a"},
+        {fieldKey(user.ID): 9999},
+        {fieldKey(text.ID): 42},
+        {"9999": "x"},
+    } {
+        if w, _ := setTaskFields(task.ID, owner.ID, body); w.Code != http.StatusBadRequest { t.Fatalf("expected 400 for %v, got %d", body, w.Code) }
+    }
+    if w, _ := setTaskFields(loose.ID, owner.ID, map[string]interface{}{fieldKey(text.ID): "Acme"}); w.Code != http.StatusBadRequest { t.Fatalf("expected 400 outside a project, got %d", w.Code) }
+    if w, _ := setTaskFields(task.ID, alice.ID, map[string]interface{}{fieldKey(text.ID): "Acme"}); w.Code != http.StatusForbidden { t.Fatalf("expected 403, got %d", w.Code) }
+
+    w, fields := setTaskFields(task.ID, owner.ID, map[string]interface{}{
+        fieldKey(text.ID):   " Acme ",
+        fieldKey(number.ID): 5,
+        fieldKey(date.ID):   "2026-03-02",
+        fieldKey(single.ID): "Gold",
+        fieldKey(multi.ID):  []string{"c", "a"},
+        fieldKey(user.ID):   alice.ID,
+    })
+    if w.Code != http.StatusOK || len(fields) != 6 { t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String()) }
+    if fields[0].Value != "Acme" || fields[1].Value != float64(5) || fields[2].Value != "2026-03-02" || fields[3].Value != "Gold" { t.Fatalf("unexpected values: %s", w.Body.String()) }
+    if tags := fields[4].Value.([]interface{}); len(tags) != 2 || tags[0] != "a" || tags[1] != "c" { t.Fatalf("expected the options in definition order, got %v", tags) }
+    if fields[5].Value != float64(alice.ID) { t.Fatalf("unexpected user: %v", fields[5].Value) }
+
+    // 一部だけ送ると他の値は残り、null で削除できる
+    w, fields = setTaskFields(task.ID, owner.ID, map[string]interface{}{fieldKey(number.ID): nil})
+    if w.Code != http.StatusOK || fields[1].Value != nil || fields[0].Value != "Acme" { t.Fatalf("unexpected response: %s", w.Body.String()) }
+
+    w, c := performJSONRequest(GetTask, http.MethodGet, nil)
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(task.ID))}}
+    GetTask(c)
+    var got models.Task
+    json.Unmarshal(w.Body.Bytes(), &got)
+    if len(got.CustomFields) != 5 || got.CustomFields[fieldKey(single.ID)] != "Gold" || got.Version != 3 { t.Fatalf("unexpected task: %s", w.Body.String()) }
+
+    var events []models.TaskEvent
+    database.DB.Where("task_id = ?", task.ID).Order("id").Find(&events)
+    if len(events) != 2 || len(events[0].Changes) != 6 { t.Fatalf("unexpected history: %+v", events) }
+    if change := events[1].Changes["custom_fields."+fieldKey(number.ID)]; change.From != float64(5) || change.To != nil { t.Fatalf("unexpected change: %+v", change) }
+
+    // 別のプロジェクトに移すと値は表示されない
+    database.DB.Model(&task).Update("project_id", nil)
+    w, c = performJSONRequest(GetTask, http.MethodGet, nil)
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(task.ID))}}
+    GetTask(c)
+    got = models.Task{}
+    json.Unmarshal(w.Body.Bytes(), &got)
+    if len(got.CustomFields) != 0 { t.Fatalf("expected no values outside the project, got %v", got.CustomFields) }
+}
+
+func TestCustomFields_FilterAndSort(t *testing.T) {
+    setupTaskDB(t)
+    p := createProjectAs(t, 1, "Clients")
+    customer := createFieldAs(t, 1, p.ID, map[string]interface{}{"name": "Customer", "type": "text"})
+    points := createFieldAs(t, 1, p.ID, map[string]interface{}{"name": "Points", "type": "number"})
+    signed := createFieldAs(t, 1, p.ID, map[string]interface{}{"name": "Signed", "type": "date"})
+    tags := createFieldAs(t, 1, p.ID, map[string]interface{}{"name": "Tags", "type": "multi_select", "options": []string{"x", "y", "z"}})
+
+    var ids []uint
+    for i, values := range []map[string]interface{}{
+        {fieldKey(customer.ID): "Acme Corp", fieldKey(points.ID): 8, fieldKey(signed.ID): "2026-03-02", fieldKey(tags.ID): []string{"x"}},
+        {fieldKey(customer.ID): "Globex", fieldKey(points.ID): 3, fieldKey(signed.ID): "2026-01-15", fieldKey(tags.ID): []string{"y", "z"}},
+        {fieldKey(customer.ID): "acme labs", fieldKey(points.ID): 5},
+        {},
+    } {
+        task := models.Task{Title: fmt.Sprintf("T%d", i), UserID: 1, ProjectID: &p.ID}
+        database.DB.Create(&task)
+        ids = append(ids, task.ID)
+        if len(values) > 0 {
+            if w, _ := setTaskFields(task.ID, 1, values); w.Code != http.StatusOK { t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String()) }
+        }
+    }
+    // プロジェクト外のタスクは値を持たない
+    outside := models.Task{Title: "Outside", UserID: 1}
+    database.DB.Create(&outside)
+
+    list := func(query url.Values) (int, []uint, string) {
+        w, c := performJSONRequest(GetTasks, http.MethodGet, nil)
+        c.Request.URL.RawQuery = query.Encode()
+        c.Set("user_id", uint(1))
+        GetTasks(c)
+        var res struct {
+            Data       []models.Task `json:"data"`
+            NextCursor *string       `json:"next_cursor"`
+        }
+        json.Unmarshal(w.Body.Bytes(), &res)
+        got := []uint{}
+        for _, task := range res.Data {
+            got = append(got, task.ID)
+        }
+        next := ""
+        if res.NextCursor != nil {
+            next = *res.NextCursor
+        }
+        return w.Code, got, next
+    }
+    expect := func(query url.Values, want ...uint) {
+        t.Helper()
+        code, got, _ := list(query)
+        if code != http.StatusOK || fmt.Sprint(got) != fmt.Sprint(want) { t.Fatalf("%s: expected %v, got %d %v", query.Encode(), want, code, got) }
+    }
+    cf := func(param string, field models.CustomField) string { return param + "[" + fieldKey(field.ID) + "]" }
+
+    expect(url.Values{cf("cf", customer): {"ACME"}}, ids[0], ids[2])
+    expect(url.Values{cf("cf", customer): {""}}, ids[0], ids[1], ids[2])
+    expect(url.Values{cf("cf", points): {"3"}}, ids[1])
+    expect(url.Values{cf("cf_from", points): {"4"}, cf("cf_to", points): {"8"}}, ids[0], ids[2])
+    expect(url.Values{cf("cf_to", signed): {"2026-02-01"}}, ids[1])
+    expect(url.Values{cf("cf", tags): {"x,z"}}, ids[0], ids[1])
+    expect(url.Values{cf("cf", tags): {"y"}, cf("cf", customer): {"glob"}}, ids[1])
+
+    expect(url.Values{"sort": {"cf." + fieldKey(points.ID)}}, ids[1], ids[2], ids[0], ids[3], outside.ID)
+    expect(url.Values{"sort": {"cf." + fieldKey(points.ID)}, "order": {"desc"}}, ids[0], ids[2], ids[1], ids[3], outside.ID)
+    expect(url.Values{"sort": {"cf." + fieldKey(customer.ID)}}, ids[0], ids[1], ids[2], ids[3], outside.ID)
+
+    for _, query := range []url.Values{
+        {cf("cf_from", customer): {"a"}},
+        {cf("cf", points): {"many"}},
+        {"cf[9999]": {"1"}},
+        {"cf[abc]": {"1"}},
+        {"sort": {"cf." + fieldKey(tags.ID)}},
+        {"sort": {"cf.9999"}},
+        {"sort": {"custom"}},
+    } {
+        if code, _, _ := list(query); code != http.StatusBadRequest { t.Fatalf("%s: expected 400, got %d", query.Encode(), code) }
+    }
+
+    // カーソルで1件ずつ辿っても同じ順序になる（値の無いタスクは末尾）
+    for _, field := range []models.CustomField{points, signed} {
+        query := url.Values{"sort": {"cf." + fieldKey(field.ID)}, "limit": {"1"}, "cursor": {""}}
+        _, all, _ := list(url.Values{"sort": {"cf." + fieldKey(field.ID)}})
+        var walked []uint
+        for page := 0; page < 10; page++ {
+            code, got, next := list(query)
+            if code != http.StatusOK { t.Fatalf("expected 200, got %d", code) }
+            walked = append(walked, got...)
+            if next == "" {
+                break
+            }
+            query.Set("cursor", next)
+        }
+        if fmt.Sprint(walked) != fmt.Sprint(all) { t.Fatalf("cursor order %v differs from %v", walked, all) }
+    }
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"flux/database"
+	"flux/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// DependencyRequest 依存関係追加リクエスト
+type DependencyRequest struct {
+	TaskID uint `json:"task_id" binding:"required"`
+}
+
+// blockedTaskError ブロックされているタスクを着手しようとした
+type blockedTaskError struct {
+	blockers []uint
+}
+
+func (e *blockedTaskError) Error() string {
+	return fmt.Sprintf("task is blocked by %d unfinished tasks (use force=true to start anyway)", len(e.blockers))
+}
+
+// GetTaskDependencies lists the tasks blocking and blocked by a task
+func GetTaskDependencies(c *gin.Context) {
+	task, ok := findVisibleTask(c)
+	if !ok {
+		return
+	}
+
+	blockedBy := []models.Task{}
+	if err := visibleTasks(c, database.DB).Where("id IN (SELECT blocked_by_id FROM task_dependencies WHERE task_id = ?)", task.ID).
+		Order("id").Find(&blockedBy).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	blocks := []models.Task{}
+	if err := visibleTasks(c, database.DB).Where("id IN (SELECT task_id FROM task_dependencies WHERE blocked_by_id = ?)", task.ID).
+		Order("id").Find(&blocks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"blocked_by": blockedBy, "blocks": blocks})
+}
+
+// AddBlockedBy marks the task as blocked by another task
+func AddBlockedBy(c *gin.Context) {
+	addDependency(c, false)
+}
+
+// AddBlocks marks the task as blocking another task
+func AddBlocks(c *gin.Context) {
+	addDependency(c, true)
+}
+
+// RemoveBlockedBy removes a "blocked by" link
+func RemoveBlockedBy(c *gin.Context) {
+	removeDependency(c, false)
+}
+
+// RemoveBlocks removes a "blocks" link
+func RemoveBlocks(c *gin.Context) {
+	removeDependency(c, true)
+}
+
+func addDependency(c *gin.Context, blocks bool) {
+	task, ok := findAuthorizedTask(c, taskActionEdit)
+	if !ok {
+		return
+	}
+
+	var req DependencyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var other models.Task
+	if err := database.DB.First(&other, req.TaskID).Error; err != nil || !sameTaskScope(&task, &other) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Related task not found"})
+		return
+	}
+
+	dep := models.TaskDependency{TaskID: task.ID, BlockedByID: other.ID}
+	if blocks {
+		dep = models.TaskDependency{TaskID: other.ID, BlockedByID: task.ID}
+	}
+	if dep.TaskID == dep.BlockedByID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "a task cannot depend on itself"})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.TaskDependency{}).Where("task_id = ? AND blocked_by_id = ?", dep.TaskID, dep.BlockedByID).
+			Count(&count).Error; err != nil || count > 0 {
+			return err
+		}
+		cyclic, err := dependsOn(tx, dep.BlockedByID, dep.TaskID)
+		if err != nil {
+			return err
+		}
+		if cyclic {
+			return &hierarchyError{msg: "dependency would create a cycle"}
+		}
+		if err := tx.Create(&dep).Error; err != nil {
+			return err
+		}
+		// 待つ側のタスクのブロック状態が変わる
+		return bumpTaskVersion(tx, dep.TaskID)
+	})
+	if err != nil {
+		respondTaskError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"task_id": dep.TaskID, "blocked_by_id": dep.BlockedByID})
+}
+
+func removeDependency(c *gin.Context, blocks bool) {
+	task, ok := findAuthorizedTask(c, taskActionEdit)
+	if !ok {
+		return
+	}
+
+	otherID, err := strconv.Atoi(c.Param("other_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Dependency not found"})
+		return
+	}
+	dep := models.TaskDependency{TaskID: task.ID, BlockedByID: uint(otherID)}
+	if blocks {
+		dep = models.TaskDependency{TaskID: uint(otherID), BlockedByID: task.ID}
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("task_id = ? AND blocked_by_id = ?", dep.TaskID, dep.BlockedByID).Delete(&models.TaskDependency{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return bumpTaskVersion(tx, dep.TaskID)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Dependency not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Dependency removed successfully"})
+}
+
+// dependsOn reports whether taskID transitively waits on targetID
+func dependsOn(tx *gorm.DB, taskID, targetID uint) (bool, error) {
+	seen := map[uint]bool{taskID: true}
+	frontier := []uint{taskID}
+	for len(frontier) > 0 {
+		var next []uint
+		if err := tx.Model(&models.TaskDependency{}).Where("task_id IN ?", frontier).Pluck("blocked_by_id", &next).Error; err != nil {
+			return false, err
+		}
+		frontier = frontier[:0]
+		for _, id := range next {
+			if id == targetID {
+				return true, nil
+			}
+			if !seen[id] {
+				seen[id] = true
+				frontier = append(frontier, id)
+			}
+		}
+	}
+	return false, nil
+}
+
+// openBlockers returns the unfinished tasks blocking each of taskIDs
+func openBlockers(db *gorm.DB, taskIDs []uint) (map[uint][]uint, error) {
+	var rows []struct {
+		TaskID      uint
+		BlockedByID uint
+	}
+	err := db.Table("task_dependencies").
+		Select("task_dependencies.task_id, task_dependencies.blocked_by_id").
+		Joins("JOIN tasks ON tasks.id = task_dependencies.blocked_by_id").
+		Where("task_dependencies.task_id IN ? AND tasks.status <> ? AND tasks.deleted_at IS NULL", taskIDs, models.StatusCompleted).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	blockers := make(map[uint][]uint)
+	for _, r := range rows {
+		blockers[r.TaskID] = append(blockers[r.TaskID], r.BlockedByID)
+	}
+	return blockers, nil
+}
+
+// checkNotBlocked rejects starting a blocked task unless force is set
+func checkNotBlocked(db *gorm.DB, task *models.Task, force bool) error {
+	if force {
+		return nil
+	}
+	blockers, err := openBlockers(db, []uint{task.ID})
+	if err != nil {
+		return err
+	}
+	if len(blockers[task.ID]) > 0 {
+		return &blockedTaskError{blockers: blockers[task.ID]}
+	}
+	return nil
+}
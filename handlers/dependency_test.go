@@ -0,0 +1,81 @@
+package handlers
+
+import (
+    "encoding/json"
+    "net/http"
+    "strconv"
+    "testing"
+
+    "github.com/gin-gonic/gin"
+)
+
+func addDependencyAs(t *testing.T, h gin.HandlerFunc, userID, taskID, otherID uint) int {
+    t.Helper()
+    w, c := performJSONRequest(h, http.MethodPost, DependencyRequest{TaskID: otherID})
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(taskID))}}
+    c.Set("user_id", userID)
+    h(c)
+    return w.Code
+}
+
+func TestDependencies_CycleDetection(t *testing.T) {
+    setupTaskDB(t)
+
+    _, a := createSubtask(t, 1, "A", nil)
+    _, b := createSubtask(t, 1, "B", nil)
+    _, c := createSubtask(t, 1, "C", nil)
+
+    // A は B 待ち、B は C 待ち
+    if code := addDependencyAs(t, AddBlockedBy, 1, a.ID, b.ID); code != http.StatusCreated { t.Fatalf("expected 201, got %d", code) }
+    if code := addDependencyAs(t, AddBlocks, 1, c.ID, b.ID); code != http.StatusCreated { t.Fatalf("expected 201, got %d", code) }
+
+    // C を A 待ちにすると循環する
+    if code := addDependencyAs(t, AddBlockedBy, 1, c.ID, a.ID); code != http.StatusBadRequest { t.Fatalf("expected 400 for cycle, got %d", code) }
+    if code := addDependencyAs(t, AddBlockedBy, 1, a.ID, a.ID); code != http.StatusBadRequest { t.Fatalf("expected 400 for self dependency, got %d", code) }
+    if code := addDependencyAs(t, AddBlockedBy, 2, a.ID, c.ID); code != http.StatusForbidden { t.Fatalf("expected 403, got %d", code) }
+
+    w, ctx := performJSONRequest(GetTaskDependencies, http.MethodGet, nil)
+    ctx.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(b.ID))}}
+    GetTaskDependencies(ctx)
+    var deps struct {
+        BlockedBy []struct{ ID uint } `json:"blocked_by"`
+        Blocks    []struct{ ID uint } `json:"blocks"`
+    }
+    if err := json.Unmarshal(w.Body.Bytes(), &deps); err != nil { t.Fatal(err) }
+    if len(deps.BlockedBy) != 1 || deps.BlockedBy[0].ID != c.ID || len(deps.Blocks) != 1 || deps.Blocks[0].ID != a.ID {
+        t.Fatalf("unexpected dependencies: %+v", deps)
+    }
+
+    w, ctx = performJSONRequest(RemoveBlockedBy, http.MethodDelete, nil)
+    ctx.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(a.ID))}, {Key: "other_id", Value: strconv.Itoa(int(b.ID))}}
+    ctx.Set("user_id", uint(1))
+    RemoveBlockedBy(ctx)
+    if w.Code != http.StatusOK { t.Fatalf("expected 200, got %d", w.Code) }
+}
+
+func TestDependencies_BlockedTaskCannotStart(t *testing.T) {
+    setupTaskDB(t)
+
+    _, a := createSubtask(t, 1, "A", nil)
+    _, b := createSubtask(t, 1, "B", nil)
+    if code := addDependencyAs(t, AddBlockedBy, 1, a.ID, b.ID); code != http.StatusCreated { t.Fatalf("expected 201, got %d", code) }
+
+    w, ctx := performJSONRequest(GetTask, http.MethodGet, nil)
+    ctx.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(a.ID))}}
+    GetTask(ctx)
+    var got map[string]interface{}
+    _ = json.Unmarshal(w.Body.Bytes(), &got)
+    if got["is_blocked"] != true { t.Fatalf("expected is_blocked, got %v", got["is_blocked"]) }
+
+    code, res := updateTaskAs(t, 1, a.ID, map[string]string{"status": "in_progress"}, "")
+    if code != http.StatusUnprocessableEntity || res["blocked_by"] == nil { t.Fatalf("expected 422 with blockers, got %d %v", code, res) }
+
+    if code, _ := updateTaskAs(t, 1, a.ID, map[string]string{"status": "in_progress"}, "force=true"); code != http.StatusOK {
+        t.Fatalf("expected 200 with force, got %d", code)
+    }
+
+    // ブロック元が完了すれば通常どおり着手できる
+    if code, _ := updateTaskAs(t, 1, a.ID, map[string]string{"status": "pending"}, ""); code != http.StatusOK { t.Fatalf("expected 200, got %d", code) }
+    if code, _ := updateTaskAs(t, 1, b.ID, map[string]string{"status": "completed"}, ""); code != http.StatusOK { t.Fatalf("expected 200, got %d", code) }
+    if code, _ := updateTaskAs(t, 1, a.ID, map[string]string{"status": "in_progress"}, ""); code != http.StatusOK { t.Fatalf("expected 200, got %d", code) }
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"flux/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// errVersionConflict 読み込んだ後に他の更新が入った
+var errVersionConflict = errors.New("task has been modified by someone else")
+
+// taskETag タスクのバージョンから ETag を作る
+func taskETag(task *models.Task) string {
+	return fmt.Sprintf(`"%d-%d"`, task.ID, task.Version)
+}
+
+// etagMatches reports whether an If-Match / If-None-Match header value lists etag.
+// Weak validators are compared by their opaque tag.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIfMatch honours the If-Match request header for a write to task, writing
+// 412 Precondition Failed with the current ETag on mismatch
+func checkIfMatch(c *gin.Context, task *models.Task) bool {
+	header := c.GetHeader("If-Match")
+	if header == "" || etagMatches(header, taskETag(task)) {
+		return true
+	}
+	c.Header("ETag", taskETag(task))
+	c.JSON(http.StatusPreconditionFailed, gin.H{"error": errVersionConflict.Error(), "version": task.Version})
+	return false
+}
+
+// saveTaskVersioned writes all fields of task only if the stored version is still the
+// one it was loaded with, bumping the version. It returns errVersionConflict otherwise.
+func saveTaskVersioned(tx *gorm.DB, task *models.Task) error {
+	loaded := task.Version
+	task.Version++
+	result := tx.Model(task).Where("version = ?", loaded).Select("*").Omit("created_at", "Assignees", "Labels", "User").Updates(task)
+	if result.Error != nil {
+		task.Version = loaded
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		task.Version = loaded
+		return errVersionConflict
+	}
+	return nil
+}
+
+// bumpTaskVersion marks a task as changed when something shown with it, such as
+// its labels or assignees, changes without the task row being written
+func bumpTaskVersion(tx *gorm.DB, taskID uint) error {
+	return tx.Model(&models.Task{}).Where("id = ?", taskID).UpdateColumn("version", gorm.Expr("version + 1")).Error
+}
+
+// bumpTaskVersions bumps the version of every task in ids, which may also be a subquery of task IDs
+func bumpTaskVersions(tx *gorm.DB, ids interface{}) error {
+	return tx.Model(&models.Task{}).Where("id IN (?)", ids).UpdateColumn("version", gorm.Expr("version + 1")).Error
+}
+
+// bumpRelatedVersions marks the tasks whose computed fields follow task as changed: the
+// parents whose subtask progress and the tasks whose blocked state it affects. before is
+// the task as loaded for an update, or nil when task was created, deleted or restored.
+func bumpRelatedVersions(tx *gorm.DB, before, task *models.Task) error {
+	statusChanged := before == nil || before.Status != task.Status
+	var ids []uint
+	if before != nil && before.ParentID != nil && (task.ParentID == nil || *task.ParentID != *before.ParentID) {
+		ids = append(ids, *before.ParentID)
+	}
+	if task.ParentID != nil && (statusChanged || before.ParentID == nil || *before.ParentID != *task.ParentID) {
+		ids = append(ids, *task.ParentID)
+	}
+	if len(ids) > 0 {
+		if err := bumpTaskVersions(tx, ids); err != nil {
+			return err
+		}
+	}
+	if !statusChanged {
+		return nil
+	}
+	return bumpBlockedVersions(tx, task.ID)
+}
+
+// bumpBlockedVersions marks the tasks waiting on taskID as changed, since their blocked state follows its status
+func bumpBlockedVersions(tx *gorm.DB, taskID uint) error {
+	return bumpTaskVersions(tx, tx.Model(&models.TaskDependency{}).Select("task_id").Where("blocked_by_id = ?", taskID))
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+    "net/http"
+    "strconv"
+    "testing"
+    "time"
+
+    "flux/database"
+    "flux/models"
+    "github.com/gin-gonic/gin"
+)
+
+func TestETag_ConditionalRequests(t *testing.T) {
+    setupTaskDB(t)
+    task := models.Task{Title: "Shared doc", UserID: 1}
+    if err := database.DB.Create(&task).Error; err != nil { t.Fatal(err) }
+    id := strconv.Itoa(int(task.ID))
+
+    w, c := performJSONRequest(GetTask, http.MethodGet, nil)
+    c.Params = []gin.Param{{Key: "id", Value: id}}
+    GetTask(c)
+    etag := w.Header().Get("ETag")
+    if etag == "" { t.Fatal("expected an ETag header") }
+
+    w, c = performJSONRequest(GetTask, http.MethodGet, nil)
+    c.Params = []gin.Param{{Key: "id", Value: id}}
+    c.Request.Header.Set("If-None-Match", etag)
+    GetTask(c)
+    if w.Code != http.StatusNotModified { t.Fatalf("expected 304, got %d", w.Code) }
+
+    update := func(title, ifMatch string) *ginRecorder {
+        w, c := performJSONRequest(UpdateTask, http.MethodPut, models.Task{Title: title})
+        c.Params = []gin.Param{{Key: "id", Value: id}}
+        c.Request.Header.Set("If-Match", ifMatch)
+        c.Set("user_id", uint(1))
+        UpdateTask(c)
+        return &ginRecorder{code: w.Code, etag: w.Header().Get("ETag")}
+    }
+
+    // 最初の更新は成功し、同じ ETag を使った2回目の更新は拒否される
+    first := update("Edit A", etag)
+    if first.code != http.StatusOK || first.etag == etag { t.Fatalf("expected 200 with a new ETag, got %d %q", first.code, first.etag) }
+    if second := update("Edit B", etag); second.code != http.StatusPreconditionFailed { t.Fatalf("expected 412, got %d", second.code) }
+
+    var saved models.Task
+    database.DB.First(&saved, task.ID)
+    if saved.Title != "Edit A" || saved.Version != 2 { t.Fatalf("unexpected task after conflict: %q v%d", saved.Title, saved.Version) }
+
+    // 古い ETag では削除もできない
+    w, c = performJSONRequest(DeleteTask, http.MethodDelete, nil)
+    c.Params = []gin.Param{{Key: "id", Value: id}}
+    c.Request.Header.Set("If-Match", etag)
+    c.Set("user_id", uint(1))
+    DeleteTask(c)
+    if w.Code != http.StatusPreconditionFailed { t.Fatalf("expected 412, got %d", w.Code) }
+
+    w, c = performJSONRequest(DeleteTask, http.MethodDelete, nil)
+    c.Params = []gin.Param{{Key: "id", Value: id}}
+    c.Request.Header.Set("If-Match", first.etag)
+    c.Set("user_id", uint(1))
+    DeleteTask(c)
+    if w.Code != http.StatusOK { t.Fatalf("expected 200, got %d", w.Code) }
+}
+
+func TestETag_ChangesWithComputedFields(t *testing.T) {
+    setupTaskDB(t)
+    database.DB.Create(&models.User{Name: "U", Email: "computed@example.com", Password: "Password1!"})
+    _, parent := createSubtask(t, 1, "Parent", nil)
+    _, child := createSubtask(t, 1, "Child", &parent.ID)
+    _, blocker := createSubtask(t, 1, "Blocker", nil)
+
+    etag := func() string {
+        w, c := performJSONRequest(GetTask, http.MethodGet, nil)
+        c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(parent.ID))}}
+        c.Set("user_id", uint(1))
+        GetTask(c)
+        return w.Header().Get("ETag")
+    }
+    changed := func(what string, change func()) {
+        t.Helper()
+        before := etag()
+        change()
+        if etag() == before { t.Fatalf("expected the ETag to change after %s", what) }
+    }
+
+    // 本体を書き換えなくても、表示に含まれる集計が変われば ETag も変わる
+    changed("adding a dependency", func() {
+        if code := addDependencyAs(t, AddBlockedBy, 1, parent.ID, blocker.ID); code != http.StatusCreated { t.Fatalf("expected 201, got %d", code) }
+    })
+    changed("completing the blocker", func() {
+        if code, _ := updateTaskAs(t, 1, blocker.ID, map[string]string{"status": "completed"}, ""); code != http.StatusOK { t.Fatalf("expected 200, got %d", code) }
+    })
+    changed("completing a subtask", func() {
+        if code, _ := updateTaskAs(t, 1, child.ID, map[string]string{"status": "completed"}, ""); code != http.StatusOK { t.Fatalf("expected 200, got %d", code) }
+    })
+    changed("commenting", func() { postComment(t, 1, parent.ID, "done?") })
+    changed("logging time", func() {
+        minutes := 15
+        start := time.Now().Add(-time.Hour)
+        if w := timeEntryRequest(CreateTaskTimeEntry, 1, map[string]uint{"id": parent.ID}, TimeEntryRequest{StartedAt: &start, Minutes: &minutes}); w.Code != http.StatusCreated { t.Fatalf("expected 201, got %d", w.Code) }
+    })
+
+    // 付いていないラベルを外しても ETag は変わらない
+    label := createLabel(t, 1, "idle")
+    before := etag()
+    w, c := performJSONRequest(DetachTaskLabel, http.MethodDelete, nil)
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(parent.ID))}, {Key: "label_id", Value: strconv.Itoa(int(label.ID))}}
+    c.Set("user_id", uint(1))
+    DetachTaskLabel(c)
+    if w.Code != http.StatusOK { t.Fatalf("expected 200, got %d", w.Code) }
+    if etag() != before { t.Fatal("expected the ETag to stay the same after detaching a label that was not attached") }
+
+    if code := attachLabels(t, 1, parent.ID, label.ID); code != http.StatusOK { t.Fatalf("expected 200, got %d", code) }
+    changed("deleting an attached label", func() {
+        w, c := performJSONRequest(DeleteLabel, http.MethodDelete, nil)
+        c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(label.ID))}}
+        c.Set("user_id", uint(1))
+        DeleteLabel(c)
+        if w.Code != http.StatusOK { t.Fatalf("expected 200, got %d", w.Code) }
+    })
+}
+
+func TestCreateTask_IgnoresIDAndVersionInBody(t *testing.T) {
+    setupTaskDB(t)
+    database.DB.Create(&models.User{Name: "U", Email: "fresh@example.com", Password: "Password1!"})
+    w, c := performJSONRequest(CreateTask, http.MethodPost, map[string]interface{}{"id": 42, "title": "Fresh", "version": 99})
+    c.Set("user_id", uint(1))
+    CreateTask(c)
+    if w.Code != http.StatusCreated { t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String()) }
+    var task models.Task
+    database.DB.First(&task, "title = ?", "Fresh")
+    if task.ID == 42 || task.Version != 1 { t.Fatalf("expected a new ID at version 1, got %d v%d", task.ID, task.Version) }
+    if etag := w.Header().Get("ETag"); etag != taskETag(&task) { t.Fatalf("unexpected ETag %s", etag) }
+}
+
+func TestSaveTaskVersioned_DetectsConcurrentWrite(t *testing.T) {
+    setupTaskDB(t)
+    task := models.Task{Title: "Race", UserID: 1}
+    database.DB.Create(&task)
+
+    var a, b models.Task
+    database.DB.First(&a, task.ID)
+    database.DB.First(&b, task.ID)
+    a.Title = "A"
+    if err := saveTaskVersioned(database.DB, &a); err != nil { t.Fatal(err) }
+    b.Title = "B"
+    if err := saveTaskVersioned(database.DB, &b); err != errVersionConflict { t.Fatalf("expected errVersionConflict, got %v", err) }
+}
+
+type ginRecorder struct {
+    code int
+    etag string
+}
+
+func TestEtagMatches(t *testing.T) {
+    cases := []struct {
+        header string
+        want   bool
+    }{
+        {`"1-2"`, true},
+        {`W/"1-2"`, true},
+        {`"1-1", "1-2"`, true},
+        {`*`, true},
+        {`"1-3"`, false},
+    }
+    for _, tc := range cases {
+        if got := etagMatches(tc.header, `"1-2"`); got != tc.want {
+            t.Errorf("etagMatches(%s) = %v, want %v", tc.header, got, tc.want)
+        }
+    }
+}
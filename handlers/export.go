@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"flux/database"
+	"flux/middleware"
+	"flux/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// exportBatchSize エクスポート時に一度に読み込むタスク数
+const exportBatchSize = 200
+
+// transferContentTypes エクスポート・インポートの形式ごとの Content-Type
+var transferContentTypes = map[string]string{
+	"csv":    "text/csv; charset=utf-8",
+	"json":   "application/json",
+	"ndjson": "application/x-ndjson",
+}
+
+// taskRecordColumns CSV の列（インポート時は id と日時の列は無視する、後から追加した列は末尾に置く）
+var taskRecordColumns = []string{
+	"external_id", "id", "title", "description", "status", "priority", "start_at", "due_at",
+	"recurrence", "project_id", "labels", "completed_at", "created_at", "updated_at", "estimated_minutes",
+}
+
+// labelSeparator CSV でラベル名を区切る文字
+const labelSeparator = ";"
+
+// csvFormulaPrefixes 表計算ソフトで数式として解釈される先頭の文字
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// escapeCSVCell 数式として解釈されないよう、該当する文字で始まる値の先頭に ' を付ける。
+// 元から ' で始まる値にも付けるので、unescapeCSVCell で必ず元の値に戻せる
+func escapeCSVCell(s string) string {
+	if s != "" && (s[0] == '\'' || strings.ContainsRune(csvFormulaPrefixes, rune(s[0]))) {
+		return "'" + s
+	}
+	return s
+}
+
+// unescapeCSVCell escapeCSVCell で付けた ' を取り除く
+func unescapeCSVCell(s string) string {
+	if len(s) > 1 && s[0] == '\'' && (s[1] == '\'' || strings.ContainsRune(csvFormulaPrefixes, rune(s[1]))) {
+		return s[1:]
+	}
+	return s
+}
+
+// TaskRecord エクスポート・インポートで扱うタスクの1件分
+type TaskRecord struct {
+	ExternalID       string          `json:"external_id"`
+	ID               uint            `json:"id,omitempty"`
+	Title            string          `json:"title"`
+	Description      string          `json:"description"`
+	Status           string          `json:"status"`
+	Priority         models.Priority `json:"priority"`
+	StartAt          *time.Time      `json:"start_at"`
+	DueAt            *time.Time      `json:"due_at"`
+	Recurrence       string          `json:"recurrence"`
+	EstimatedMinutes *int            `json:"estimated_minutes"`
+	ProjectID        *uint           `json:"project_id"`
+	Labels           []string        `json:"labels"`
+	CompletedAt      *time.Time      `json:"completed_at,omitempty"`
+	CreatedAt        *time.Time      `json:"created_at,omitempty"`
+	UpdatedAt        *time.Time      `json:"updated_at,omitempty"`
+	// 他のサービスからのインポートでのみ使う（nil なら既存の値を変えない）
+	ParentID    *uint  `json:"-"`
+	AssigneeIDs []uint `json:"-"`
+}
+
+func newTaskRecord(task *models.Task) TaskRecord {
+	labels := make([]string, 0, len(task.Labels))
+	for _, l := range task.Labels {
+		labels = append(labels, l.Name)
+	}
+	createdAt, updatedAt := task.CreatedAt, task.UpdatedAt
+	return TaskRecord{
+		ExternalID:       task.ExternalID,
+		ID:               task.ID,
+		Title:            task.Title,
+		Description:      task.Description,
+		Status:           task.Status,
+		Priority:         task.Priority,
+		StartAt:          task.StartAt,
+		DueAt:            task.DueAt,
+		Recurrence:       task.Recurrence,
+		EstimatedMinutes: task.EstimatedMinutes,
+		ProjectID:        task.ProjectID,
+		Labels:           labels,
+		CompletedAt:      task.CompletedAt,
+		CreatedAt:        &createdAt,
+		UpdatedAt:        &updatedAt,
+	}
+}
+
+// ExportTasks streams the caller's tasks matching the list filters as csv, json or ndjson
+func ExportTasks(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "認証が必要です"})
+		return
+	}
+	format := c.DefaultQuery("format", "json")
+	contentType, ok := transferContentTypes[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be one of csv, json, ndjson"})
+		return
+	}
+	query, err := applyTaskFilters(c, database.DB.Model(&models.Task{}).Where("user_id = ?", userID))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query = excludeArchivedProjects(c, query).Preload("Labels")
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="tasks.`+format+`"`)
+	c.Status(http.StatusOK)
+
+	// 全件をメモリに載せないよう、一定件数ずつ読み込んで書き出す
+	w := newTaskRecordWriter(format, c.Writer)
+	var batch []models.Task
+	err = query.FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+		for i := range batch {
+			if err := w.Write(newTaskRecord(&batch[i])); err != nil {
+				return err
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	}).Error
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		// 書き出し開始後はステータスを変えられないので記録だけ残す
+		log.Printf("failed to export tasks for user %d: %v", userID, err)
+	}
+}
+
+// taskRecordWriter タスクを1件ずつ書き出す（Flush でバッファした分を書き出す）
+type taskRecordWriter interface {
+	Write(rec TaskRecord) error
+	Flush() error
+	Close() error
+}
+
+func newTaskRecordWriter(format string, w io.Writer) taskRecordWriter {
+	switch format {
+	case "csv":
+		return &csvRecordWriter{w: csv.NewWriter(w)}
+	case "ndjson":
+		return &ndjsonRecordWriter{enc: json.NewEncoder(w)}
+	}
+	return &jsonRecordWriter{w: w}
+}
+
+type csvRecordWriter struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+func (cw *csvRecordWriter) header() error {
+	if cw.wroteHeader {
+		return nil
+	}
+	cw.wroteHeader = true
+	return cw.w.Write(taskRecordColumns)
+}
+
+func (cw *csvRecordWriter) Write(rec TaskRecord) error {
+	if err := cw.header(); err != nil {
+		return err
+	}
+	timeCell := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339)
+	}
+	projectID, estimate := "", ""
+	if rec.ProjectID != nil {
+		projectID = strconv.Itoa(int(*rec.ProjectID))
+	}
+	if rec.EstimatedMinutes != nil {
+		estimate = strconv.Itoa(*rec.EstimatedMinutes)
+	}
+	// 利用者が入力した文字列は数式として開かれないようにする
+	return cw.w.Write([]string{
+		escapeCSVCell(rec.ExternalID), strconv.Itoa(int(rec.ID)), escapeCSVCell(rec.Title), escapeCSVCell(rec.Description),
+		rec.Status, rec.Priority.String(), timeCell(rec.StartAt), timeCell(rec.DueAt), escapeCSVCell(rec.Recurrence), projectID,
+		escapeCSVCell(strings.Join(rec.Labels, labelSeparator)), timeCell(rec.CompletedAt), timeCell(rec.CreatedAt), timeCell(rec.UpdatedAt), estimate,
+	})
+}
+
+func (cw *csvRecordWriter) Flush() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+func (cw *csvRecordWriter) Close() error {
+	if err := cw.header(); err != nil {
+		return err
+	}
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// jsonRecordWriter 配列を1要素ずつ書き出す
+type jsonRecordWriter struct {
+	w     io.Writer
+	count int
+}
+
+func (jw *jsonRecordWriter) Write(rec TaskRecord) error {
+	sep := ",\n"
+	if jw.count == 0 {
+		sep = "[\n"
+	}
+	jw.count++
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(jw.w, sep); err != nil {
+		return err
+	}
+	_, err = jw.w.Write(data)
+	return err
+}
+
+func (jw *jsonRecordWriter) Flush() error { return nil }
+
+func (jw *jsonRecordWriter) Close() error {
+	end := "\n]\n"
+	if jw.count == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(jw.w, end)
+	return err
+}
+
+type ndjsonRecordWriter struct {
+	enc *json.Encoder
+}
+
+func (nw *ndjsonRecordWriter) Write(rec TaskRecord) error { return nw.enc.Encode(rec) }
+
+func (nw *ndjsonRecordWriter) Flush() error { return nil }
+
+func (nw *ndjsonRecordWriter) Close() error { return nil }
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+    "bufio"
+    "encoding/csv"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "flux/database"
+    "flux/models"
+)
+
+func runExport(userID uint, query string) *httptest.ResponseRecorder {
+    w, c := performJSONRequest(ExportTasks, http.MethodGet, nil)
+    c.Request.URL.RawQuery = query
+    c.Set("user_id", userID)
+    ExportTasks(c)
+    return w
+}
+
+func TestExportTasks_Formats(t *testing.T) {
+    setupTaskDB(t)
+    due := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+    label := models.Label{Name: "billing", UserID: 1}
+    database.DB.Create(&label)
+    mine := models.Task{Title: "Invoice, March", UserID: 1, DueAt: &due, ExternalID: "ext-1", Labels: []models.Label{label}}
+    done := models.Task{Title: "Done", UserID: 1, Status: models.StatusCompleted}
+    theirs := models.Task{Title: "Theirs", UserID: 2}
+    for _, task := range []*models.Task{&mine, &done, &theirs} {
+        if err := database.DB.Create(task).Error; err != nil { t.Fatal(err) }
+    }
+
+    w := runExport(1, "format=csv&status=pending")
+    if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") { t.Fatalf("unexpected response: %d %s", w.Code, w.Header().Get("Content-Type")) }
+    rows, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+    if err != nil { t.Fatal(err) }
+    if len(rows) != 2 || rows[1][0] != "ext-1" || rows[1][2] != "Invoice, March" || rows[1][7] != "2026-03-01T09:00:00Z" || rows[1][10] != "billing" {
+        t.Fatalf("unexpected csv: %v", rows)
+    }
+
+    w = runExport(1, "format=json")
+    var records []TaskRecord
+    if err := json.Unmarshal(w.Body.Bytes(), &records); err != nil { t.Fatalf("invalid json: %v\n%s", err, w.Body.String()) }
+    if len(records) != 2 { t.Fatalf("expected only the caller's 2 tasks, got %d", len(records)) }
+
+    w = runExport(1, "format=ndjson&status=completed")
+    lines := 0
+    for sc := bufio.NewScanner(strings.NewReader(w.Body.String())); sc.Scan(); lines++ {
+        var rec TaskRecord
+        if err := json.Unmarshal(sc.Bytes(), &rec); err != nil || rec.Title != "Done" { t.Fatalf("unexpected line %q: %v", sc.Text(), err) }
+    }
+    if lines != 1 { t.Fatalf("expected 1 line, got %d", lines) }
+
+    if w := runExport(1, "format=xml"); w.Code != http.StatusBadRequest { t.Fatalf("expected 400, got %d", w.Code) }
+}
+
+func TestExportTasks_CSVEscapesFormulas(t *testing.T) {
+    setupTaskDB(t)
+    tasks := []models.Task{
+        {Title: "=HYPERLINK(\"http://evil\")", Description: "-1+2", UserID: 1, ExternalID: "@x"},
+        // 元から ' で始まる値や、タブで始まる値
+        {Title: "'=x", Description: "\t=cmd", UserID: 1, ExternalID: "'y"},
+    }
+    for i := range tasks {
+        if err := database.DB.Create(&tasks[i]).Error; err != nil { t.Fatal(err) }
+    }
+
+    w := runExport(1, "format=csv&sort=id&order=asc")
+    rows, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+    if err != nil { t.Fatal(err) }
+    if len(rows) != 3 || rows[1][0] != "'@x" || rows[1][2] != "'=HYPERLINK(\"http://evil\")" || rows[1][3] != "'-1+2" {
+        t.Fatalf("expected escaped cells, got %v", rows)
+    }
+    if rows[2][0] != "''y" || rows[2][2] != "''=x" || rows[2][3] != "'\t=cmd" { t.Fatalf("expected escaped cells, got %v", rows[2]) }
+
+    // インポート時は付けた ' を取り除き、元の値に戻る
+    code, report := runImportRequest(t, 2, "format=csv", "text/csv", w.Body.String())
+    if code != http.StatusOK || report.Created != 2 { t.Fatalf("unexpected report: %d %+v", code, report) }
+    for i, task := range tasks {
+        var imported models.Task
+        database.DB.First(&imported, report.Rows[i].TaskID)
+        if imported.Title != task.Title || imported.Description != task.Description || imported.ExternalID != task.ExternalID {
+            t.Fatalf("unexpected round trip: %+v", imported)
+        }
+    }
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"flux/database"
+	"flux/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var taskEventListSpec = listSpec{
+	sortFields: map[string]string{
+		"id":         "id",
+		"created_at": "created_at",
+	},
+	defaultSort: "id",
+	preloads:    []string{"Actor"},
+}
+
+// GetTaskHistory retrieves the activity history of a task, oldest first
+func GetTaskHistory(c *gin.Context) {
+	task, ok := findVisibleTask(c)
+	if !ok {
+		return
+	}
+
+	query := database.DB.Model(&models.TaskEvent{}).Where("task_id = ?", task.ID)
+	if v := c.Query("type"); v != "" {
+		query = query.Where("type = ?", v)
+	}
+	var events []models.TaskEvent
+	respondList(c, query, taskEventListSpec, &events)
+}
+
+// recordTaskEvent writes a history event inside the transaction of the change it
+// describes. actorID 0 marks a change made automatically as a side effect.
+func recordTaskEvent(tx *gorm.DB, taskID, actorID uint, eventType string, changes map[string]models.FieldChange) error {
+	event := models.TaskEvent{TaskID: taskID, Type: eventType, Changes: changes}
+	if actorID != 0 {
+		event.ActorID = &actorID
+	}
+	return tx.Create(&event).Error
+}
+
+// recordTaskUpdate writes an update event when before and after differ in a tracked field
+func recordTaskUpdate(tx *gorm.DB, before, after *models.Task, actorID uint) error {
+	changes := models.DiffTasks(before, after)
+	if len(changes) == 0 {
+		return nil
+	}
+	return recordTaskEvent(tx, after.ID, actorID, models.EventUpdated, changes)
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+    "encoding/json"
+    "net/http"
+    "strconv"
+    "testing"
+
+    "flux/database"
+    "flux/models"
+    "github.com/gin-gonic/gin"
+)
+
+func TestTaskHistory_RecordsChanges(t *testing.T) {
+    setupTaskDB(t)
+    database.DB.Create(&models.User{Name: "owner", Email: "owner@example.com"})
+    database.DB.Create(&models.User{Name: "helper", Email: "helper@example.com"})
+
+    w, c := performJSONRequest(CreateTask, http.MethodPost, models.Task{Title: "Draft"})
+    c.Set("user_id", uint(1))
+    CreateTask(c)
+    var task models.Task
+    if err := json.Unmarshal(w.Body.Bytes(), &task); err != nil { t.Fatal(err) }
+    id := strconv.Itoa(int(task.ID))
+
+    w, c = performJSONRequest(UpdateTask, http.MethodPut, models.Task{Title: "Final", Status: models.StatusInProgress})
+    c.Params = []gin.Param{{Key: "id", Value: id}}
+    c.Set("user_id", uint(1))
+    UpdateTask(c)
+    if w.Code != http.StatusOK { t.Fatalf("expected 200, got %d", w.Code) }
+
+    // 変更のない更新は記録しない
+    w, c = performJSONRequest(UpdateTask, http.MethodPut, models.Task{Title: "Final", Status: models.StatusInProgress})
+    c.Params = []gin.Param{{Key: "id", Value: id}}
+    c.Set("user_id", uint(1))
+    UpdateTask(c)
+
+    w, c = performJSONRequest(AssignTask, http.MethodPost, AssigneeIDsRequest{UserIDs: []uint{2}})
+    c.Params = []gin.Param{{Key: "id", Value: id}}
+    c.Set("user_id", uint(1))
+    AssignTask(c)
+    postComment(t, 2, task.ID, "on it")
+
+    w, c = performJSONRequest(GetTaskHistory, http.MethodGet, nil)
+    c.Params = []gin.Param{{Key: "id", Value: id}}
+    GetTaskHistory(c)
+    var res struct {
+        Data []models.TaskEvent `json:"data"`
+    }
+    if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil { t.Fatal(err) }
+    types := []string{models.EventCreated, models.EventUpdated, models.EventAssigned, models.EventCommented}
+    if len(res.Data) != len(types) { t.Fatalf("expected %d events, got %+v", len(types), res.Data) }
+    for i, typ := range types {
+        if res.Data[i].Type != typ { t.Fatalf("event %d: expected %s, got %s", i, typ, res.Data[i].Type) }
+    }
+    update := res.Data[1]
+    if update.Actor == nil || update.Actor.ID != 1 { t.Fatalf("expected actor 1, got %+v", update.Actor) }
+    if c := update.Changes["title"]; c.From != "Draft" || c.To != "Final" { t.Fatalf("unexpected title change: %+v", c) }
+    if c := update.Changes["status"]; c.From != models.StatusPending || c.To != models.StatusInProgress { t.Fatalf("unexpected status change: %+v", c) }
+    if len(update.Changes) != 2 { t.Fatalf("expected only title and status to change, got %+v", update.Changes) }
+
+    w, c = performJSONRequest(DeleteTask, http.MethodDelete, nil)
+    c.Params = []gin.Param{{Key: "id", Value: id}}
+    c.Set("user_id", uint(1))
+    DeleteTask(c)
+    var deleted int64
+    database.DB.Model(&models.TaskEvent{}).Where("task_id = ? AND type = ?", task.ID, models.EventDeleted).Count(&deleted)
+    if deleted != 1 { t.Fatalf("expected a delete event, got %d", deleted) }
+}
+
+func TestTaskHistory_RolledBackWithFailedUpdate(t *testing.T) {
+    setupTaskDB(t)
+    _, parent := createSubtask(t, 1, "Parent", nil)
+    createSubtask(t, 1, "Child", &parent.ID)
+
+    // 未完了のサブタスクがあると完了できず、履歴も残らない
+    w, c := performJSONRequest(UpdateTask, http.MethodPut, models.Task{Title: "Parent", Status: models.StatusCompleted})
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(parent.ID))}}
+    c.Set("user_id", uint(1))
+    UpdateTask(c)
+    if w.Code != http.StatusUnprocessableEntity { t.Fatalf("expected 422, got %d", w.Code) }
+    var count int64
+    database.DB.Model(&models.TaskEvent{}).Where("task_id = ? AND type = ?", parent.ID, models.EventUpdated).Count(&count)
+    if count != 0 { t.Fatalf("expected no update event, got %d", count) }
+}
@@ -0,0 +1,609 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"flux/database"
+	"flux/middleware"
+	"flux/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// importMaxBytes インポートできるファイルの最大サイズ
+	importMaxBytes = 10 << 20
+	// importMaxRows 一度にインポートできる行数
+	importMaxRows = 5000
+)
+
+// インポート結果の各行の処理内容
+const (
+	importCreated   = "created"
+	importUpdated   = "updated"
+	importUnchanged = "unchanged"
+	importFailed    = "failed"
+)
+
+// ImportRowResult インポートの各行の結果
+type ImportRowResult struct {
+	Row        int      `json:"row"`
+	ExternalID string   `json:"external_id,omitempty"`
+	Title      string   `json:"title,omitempty"`
+	Action     string   `json:"action"`
+	TaskID     uint     `json:"task_id,omitempty"`
+	Errors     []string `json:"errors,omitempty"`
+}
+
+// ImportReport インポート結果の集計
+type ImportReport struct {
+	Source    string            `json:"source,omitempty"`
+	DryRun    bool              `json:"dry_run"`
+	Created   int               `json:"created"`
+	Updated   int               `json:"updated"`
+	Unchanged int               `json:"unchanged"`
+	Failed    int               `json:"failed"`
+	Skipped   int               `json:"skipped,omitempty"`
+	Warnings  []string          `json:"warnings,omitempty"`
+	Rows      []ImportRowResult `json:"rows"`
+}
+
+// importRow 読み込んだ1行と、読み込み時に見つかったエラー
+type importRow struct {
+	record TaskRecord
+	errs   []string
+	// parent 親タスクになる行の external_id（サブタスクの場合のみ）
+	parent string
+}
+
+var errImportRolledBack = errors.New("import rolled back")
+
+// ImportTasks imports tasks from a csv, json or ndjson upload. Rows with an
+// external_id update the caller's task with the same external_id instead of
+// creating a new one. Nothing is written if any row is invalid or dry_run=true.
+func ImportTasks(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "認証が必要です"})
+		return
+	}
+
+	r, filename, ok := importSource(c)
+	if !ok {
+		return
+	}
+	format := importFormat(c, filename)
+	if _, ok := transferContentTypes[format]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be one of csv, json, ndjson"})
+		return
+	}
+	rows, err := readTaskRecords(format, r)
+	if err != nil {
+		respondImportReadError(c, err)
+		return
+	}
+
+	report := runImport(userID, rows, c.Query("dry_run") == "true")
+	if report == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "import failed"})
+		return
+	}
+	status := http.StatusOK
+	if report.Failed > 0 && !report.DryRun {
+		status = http.StatusUnprocessableEntity
+	}
+	c.JSON(status, report)
+}
+
+// runImport upserts the rows in one transaction, rolling it back for a dry run or
+// when any row fails. It returns nil if the transaction itself failed.
+func runImport(userID uint, rows []importRow, dryRun bool) *ImportReport {
+	report := &ImportReport{DryRun: dryRun, Rows: make([]ImportRowResult, 0, len(rows))}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		seen := map[string]bool{}
+		// 取り込めた行の external_id -> タスク ID（サブタスクの親の解決に使う）
+		taskIDs := map[string]uint{}
+		for i, row := range rows {
+			result := ImportRowResult{Row: i + 1, ExternalID: row.record.ExternalID, Title: row.record.Title, Errors: row.errs}
+			if id := row.record.ExternalID; id != "" {
+				if seen[id] {
+					result.Errors = append(result.Errors, "duplicate external_id in file")
+				}
+				seen[id] = true
+			}
+			if row.parent != "" {
+				parentID, ok := taskIDs[row.parent]
+				if !ok {
+					result.Errors = append(result.Errors, "parent task was not imported")
+				}
+				row.record.ParentID = &parentID
+			}
+			if len(result.Errors) == 0 {
+				// 行ごとにセーブポイントを作り、失敗しても後続の行の検証を続ける
+				err := tx.Transaction(func(tx *gorm.DB) error {
+					var err error
+					result.Action, result.TaskID, err = upsertTaskRecord(tx, userID, &row.record)
+					return err
+				})
+				if err != nil {
+					result.Errors = append(result.Errors, err.Error())
+				} else if row.record.ExternalID != "" {
+					taskIDs[row.record.ExternalID] = result.TaskID
+				}
+			}
+			report.add(result)
+		}
+		if dryRun || report.Failed > 0 {
+			return errImportRolledBack
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errImportRolledBack) {
+		return nil
+	}
+	if dryRun {
+		// ロールバックしたので新規作成分の ID は存在しない
+		for i := range report.Rows {
+			if report.Rows[i].Action == importCreated {
+				report.Rows[i].TaskID = 0
+			}
+		}
+	}
+	return report
+}
+
+func (r *ImportReport) add(result ImportRowResult) {
+	if len(result.Errors) > 0 {
+		result.Action, result.TaskID = importFailed, 0
+	}
+	switch result.Action {
+	case importCreated:
+		r.Created++
+	case importUpdated:
+		r.Updated++
+	case importUnchanged:
+		r.Unchanged++
+	case importFailed:
+		r.Failed++
+	}
+	r.Rows = append(r.Rows, result)
+}
+
+// upsertTaskRecord creates a task from rec, or updates the caller's task with the
+// same external_id, and returns what was done
+func upsertTaskRecord(tx *gorm.DB, userID uint, rec *TaskRecord) (string, uint, error) {
+	title := strings.TrimSpace(rec.Title)
+	if title == "" {
+		return "", 0, errors.New("title is required")
+	}
+	if utf8.RuneCountInString(title) > 200 {
+		return "", 0, errors.New("title must be at most 200 characters")
+	}
+
+	var task models.Task
+	if rec.ExternalID != "" {
+		err := tx.Where("user_id = ? AND external_id = ?", userID, rec.ExternalID).First(&task).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", 0, err
+		}
+	}
+	isNew := task.ID == 0
+	if !isNew && !canAccessTask(tx, &task, userID, taskActionEdit) {
+		return "", 0, errors.New("権限がありません")
+	}
+	before := task
+
+	task.UserID = userID
+	task.ExternalID = rec.ExternalID
+	task.Title = title
+	task.Description = rec.Description
+	task.Priority = rec.Priority
+	if task.Priority == 0 {
+		task.Priority = models.PriorityMedium
+	}
+	task.StartAt, task.DueAt, task.Recurrence = rec.StartAt, rec.DueAt, rec.Recurrence
+	task.EstimatedMinutes = rec.EstimatedMinutes
+	status := rec.Status
+	if status == "" {
+		status = models.StatusPending
+	}
+	if err := task.SetStatus(status, time.Now()); err != nil {
+		return "", 0, err
+	}
+	task.ProjectID = nil
+	if rec.ProjectID != nil && *rec.ProjectID != 0 {
+		if err := validateProject(tx, &task, *rec.ProjectID, userID); err != nil {
+			return "", 0, err
+		}
+		task.ProjectID = rec.ProjectID
+	}
+	if rec.ParentID != nil {
+		if err := validateParent(tx, &task, *rec.ParentID); err != nil {
+			return "", 0, err
+		}
+		task.ParentID = rec.ParentID
+	}
+	if err := task.Validate(); err != nil {
+		return "", 0, err
+	}
+	labels, err := importLabels(tx, userID, rec.Labels)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if isNew {
+		if task.Rank, err = appendRank(tx, &task); err != nil {
+			return "", 0, err
+		}
+		if err := tx.Create(&task).Error; err != nil {
+			return "", 0, err
+		}
+		if err := recordTaskEvent(tx, task.ID, userID, models.EventCreated, nil); err != nil {
+			return "", 0, err
+		}
+		if err := bumpRelatedVersions(tx, nil, &task); err != nil {
+			return "", 0, err
+		}
+		if len(labels) > 0 {
+			if err := tx.Model(&task).Association("Labels").Replace(&labels); err != nil {
+				return "", 0, err
+			}
+		}
+		if len(rec.AssigneeIDs) > 0 {
+			users := usersByID(rec.AssigneeIDs)
+			if err := tx.Model(&task).Association("Assignees").Replace(&users); err != nil {
+				return "", 0, err
+			}
+		}
+		return importCreated, task.ID, nil
+	}
+
+	// 既存タスクは内容が変わった場合だけ更新する
+	current, err := taskLabelIDs(tx, task.ID)
+	if err != nil {
+		return "", 0, err
+	}
+	labelsChanged := !sameIDs(current, labelIDs(labels))
+	assigneesChanged := false
+	if rec.AssigneeIDs != nil {
+		currentAssignees, err := assigneeIDs(tx, task.ID)
+		if err != nil {
+			return "", 0, err
+		}
+		assigneesChanged = !sameIDs(currentAssignees, rec.AssigneeIDs)
+	}
+	fieldsChanged := len(models.DiffTasks(&before, &task)) > 0
+	if !fieldsChanged && !labelsChanged && !assigneesChanged {
+		return importUnchanged, task.ID, nil
+	}
+	if fieldsChanged {
+		// ステータスの変更は UpdateTask と同じ規則で確認する（force・cascade は使えない）
+		now := time.Now()
+		if task.Status == models.StatusInProgress && before.Status != models.StatusInProgress {
+			if err := checkNotBlocked(tx, &task, false); err != nil {
+				return "", 0, err
+			}
+		}
+		if task.Status == models.StatusCompleted && before.Status != models.StatusCompleted {
+			if err := completeSubtasks(tx, &task, false, now); err != nil {
+				return "", 0, err
+			}
+			if err := spawnNextOccurrence(tx, &task, now); err != nil {
+				return "", 0, err
+			}
+		}
+		if err := saveTaskVersioned(tx, &task); err != nil {
+			return "", 0, err
+		}
+		if err := bumpRelatedVersions(tx, &before, &task); err != nil {
+			return "", 0, err
+		}
+		if err := recordTaskUpdate(tx, &before, &task, userID); err != nil {
+			return "", 0, err
+		}
+	}
+	if labelsChanged {
+		if err := tx.Model(&task).Association("Labels").Replace(&labels); err != nil {
+			return "", 0, err
+		}
+		if !fieldsChanged {
+			if err := bumpTaskVersion(tx, task.ID); err != nil {
+				return "", 0, err
+			}
+		}
+	}
+	if assigneesChanged {
+		users := usersByID(rec.AssigneeIDs)
+		err := changeAssignees(tx, &task, userID, models.EventAssigned, func(a *gorm.Association) error {
+			return a.Replace(&users)
+		})
+		if err != nil {
+			return "", 0, err
+		}
+	}
+	return importUpdated, task.ID, nil
+}
+
+// importLabels finds the caller's personal labels by name, creating the missing ones
+func importLabels(tx *gorm.DB, userID uint, names []string) ([]models.Label, error) {
+	var labels []models.Label
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		if utf8.RuneCountInString(name) > 50 {
+			return nil, fmt.Errorf("label %q must be at most 50 characters", name)
+		}
+		label := models.Label{UserID: userID, Name: name}
+		if err := tx.Where("organization_id IS NULL").Where(label).FirstOrCreate(&label).Error; err != nil {
+			return nil, err
+		}
+		labels = append(labels, label)
+	}
+	return labels, nil
+}
+
+// usersByID builds user stubs for replacing an association by ID
+func usersByID(ids []uint) []models.User {
+	users := make([]models.User, 0, len(ids))
+	for _, id := range ids {
+		users = append(users, models.User{ID: id})
+	}
+	return users
+}
+
+func taskLabelIDs(db *gorm.DB, taskID uint) ([]uint, error) {
+	ids := []uint{}
+	err := db.Table("task_labels").Where("task_id = ?", taskID).Pluck("label_id", &ids).Error
+	return ids, err
+}
+
+func labelIDs(labels []models.Label) []uint {
+	ids := make([]uint, 0, len(labels))
+	for _, l := range labels {
+		ids = append(ids, l.ID)
+	}
+	return ids
+}
+
+// sameIDs reports whether a and b hold the same IDs in any order
+func sameIDs(a, b []uint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = append([]uint(nil), a...), append([]uint(nil), b...)
+	sort.Slice(a, func(i, j int) bool { return a[i] < a[j] })
+	sort.Slice(b, func(i, j int) bool { return b[i] < b[j] })
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// importSource returns the uploaded file (multipart field "file") or the raw request
+// body, limited to importMaxBytes. On failure the error response has already been written.
+func importSource(c *gin.Context) (io.Reader, string, bool) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, importMaxBytes)
+	if c.ContentType() != "multipart/form-data" {
+		return c.Request.Body, "", true
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("file must be at most %d bytes", importMaxBytes)})
+			return nil, "", false
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return nil, "", false
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, "", false
+	}
+	return file, header.Filename, true
+}
+
+// importFormat returns the format parameter, falling back to the file extension
+// and then the request Content-Type
+func importFormat(c *gin.Context, filename string) string {
+	if format := c.Query("format"); format != "" {
+		return format
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return "csv"
+	case ".ndjson", ".jsonl":
+		return "ndjson"
+	case ".json":
+		return "json"
+	}
+	switch c.ContentType() {
+	case "text/csv":
+		return "csv"
+	case "application/x-ndjson":
+		return "ndjson"
+	}
+	return "json"
+}
+
+// respondImportReadError responds 413 for an oversized upload and 400 for a malformed one
+func respondImportReadError(c *gin.Context, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("file must be at most %d bytes", importMaxBytes)})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// readTaskRecords parses every row of an import file. Problems with a single row
+// are kept with the row; an error is returned only if the file cannot be read.
+func readTaskRecords(format string, r io.Reader) ([]importRow, error) {
+	var rows []importRow
+	add := func(row importRow) error {
+		if len(rows) == importMaxRows {
+			return fmt.Errorf("file must have at most %d rows", importMaxRows)
+		}
+		rows = append(rows, row)
+		return nil
+	}
+
+	switch format {
+	case "csv":
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = -1
+		header, err := cr.Read()
+		if err == io.EOF {
+			return nil, errors.New("file is empty")
+		}
+		if err != nil {
+			return nil, err
+		}
+		// Excel などが付ける BOM は列名に含めない
+		columns := make(map[string]int, len(header))
+		for i, name := range header {
+			columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+		}
+		if _, ok := columns["title"]; !ok {
+			return nil, errors.New("title column is required")
+		}
+		for {
+			cells, err := cr.Read()
+			if err == io.EOF {
+				return rows, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			if err := add(parseCSVRecord(columns, cells)); err != nil {
+				return nil, err
+			}
+		}
+
+	case "json":
+		dec := json.NewDecoder(r)
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+			return nil, errors.New("json import must be an array of tasks")
+		}
+		for dec.More() {
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				return nil, err
+			}
+			if err := add(parseJSONRecord(raw)); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return rows, nil
+
+	case "ndjson":
+		dec := json.NewDecoder(r)
+		for {
+			var raw json.RawMessage
+			err := dec.Decode(&raw)
+			if err == io.EOF {
+				return rows, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			if err := add(parseJSONRecord(raw)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return nil, errors.New("format must be one of csv, json, ndjson")
+}
+
+func parseJSONRecord(raw json.RawMessage) importRow {
+	var row importRow
+	if err := json.Unmarshal(raw, &row.record); err != nil {
+		row.errs = append(row.errs, err.Error())
+	}
+	row.record.ExternalID = strings.TrimSpace(row.record.ExternalID)
+	return row
+}
+
+// parseCSVRecord converts a CSV row using the header's column positions
+func parseCSVRecord(columns map[string]int, cells []string) importRow {
+	var row importRow
+	cell := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(cells) {
+			return unescapeCSVCell(strings.TrimSpace(cells[i]))
+		}
+		return ""
+	}
+	timeCell := func(name string) *time.Time {
+		v := cell(name)
+		if v == "" {
+			return nil
+		}
+		t, err := parseTimeParam(v)
+		if err != nil {
+			row.errs = append(row.errs, fmt.Sprintf("invalid %s: %s", name, v))
+			return nil
+		}
+		return &t
+	}
+
+	rec := &row.record
+	rec.ExternalID = cell("external_id")
+	rec.Title = cell("title")
+	rec.Description = cell("description")
+	rec.Status = cell("status")
+	rec.Recurrence = cell("recurrence")
+	rec.StartAt = timeCell("start_at")
+	rec.DueAt = timeCell("due_at")
+	if v := cell("priority"); v != "" {
+		p, err := models.ParsePriority(v)
+		if err != nil {
+			row.errs = append(row.errs, err.Error())
+		}
+		rec.Priority = p
+	}
+	if v := cell("estimated_minutes"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			row.errs = append(row.errs, "invalid estimated_minutes: "+v)
+		} else {
+			rec.EstimatedMinutes = &n
+		}
+	}
+	if v := cell("project_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil || id < 0 {
+			row.errs = append(row.errs, "invalid project_id: "+v)
+		} else {
+			pid := uint(id)
+			rec.ProjectID = &pid
+		}
+	}
+	for _, name := range strings.Split(cell("labels"), labelSeparator) {
+		if name = strings.TrimSpace(name); name != "" {
+			rec.Labels = append(rec.Labels, name)
+		}
+	}
+	return row
+}
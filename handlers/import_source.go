@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"flux/database"
+	"flux/importer"
+	"flux/middleware"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ImportFromSource imports an export file from another tool (trello, todoist or
+// github) and responds with an import report. Re-importing the same file updates
+// the tasks created the first time instead of duplicating them.
+func ImportFromSource(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "認証が必要です"})
+		return
+	}
+
+	var projectID *uint
+	if v := c.Query("project_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil || id < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project_id"})
+			return
+		}
+		pid := uint(id)
+		projectID = &pid
+	}
+
+	source := c.Param("source")
+	r, _, ok := importSource(c)
+	if !ok {
+		return
+	}
+	parsed, err := importer.Parse(source, r)
+	if errors.Is(err, importer.ErrUnknownSource) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "source must be one of " + strings.Join(importer.Sources(), ", ")})
+		return
+	}
+	if err != nil {
+		respondImportReadError(c, err)
+		return
+	}
+
+	rows, warnings := importerRows(database.DB, parsed.Tasks, projectID)
+	if len(rows) > importMaxRows {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("file must have at most %d tasks", importMaxRows)})
+		return
+	}
+	report := runImport(userID, rows, c.Query("dry_run") == "true")
+	if report == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "import failed"})
+		return
+	}
+	report.Source = source
+	report.Skipped = parsed.Skipped
+	report.Warnings = append(parsed.Warnings, warnings...)
+
+	status := http.StatusOK
+	if report.Failed > 0 && !report.DryRun {
+		status = http.StatusUnprocessableEntity
+	}
+	c.JSON(status, report)
+}
+
+// importerRows flattens imported tasks into rows, each parent before its subtasks,
+// and resolves assignees to Flux users. Assignees that match no single user are
+// reported as warnings.
+func importerRows(db *gorm.DB, tasks []importer.Task, projectID *uint) ([]importRow, []string) {
+	var rows []importRow
+	var warnings []string
+	unresolved := map[string]bool{}
+
+	var add func(tasks []importer.Task, parent string)
+	add = func(tasks []importer.Task, parent string) {
+		for _, t := range tasks {
+			rec := TaskRecord{
+				ExternalID:  t.ExternalID,
+				Title:       t.Title,
+				Description: t.Description,
+				Status:      t.Status,
+				Priority:    t.Priority,
+				DueAt:       t.DueAt,
+				ProjectID:   projectID,
+				Labels:      t.Labels,
+			}
+			for _, a := range t.Assignees {
+				user, ok := resolveAssignee(db, a)
+				if !ok {
+					if name := a.String(); !unresolved[name] {
+						unresolved[name] = true
+						warnings = append(warnings, fmt.Sprintf("assignee %q does not match a user", name))
+					}
+					continue
+				}
+				rec.AssigneeIDs = append(rec.AssigneeIDs, user)
+			}
+			rec.AssigneeIDs = uniqueIDs(rec.AssigneeIDs)
+			rows = append(rows, importRow{record: rec, parent: parent})
+			add(t.Subtasks, t.ExternalID)
+		}
+	}
+	add(tasks, "")
+	return rows, warnings
+}
+
+// resolveAssignee matches an imported assignee by email, then name, then login
+func resolveAssignee(db *gorm.DB, a importer.Assignee) (uint, bool) {
+	for _, handle := range []string{a.Email, a.Name, a.Login} {
+		if handle == "" {
+			continue
+		}
+		if user, ok := resolveMention(db, strings.ToLower(handle)); ok {
+			return user.ID, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "flux/database"
+    "flux/models"
+    "github.com/gin-gonic/gin"
+)
+
+const githubExport = `[
+    {"number": 1, "title": "Crash on save", "body": "- [x] reproduce\n- [ ] fix", "state": "open",
+     "labels": [{"name": "bug"}], "assignees": [{"login": "octocat"}, {"login": "ghost"}],
+     "repository_url": "https://api.github.com/repos/acme/app"},
+    {"number": 2, "title": "Old PR", "state": "closed", "pull_request": {}}
+]`
+
+func runSourceImport(t *testing.T, source, query, body string) (int, ImportReport) {
+    t.Helper()
+    gin.SetMode(gin.TestMode)
+    w := httptest.NewRecorder()
+    c, _ := gin.CreateTestContext(w)
+    c.Request, _ = http.NewRequest(http.MethodPost, "/tasks/import/"+source+"?"+query, strings.NewReader(body))
+    c.Request.Header.Set("Content-Type", "application/json")
+    c.Params = []gin.Param{{Key: "source", Value: source}}
+    c.Set("user_id", uint(1))
+    ImportFromSource(c)
+    var report ImportReport
+    if w.Code == http.StatusOK || w.Code == http.StatusUnprocessableEntity {
+        if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil { t.Fatal(err) }
+    }
+    return w.Code, report
+}
+
+func TestImportFromSource_GitHubIssues(t *testing.T) {
+    setupTaskDB(t)
+    database.DB.Create(&models.User{Name: "owner", Email: "owner@example.com"})
+    database.DB.Create(&models.User{Name: "octocat", Email: "octo@example.com"})
+
+    code, report := runSourceImport(t, "github", "", githubExport)
+    if code != http.StatusOK || report.Source != "github" || report.Created != 3 || report.Skipped != 1 { t.Fatalf("unexpected report: %d %+v", code, report) }
+    if len(report.Warnings) != 1 || !strings.Contains(report.Warnings[0], "ghost") { t.Fatalf("expected a warning for the unknown assignee, got %v", report.Warnings) }
+
+    var issue models.Task
+    database.DB.Preload("Labels").Preload("Assignees").Where("external_id = ?", "github:acme/app#1").First(&issue)
+    if len(issue.Labels) != 1 || len(issue.Assignees) != 1 || issue.Assignees[0].Name != "octocat" { t.Fatalf("unexpected issue: %+v", issue) }
+    var subtasks []models.Task
+    database.DB.Where("parent_id = ?", issue.ID).Order("id").Find(&subtasks)
+    if len(subtasks) != 2 || subtasks[0].Status != models.StatusCompleted || subtasks[1].Title != "fix" { t.Fatalf("unexpected subtasks: %+v", subtasks) }
+
+    // 同じファイルを再度取り込んでも重複しない
+    code, report = runSourceImport(t, "github", "", githubExport)
+    if code != http.StatusOK || report.Unchanged != 3 { t.Fatalf("expected unchanged rows, got %d %+v", code, report) }
+
+    if code, _ := runSourceImport(t, "jira", "", githubExport); code != http.StatusBadRequest { t.Fatalf("expected 400 for unknown source, got %d", code) }
+    if code, _ := runSourceImport(t, "trello", "", `{"oops"`); code != http.StatusBadRequest { t.Fatalf("expected 400 for malformed file, got %d", code) }
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "flux/database"
+    "flux/models"
+    "github.com/gin-gonic/gin"
+)
+
+func runImportRequest(t *testing.T, userID uint, query, contentType, body string) (int, ImportReport) {
+    t.Helper()
+    gin.SetMode(gin.TestMode)
+    w := httptest.NewRecorder()
+    c, _ := gin.CreateTestContext(w)
+    c.Request, _ = http.NewRequest(http.MethodPost, "/tasks/import?"+query, strings.NewReader(body))
+    c.Request.Header.Set("Content-Type", contentType)
+    c.Set("user_id", userID)
+    ImportTasks(c)
+    var report ImportReport
+    if w.Code == http.StatusOK || w.Code == http.StatusUnprocessableEntity {
+        if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil { t.Fatal(err) }
+    }
+    return w.Code, report
+}
+
+func TestImportTasks_DryRunAndIdempotentUpsert(t *testing.T) {
+    setupTaskDB(t)
+    body := `[
+        {"external_id": "A-1", "title": "Write spec", "priority": "high", "labels": ["docs"]},
+        {"external_id": "A-2", "title": "Review spec", "status": "in_progress"}
+    ]`
+
+    code, report := runImportRequest(t, 1, "dry_run=true", "application/json", body)
+    if code != http.StatusOK || !report.DryRun || report.Created != 2 { t.Fatalf("unexpected dry run: %d %+v", code, report) }
+    var count int64
+    database.DB.Model(&models.Task{}).Count(&count)
+    if count != 0 { t.Fatalf("dry run wrote %d tasks", count) }
+
+    code, report = runImportRequest(t, 1, "", "application/json", body)
+    if code != http.StatusOK || report.Created != 2 || report.Rows[0].TaskID == 0 { t.Fatalf("unexpected import: %d %+v", code, report) }
+
+    // 同じ内容なら何も変わらない
+    code, report = runImportRequest(t, 1, "", "application/json", body)
+    if code != http.StatusOK || report.Unchanged != 2 { t.Fatalf("expected unchanged rows, got %d %+v", code, report) }
+
+    ndjson := `{"external_id": "A-1", "title": "Write spec v2", "priority": "high", "labels": ["docs"]}` + "\n"
+    code, report = runImportRequest(t, 1, "format=ndjson", "application/x-ndjson", ndjson)
+    if code != http.StatusOK || report.Updated != 1 { t.Fatalf("expected one update, got %d %+v", code, report) }
+
+    var task models.Task
+    database.DB.Preload("Labels").Where("external_id = ?", "A-1").First(&task)
+    if task.Title != "Write spec v2" || task.Priority != models.PriorityHigh || len(task.Labels) != 1 || task.Version != 2 {
+        t.Fatalf("unexpected task: %+v", task)
+    }
+    database.DB.Model(&models.Task{}).Count(&count)
+    if count != 2 { t.Fatalf("expected 2 tasks after re-imports, got %d", count) }
+}
+
+func TestImportTasks_CSVRowErrors(t *testing.T) {
+    setupTaskDB(t)
+    body := "external_id,title,priority,due_at,labels\n" +
+        "C-1,Call customer,low,2026-04-01,sales;phone\n" +
+        "C-2,,medium,,\n" +
+        "C-3,Bad date,high,tomorrow,\n" +
+        "C-1,Duplicate,low,,\n"
+
+    code, report := runImportRequest(t, 1, "format=csv", "text/csv", body)
+    if code != http.StatusUnprocessableEntity || report.Failed != 3 || report.Created != 1 { t.Fatalf("unexpected report: %d %+v", code, report) }
+    for _, row := range report.Rows[1:] {
+        if row.Action != importFailed || len(row.Errors) == 0 { t.Fatalf("expected row %d to fail: %+v", row.Row, row) }
+    }
+    var count int64
+    database.DB.Model(&models.Task{}).Count(&count)
+    if count != 0 { t.Fatalf("expected nothing written when rows fail, got %d", count) }
+
+    code, report = runImportRequest(t, 1, "", "text/csv", "external_id,title,priority,due_at,labels\nC-1,Call customer,low,2026-04-01,sales;phone\n")
+    if code != http.StatusOK || report.Created != 1 { t.Fatalf("unexpected report: %d %+v", code, report) }
+    var task models.Task
+    database.DB.Preload("Labels").First(&task, report.Rows[0].TaskID)
+    if task.Priority != models.PriorityLow || task.DueAt == nil || len(task.Labels) != 2 { t.Fatalf("unexpected task: %+v", task) }
+}
+
+func TestImportTasks_StatusRulesAndRuneLengths(t *testing.T) {
+    setupTaskDB(t)
+
+    // 文字数はバイト数ではなく文字で数える
+    title := strings.Repeat("あ", 70)
+    label := strings.Repeat("ラ", 20)
+    body := `[{"external_id": "J-1", "title": "` + title + `", "labels": ["` + label + `"]}]`
+    code, report := runImportRequest(t, 1, "", "application/json", body)
+    if code != http.StatusOK || report.Created != 1 { t.Fatalf("unexpected import: %d %+v", code, report) }
+    body = `[{"title": "` + strings.Repeat("あ", 201) + `"}]`
+    if code, _ = runImportRequest(t, 1, "", "application/json", body); code != http.StatusUnprocessableEntity { t.Fatalf("expected 422 for a long title, got %d", code) }
+
+    // 未完了のサブタスクがある親は完了にできない
+    parent := models.Task{Title: "Parent", UserID: 1, ExternalID: "P"}
+    database.DB.Create(&parent)
+    child := models.Task{Title: "Child", UserID: 1, ParentID: &parent.ID}
+    database.DB.Create(&child)
+    code, report = runImportRequest(t, 1, "", "application/json", `[{"external_id": "P", "title": "Parent", "status": "completed"}]`)
+    if code != http.StatusUnprocessableEntity || report.Failed != 1 { t.Fatalf("expected the completion to fail, got %d %+v", code, report) }
+
+    // ブロックされているタスクは着手にできない
+    blocker := models.Task{Title: "Blocker", UserID: 1}
+    database.DB.Create(&blocker)
+    database.DB.Create(&models.TaskDependency{TaskID: parent.ID, BlockedByID: blocker.ID})
+    code, report = runImportRequest(t, 1, "", "application/json", `[{"external_id": "P", "title": "Parent", "status": "in_progress"}]`)
+    if code != http.StatusUnprocessableEntity || report.Failed != 1 { t.Fatalf("expected the start to fail, got %d %+v", code, report) }
+
+    var saved models.Task
+    database.DB.First(&saved, parent.ID)
+    if saved.Status != models.StatusPending { t.Fatalf("expected the parent to stay pending, got %s", saved.Status) }
+}
@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"flux/database"
+	"flux/middleware"
+	"flux/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// LabelIDsRequest ラベル付与リクエスト
+type LabelIDsRequest struct {
+	LabelIDs []uint `json:"label_ids" binding:"required,min=1"`
+}
+
+// GetLabels retrieves the caller's personal labels and the labels of their organizations
+// (only one organization's labels with organization_id)
+func GetLabels(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "認証が必要です"})
+		return
+	}
+
+	query := usableLabels(database.DB, userID)
+	if v := c.Query("organization_id"); v != "" {
+		oid, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid organization_id"})
+			return
+		}
+		query = query.Where("organization_id = ?", oid)
+	}
+	var labels []models.Label
+	if err := query.Order("name, id").Find(&labels).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, labels)
+}
+
+// CreateLabel creates a personal label, or an organization label when organization_id is given
+func CreateLabel(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "認証が必要です"})
+		return
+	}
+
+	var label models.Label
+	if err := c.ShouldBindJSON(&label); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	label.ID = 0
+	label.UserID = userID
+
+	// 組織のラベルは編集権限を持つメンバーのみ作成できる
+	if label.OrganizationID != nil && *label.OrganizationID == 0 {
+		label.OrganizationID = nil
+	}
+	if label.OrganizationID != nil {
+		member, ok := findMember(database.DB, *label.OrganizationID, userID)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "organization not found"})
+			return
+		}
+		if !member.CanEdit() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "権限がありません"})
+			return
+		}
+	}
+
+	if labelNameTaken(&label, label.Name) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Label name already exists"})
+		return
+	}
+	if err := database.DB.Create(&label).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, label)
+}
+
+// UpdateLabel updates a label's name or color. A label cannot move between the caller and an organization.
+func UpdateLabel(c *gin.Context) {
+	label, ok := findEditableLabel(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var updateData models.Label
+	if err := c.ShouldBindJSON(&updateData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if labelNameTaken(&label, updateData.Name) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Label name already exists"})
+		return
+	}
+
+	label.Name = updateData.Name
+	label.Color = updateData.Color
+	if err := database.DB.Save(&label).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, label)
+}
+
+// DeleteLabel deletes a label and detaches it from all tasks
+func DeleteLabel(c *gin.Context) {
+	label, ok := findEditableLabel(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := bumpTaskVersions(tx, tx.Table("task_labels").Select("task_id").Where("label_id = ?", label.ID)); err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM task_labels WHERE label_id = ?", label.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&label).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Label deleted successfully"})
+}
+
+// AttachTaskLabels attaches the caller's labels to a task
+func AttachTaskLabels(c *gin.Context) {
+	task, ok := findAuthorizedTask(c, taskActionEdit)
+	if !ok {
+		return
+	}
+
+	var req LabelIDsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 付与できるのは操作しているユーザー自身のラベルと、タスクと同じ組織のラベルのみ
+	userID, _ := middleware.GetUserID(c)
+	var labels []models.Label
+	if err := usableLabels(database.DB, userID).Where("id IN ?", req.LabelIDs).Find(&labels).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(labels) != len(uniqueIDs(req.LabelIDs)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Label not found"})
+		return
+	}
+	if err := checkLabelScope(labels, &task); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&task).Association("Labels").Append(&labels); err != nil {
+			return err
+		}
+		return bumpTaskVersion(tx, task.ID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respondTaskLabels(c, task)
+}
+
+// DetachTaskLabel removes a label from a task
+func DetachTaskLabel(c *gin.Context) {
+	task, ok := findAuthorizedTask(c, taskActionEdit)
+	if !ok {
+		return
+	}
+
+	labelID, err := strconv.Atoi(c.Param("label_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid label ID"})
+		return
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// 付いていないラベルを外しても変更にはしない
+		result := tx.Exec("DELETE FROM task_labels WHERE task_id = ? AND label_id = ?", task.ID, labelID)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return bumpTaskVersion(tx, task.ID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respondTaskLabels(c, task)
+}
+
+func respondTaskLabels(c *gin.Context, task models.Task) {
+	if err := database.DB.Preload("Labels").First(&task, task.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, task)
+}
+
+// findEditableLabel loads a label and checks that the caller owns it, or may edit in its organization.
+// On failure the error response has already been written.
+func findEditableLabel(c *gin.Context, id string) (models.Label, bool) {
+	var label models.Label
+	if err := database.DB.First(&label, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Label not found"})
+		return label, false
+	}
+
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "認証が必要です"})
+		return label, false
+	}
+	if label.OrganizationID != nil {
+		if member, ok := findMember(database.DB, *label.OrganizationID, userID); !ok || !member.CanEdit() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "権限がありません"})
+			return label, false
+		}
+	} else if label.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "権限がありません"})
+		return label, false
+	}
+	return label, true
+}
+
+// labelNameTaken reports whether another label in the same scope (the user's personal
+// labels or the organization's labels) already has the name
+func labelNameTaken(label *models.Label, name string) bool {
+	query := database.DB.Model(&models.Label{}).Where("name = ? AND id <> ?", name, label.ID)
+	if label.OrganizationID != nil {
+		query = query.Where("organization_id = ?", *label.OrganizationID)
+	} else {
+		query = query.Where("organization_id IS NULL AND user_id = ?", label.UserID)
+	}
+	var count int64
+	query.Count(&count)
+	return count > 0
+}
+
+// usableLabels selects the labels the user can see and attach: their personal labels and
+// the labels of organizations they belong to
+func usableLabels(db *gorm.DB, userID uint) *gorm.DB {
+	return db.Model(&models.Label{}).Where(
+		"(organization_id IS NULL AND user_id = ?) OR organization_id IN (SELECT organization_id FROM organization_members WHERE user_id = ?)",
+		userID, userID)
+}
+
+// checkLabelScope checks that organization labels are only attached to tasks of that organization
+func checkLabelScope(labels []models.Label, task *models.Task) error {
+	for _, l := range labels {
+		if l.OrganizationID != nil && (task.OrganizationID == nil || *task.OrganizationID != *l.OrganizationID) {
+			return errors.New("organization labels can only be attached to tasks of that organization")
+		}
+	}
+	return nil
+}
+
+// applyLabelFilter filters tasks having any or all of the comma separated label IDs
+func applyLabelFilter(db *gorm.DB, ids, match string) (*gorm.DB, error) {
+	var labelIDs []uint
+	for _, v := range strings.Split(ids, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || id < 1 {
+			return nil, errors.New("invalid labels")
+		}
+		labelIDs = append(labelIDs, uint(id))
+	}
+	labelIDs = uniqueIDs(labelIDs)
+
+	switch match {
+	case "any":
+		return db.Where("id IN (SELECT task_id FROM task_labels WHERE label_id IN ?)", labelIDs), nil
+	case "all":
+		return db.Where("id IN (SELECT task_id FROM task_labels WHERE label_id IN ? GROUP BY task_id HAVING COUNT(DISTINCT label_id) = ?)",
+			labelIDs, len(labelIDs)), nil
+	}
+	return nil, errors.New("label_match must be any or all")
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	var out []uint
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
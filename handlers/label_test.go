@@ -0,0 +1,189 @@
+package handlers
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/url"
+    "strconv"
+    "testing"
+
+    "flux/database"
+    "flux/models"
+    "github.com/gin-gonic/gin"
+)
+
+func createLabel(t *testing.T, userID uint, name string) models.Label {
+    t.Helper()
+    w, c := performJSONRequest(CreateLabel, http.MethodPost, models.Label{Name: name, Color: "#ff0000"})
+    c.Set("user_id", userID)
+    CreateLabel(c)
+    if w.Code != http.StatusCreated { t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String()) }
+    var label models.Label
+    if err := json.Unmarshal(w.Body.Bytes(), &label); err != nil { t.Fatal(err) }
+    return label
+}
+
+func attachLabels(t *testing.T, userID, taskID uint, ids ...uint) int {
+    t.Helper()
+    w, c := performJSONRequest(AttachTaskLabels, http.MethodPost, LabelIDsRequest{LabelIDs: ids})
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(taskID))}}
+    c.Set("user_id", userID)
+    AttachTaskLabels(c)
+    return w.Code
+}
+
+func TestLabels_CRUD(t *testing.T) {
+    setupTaskDB(t)
+
+    bug := createLabel(t, 1, "bug")
+
+    // 同名ラベルは409
+    w, c := performJSONRequest(CreateLabel, http.MethodPost, models.Label{Name: "bug"})
+    c.Set("user_id", uint(1))
+    CreateLabel(c)
+    if w.Code != http.StatusConflict { t.Fatalf("expected 409, got %d", w.Code) }
+
+    // 他人のラベルは更新できない
+    w, c = performJSONRequest(UpdateLabel, http.MethodPut, models.Label{Name: "defect"})
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(bug.ID))}}
+    c.Set("user_id", uint(2))
+    UpdateLabel(c)
+    if w.Code != http.StatusForbidden { t.Fatalf("expected 403, got %d", w.Code) }
+
+    w, c = performJSONRequest(GetLabels, http.MethodGet, nil)
+    c.Set("user_id", uint(1))
+    GetLabels(c)
+    var labels []models.Label
+    if err := json.Unmarshal(w.Body.Bytes(), &labels); err != nil { t.Fatal(err) }
+    if len(labels) != 1 || labels[0].Name != "bug" { t.Fatalf("unexpected labels: %+v", labels) }
+
+    w, c = performJSONRequest(DeleteLabel, http.MethodDelete, nil)
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(bug.ID))}}
+    c.Set("user_id", uint(1))
+    DeleteLabel(c)
+    if w.Code != http.StatusOK { t.Fatalf("expected 200, got %d", w.Code) }
+}
+
+func TestGetTasks_LabelFilters(t *testing.T) {
+    setupTaskDB(t)
+    u := seedTasks(t)
+
+    bug := createLabel(t, u.ID, "bug")
+    frontend := createLabel(t, u.ID, "frontend")
+    other := createLabel(t, u.ID+1, "other")
+
+    // Task 1: bug, Task 2: bug+frontend, Task 3: frontend
+    if code := attachLabels(t, u.ID, 1, bug.ID); code != http.StatusOK { t.Fatalf("expected 200, got %d", code) }
+    if code := attachLabels(t, u.ID, 2, bug.ID, frontend.ID); code != http.StatusOK { t.Fatalf("expected 200, got %d", code) }
+    if code := attachLabels(t, u.ID, 3, frontend.ID); code != http.StatusOK { t.Fatalf("expected 200, got %d", code) }
+    // 他人のラベルは付与できない
+    if code := attachLabels(t, u.ID, 3, other.ID); code != http.StatusBadRequest { t.Fatalf("expected 400, got %d", code) }
+
+    titles := func(q url.Values) string {
+        code, body := performListRequest(t, GetTasks, q)
+        if code != http.StatusOK { t.Fatalf("expected 200, got %d", code) }
+        var res taskListResult
+        if err := json.Unmarshal(body, &res); err != nil { t.Fatal(err) }
+        var out []string
+        for _, task := range res.Data { out = append(out, task.Title) }
+        return fmt.Sprint(out)
+    }
+
+    ids := fmt.Sprintf("%d,%d", bug.ID, frontend.ID)
+    if got := titles(url.Values{"labels": {ids}}); got != "[Task 1 Task 2 Task 3]" { t.Fatalf("unexpected any-of result: %s", got) }
+    if got := titles(url.Values{"labels": {ids}, "label_match": {"all"}}); got != "[Task 2]" { t.Fatalf("unexpected all-of result: %s", got) }
+
+    // 付け外し
+    w, c := performJSONRequest(DetachTaskLabel, http.MethodDelete, nil)
+    c.Params = []gin.Param{{Key: "id", Value: "2"}, {Key: "label_id", Value: strconv.Itoa(int(bug.ID))}}
+    c.Set("user_id", u.ID)
+    DetachTaskLabel(c)
+    if w.Code != http.StatusOK { t.Fatalf("expected 200, got %d", w.Code) }
+    if got := titles(url.Values{"labels": {ids}, "label_match": {"all"}}); got != "[]" { t.Fatalf("unexpected all-of result after detach: %s", got) }
+
+    var task models.Task
+    if err := database.DB.Preload("Labels").First(&task, 2).Error; err != nil { t.Fatal(err) }
+    if len(task.Labels) != 1 || task.Labels[0].Name != "frontend" { t.Fatalf("unexpected labels: %+v", task.Labels) }
+}
+
+func TestCreateTask_IgnoresLabelsInBody(t *testing.T) {
+    setupTaskDB(t)
+
+    other := createLabel(t, 2, "theirs")
+    w, c := performJSONRequest(CreateTask, http.MethodPost, models.Task{Title: "Sneaky", Labels: []models.Label{{ID: other.ID}}})
+    c.Set("user_id", uint(1))
+    CreateTask(c)
+    if w.Code != http.StatusCreated { t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String()) }
+
+    // ボディのラベルは保存されない
+    var count int64
+    database.DB.Table("task_labels").Count(&count)
+    if count != 0 { t.Fatalf("expected no task_labels rows, got %d", count) }
+}
+
+func TestLabels_Organization(t *testing.T) {
+    setupTaskDB(t)
+    for i := 1; i <= 3; i++ {
+        database.DB.Create(&models.User{Name: "u" + strconv.Itoa(i), Email: "u" + strconv.Itoa(i) + "@example.com"})
+    }
+    org := createOrganizationAs(t, 1, "Acme")
+    if code := addMemberAs(t, 1, org.ID, 2, models.RoleViewer); code != http.StatusCreated { t.Fatalf("expected 201, got %d", code) }
+
+    create := func(userID uint, label models.Label) (int, models.Label) {
+        w, c := performJSONRequest(CreateLabel, http.MethodPost, label)
+        c.Set("user_id", userID)
+        CreateLabel(c)
+        var created models.Label
+        json.Unmarshal(w.Body.Bytes(), &created)
+        return w.Code, created
+    }
+
+    code, team := create(1, models.Label{Name: "bug", OrganizationID: &org.ID})
+    if code != http.StatusCreated { t.Fatalf("expected 201, got %d", code) }
+    // 個人のラベルと組織のラベルは名前の重複を区別する
+    if code, _ := create(1, models.Label{Name: "bug"}); code != http.StatusCreated { t.Fatalf("expected 201, got %d", code) }
+    if code, _ := create(1, models.Label{Name: "bug", OrganizationID: &org.ID}); code != http.StatusConflict { t.Fatalf("expected 409, got %d", code) }
+    // 閲覧のみのメンバーと非メンバーは作成できない
+    if code, _ := create(2, models.Label{Name: "ops", OrganizationID: &org.ID}); code != http.StatusForbidden { t.Fatalf("expected 403, got %d", code) }
+    if code, _ := create(3, models.Label{Name: "ops", OrganizationID: &org.ID}); code != http.StatusBadRequest { t.Fatalf("expected 400, got %d", code) }
+
+    // メンバーには組織のラベルが見える
+    w, c := performJSONRequest(GetLabels, http.MethodGet, nil)
+    c.Set("user_id", uint(2))
+    GetLabels(c)
+    var labels []models.Label
+    if err := json.Unmarshal(w.Body.Bytes(), &labels); err != nil { t.Fatal(err) }
+    if len(labels) != 1 || labels[0].ID != team.ID { t.Fatalf("unexpected labels: %+v", labels) }
+
+    // 組織のラベルは同じ組織のタスクにだけ付けられる
+    shared := models.Task{Title: "Shared", UserID: 1, CreatorID: 1, OrganizationID: &org.ID}
+    personal := models.Task{Title: "Mine", UserID: 1, CreatorID: 1}
+    database.DB.Create(&shared)
+    database.DB.Create(&personal)
+    if code := attachLabels(t, 1, shared.ID, team.ID); code != http.StatusOK { t.Fatalf("expected 200, got %d", code) }
+    if code := attachLabels(t, 1, personal.ID, team.ID); code != http.StatusBadRequest { t.Fatalf("expected 400, got %d", code) }
+    if code := attachLabels(t, 3, personal.ID, team.ID); code != http.StatusForbidden { t.Fatalf("expected 403, got %d", code) }
+
+    // 閲覧のみのメンバーは組織のラベルを変更できない
+    w, c = performJSONRequest(UpdateLabel, http.MethodPut, models.Label{Name: "defect"})
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(team.ID))}}
+    c.Set("user_id", uint(2))
+    UpdateLabel(c)
+    if w.Code != http.StatusForbidden { t.Fatalf("expected 403, got %d", w.Code) }
+
+    // 組織の削除でラベルも消える
+    w, c = performJSONRequest(DeleteOrganization, http.MethodDelete, nil)
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(org.ID))}}
+    c.Set("user_id", uint(1))
+    DeleteOrganization(c)
+    if w.Code != http.StatusOK { t.Fatalf("expected 200, got %d", w.Code) }
+    var count int64
+    database.DB.Model(&models.Label{}).Where("organization_id = ?", org.ID).Count(&count)
+    if count != 0 { t.Fatalf("expected organization labels to be deleted, got %d", count) }
+    // 組織のタスクはゴミ箱に残さず完全に削除する
+    database.DB.Unscoped().Model(&models.Task{}).Where("organization_id = ?", org.ID).Count(&count)
+    if count != 0 { t.Fatalf("expected organization tasks to be purged, got %d", count) }
+    database.DB.Model(&models.Task{}).Where("id = ?", personal.ID).Count(&count)
+    if count != 1 { t.Fatal("expected personal tasks to be kept") }
+}
@@ -0,0 +1,378 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"flux/database"
+	"flux/models"
+	"flux/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// ListResponse 一覧取得の共通レスポンス
+type ListResponse struct {
+	Data  interface{} `json:"data"`
+	Total int64       `json:"total"`
+	Page  int         `json:"page"`
+	Limit int         `json:"limit"`
+	Next  *string     `json:"next"`
+	Prev  *string     `json:"prev"`
+	// キーセットページング用の次ページカーソル
+	NextCursor *string `json:"next_cursor"`
+}
+
+// listSpec 一覧ごとのソート可能カラムの定義
+type listSpec struct {
+	// クエリパラメータ名 -> カラム名
+	sortFields  map[string]string
+	defaultSort string
+	preloads    []string
+	// 取得後に計算項目を埋める処理
+	decorate func(dest interface{}) error
+	// NULL を取りうるカラム（常に末尾に並べる）
+	nullable map[string]bool
+	// sortFields に無いソート指定を解決する（未対応の指定なら nil を返す）
+	customSort func(param string) (*listSort, error)
+}
+
+// listSort sortFields 以外のソート指定（カスタムフィールドなど）の解決結果
+type listSort struct {
+	// ORDER BY に使う式（NULL は末尾に並べる）
+	column string
+	// ページ末尾の行からカーソルに保存する値を取り出す
+	value func(row reflect.Value) interface{}
+}
+
+var taskListSpec = listSpec{
+	sortFields: map[string]string{
+		"id":                "id",
+		"title":             "title",
+		"status":            "status",
+		"priority":          "priority",
+		"rank":              "rank",
+		"start_at":          "start_at",
+		"due_at":            "due_at",
+		"created_at":        "created_at",
+		"updated_at":        "updated_at",
+		"estimated_minutes": "estimated_minutes",
+	},
+	defaultSort: "id",
+	preloads:    []string{"User", "Labels", "Assignees"},
+	nullable:    map[string]bool{"start_at": true, "due_at": true, "estimated_minutes": true},
+	decorate: func(dest interface{}) error {
+		return decorateTasks(database.DB, *dest.(*[]models.Task))
+	},
+	customSort: func(param string) (*listSort, error) {
+		return customFieldSort(database.DB, param)
+	},
+}
+
+var userListSpec = listSpec{
+	sortFields: map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"email":      "email",
+		"created_at": "created_at",
+		"updated_at": "updated_at",
+	},
+	defaultSort: "id",
+}
+
+// listQuery ページング・ソートのパラメータ
+type listQuery struct {
+	page     int
+	limit    int
+	column   string
+	desc     bool
+	nullable bool
+	// customSort で解決した場合のカーソル値の取り出し方
+	value func(row reflect.Value) interface{}
+}
+
+func parseListQuery(c *gin.Context, spec listSpec) (listQuery, error) {
+	q := listQuery{page: 1, limit: defaultPageLimit, column: spec.sortFields[spec.defaultSort]}
+
+	if v := c.Query("page"); v != "" {
+		p, err := strconv.Atoi(v)
+		if err != nil || p < 1 {
+			return q, errors.New("page must be a positive integer")
+		}
+		q.page = p
+	}
+	if v := c.Query("limit"); v != "" {
+		l, err := strconv.Atoi(v)
+		if err != nil || l < 1 {
+			return q, errors.New("limit must be a positive integer")
+		}
+		if l > maxPageLimit {
+			l = maxPageLimit
+		}
+		q.limit = l
+	}
+	if v := c.Query("sort"); v != "" {
+		col, ok := spec.sortFields[v]
+		switch {
+		case ok:
+			q.column = col
+			q.nullable = spec.nullable[col]
+		case spec.customSort != nil:
+			custom, err := spec.customSort(v)
+			if err != nil {
+				return q, err
+			}
+			if custom == nil {
+				return q, fmt.Errorf("unsupported sort field: %s", v)
+			}
+			q.column, q.nullable, q.value = custom.column, true, custom.value
+		default:
+			return q, fmt.Errorf("unsupported sort field: %s", v)
+		}
+	}
+	switch strings.ToLower(c.DefaultQuery("order", "asc")) {
+	case "asc":
+	case "desc":
+		q.desc = true
+	default:
+		return q, errors.New("order must be asc or desc")
+	}
+	return q, nil
+}
+
+// listCursor キーセットページング用カーソルの中身
+type listCursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d"`
+	Kind  string `json:"k"`
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+}
+
+// respondList ソート・ページングを適用して一覧レスポンスを返す
+func respondList(c *gin.Context, db *gorm.DB, spec listSpec, dest interface{}) {
+	lq, err := parseListQuery(c, spec)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var total int64
+	if err := db.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	order := lq.column
+	if lq.nullable {
+		// NULL の並び順はDBによって異なるため明示的に末尾にする
+		order = lq.column + " IS NULL, " + lq.column
+	}
+	if lq.desc {
+		order += " DESC"
+	}
+	// 同値の並びを安定させるため ID を第二キーにする
+	if lq.column != "id" {
+		order += ", id"
+	}
+
+	// cursor が指定された場合はオフセットではなくキーセットで続きを取得する
+	raw := c.Query("cursor")
+	offset := (lq.page - 1) * lq.limit
+	if raw != "" {
+		var cur listCursor
+		if err := utils.DecodeCursor(raw, &cur); err != nil || cur.Sort != lq.column || cur.Desc != lq.desc {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
+		value, err := cur.value()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
+		db = applyKeyset(db, lq, value, cur.ID)
+		offset = 0
+	}
+
+	for _, p := range spec.preloads {
+		db = db.Preload(p)
+	}
+	// 次ページの有無を判定するため1件多く取得する
+	result := db.Order(order).Offset(offset).Limit(lq.limit + 1).Find(dest)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		return
+	}
+
+	rows := reflect.ValueOf(dest).Elem()
+	hasMore := rows.Len() > lq.limit
+	if hasMore {
+		rows.Set(rows.Slice(0, lq.limit))
+	}
+	if spec.decorate != nil {
+		if err := spec.decorate(dest); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	res := ListResponse{Data: dest, Total: total, Page: lq.page, Limit: lq.limit}
+	if hasMore {
+		next, err := nextCursor(c, result.Statement.Schema, lq, rows.Index(rows.Len()-1))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		res.NextCursor = &next
+		link := listLink(c, func(v url.Values) {
+			if raw != "" {
+				v.Set("cursor", next)
+			} else {
+				v.Set("page", strconv.Itoa(lq.page+1))
+			}
+		})
+		res.Next = &link
+	}
+	if raw == "" && lq.page > 1 {
+		prev := listLink(c, func(v url.Values) { v.Set("page", strconv.Itoa(lq.page-1)) })
+		res.Prev = &prev
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// applyKeyset カーソル位置より後ろの行に絞り込む
+func applyKeyset(db *gorm.DB, lq listQuery, value interface{}, id uint) *gorm.DB {
+	op := ">"
+	if lq.desc {
+		op = "<"
+	}
+	if lq.column == "id" {
+		return db.Where("id "+op+" ?", id)
+	}
+	if lq.nullable {
+		if value == nil {
+			return db.Where(lq.column+" IS NULL AND id > ?", id)
+		}
+		return db.Where("("+lq.column+" "+op+" ? OR ("+lq.column+" = ? AND id > ?) OR "+lq.column+" IS NULL)", value, value, id)
+	}
+	return db.Where("("+lq.column+" "+op+" ? OR ("+lq.column+" = ? AND id > ?))", value, value, id)
+}
+
+// nextCursor ページ末尾の行から次ページ用カーソルを生成する
+func nextCursor(c *gin.Context, sch *schema.Schema, lq listQuery, row reflect.Value) (string, error) {
+	cur := listCursor{Sort: lq.column, Desc: lq.desc}
+
+	idField := sch.PrioritizedPrimaryField
+	id, _ := idField.ValueOf(c.Request.Context(), row)
+	cur.ID, _ = id.(uint)
+
+	var v interface{}
+	if lq.value != nil {
+		v = lq.value(row)
+	} else {
+		field := sch.LookUpField(lq.column)
+		if field == nil {
+			return "", fmt.Errorf("unknown sort column: %s", lq.column)
+		}
+		v, _ = field.ValueOf(c.Request.Context(), row)
+	}
+	// *int などのポインタは指す値で扱い、nil は null として保存する
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			v = nil
+		} else {
+			v = reflect.Indirect(rv).Interface()
+		}
+	}
+	switch val := v.(type) {
+	case nil:
+		cur.Kind = "null"
+	case time.Time:
+		cur.Kind, cur.Value = "time", val.Format(time.RFC3339Nano)
+	case string:
+		cur.Kind, cur.Value = "string", val
+	default:
+		// Priority などの独自型も数値として扱う
+		rv := reflect.ValueOf(val)
+		switch {
+		case rv.CanInt():
+			cur.Kind, cur.Value = "number", strconv.FormatInt(rv.Int(), 10)
+		case rv.CanUint():
+			cur.Kind, cur.Value = "number", strconv.FormatUint(rv.Uint(), 10)
+		case rv.CanFloat():
+			cur.Kind, cur.Value = "number", strconv.FormatFloat(rv.Float(), 'g', -1, 64)
+		default:
+			cur.Kind, cur.Value = "number", fmt.Sprint(val)
+		}
+	}
+	return utils.EncodeCursor(cur)
+}
+
+// value カーソルに保存された値をクエリ用の型に戻す
+func (cur listCursor) value() (interface{}, error) {
+	switch cur.Kind {
+	case "time":
+		return time.Parse(time.RFC3339Nano, cur.Value)
+	case "string":
+		return cur.Value, nil
+	case "number":
+		return strconv.ParseFloat(cur.Value, 64)
+	case "null":
+		return nil, nil
+	}
+	return nil, errors.New("unknown cursor kind")
+}
+
+// listLink 現在のクエリを維持したまま一部のパラメータを差し替えたURLを返す
+func listLink(c *gin.Context, modify func(url.Values)) string {
+	u := *c.Request.URL
+	values := u.Query()
+	modify(values)
+	u.RawQuery = values.Encode()
+	return u.RequestURI()
+}
+
+// parseTimeParam RFC3339 または YYYY-MM-DD 形式の日時を解釈する
+func parseTimeParam(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", v)
+}
+
+// applyTimeRange name_from / name_to パラメータを column の範囲条件として適用する
+func applyTimeRange(c *gin.Context, db *gorm.DB, name, column string) (*gorm.DB, error) {
+	if v := c.Query(name + "_from"); v != "" {
+		t, err := parseTimeParam(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s_from: %s", name, v)
+		}
+		db = db.Where(column+" >= ?", t)
+	}
+	if v := c.Query(name + "_to"); v != "" {
+		t, err := parseTimeParam(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s_to: %s", name, v)
+		}
+		// 日付のみ指定された場合はその日の終わりまでを含める
+		if len(v) == len("2006-01-02") {
+			db = db.Where(column+" < ?", t.AddDate(0, 0, 1))
+		} else {
+			db = db.Where(column+" <= ?", t)
+		}
+	}
+	return db, nil
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/url"
+    "testing"
+    "time"
+
+    "flux/database"
+    "flux/models"
+    "github.com/gin-gonic/gin"
+)
+
+type taskListResult struct {
+    Data  []models.Task `json:"data"`
+    Total int64         `json:"total"`
+    Page  int           `json:"page"`
+    Limit int           `json:"limit"`
+    Next  *string       `json:"next"`
+    Prev  *string       `json:"prev"`
+}
+
+func performListRequest(t *testing.T, h gin.HandlerFunc, query url.Values, params ...gin.Param) (int, []byte) {
+    t.Helper()
+    w, c := performJSONRequest(h, http.MethodGet, nil)
+    c.Request.URL.RawQuery = query.Encode()
+    c.Params = params
+    h(c)
+    return w.Code, w.Body.Bytes()
+}
+
+func seedTasks(t *testing.T) models.User {
+    t.Helper()
+    u := models.User{Name: "L", Email: "list@example.com", Password: "Password1!"}
+    if err := database.DB.Create(&u).Error; err != nil { t.Fatal(err) }
+    for i := 1; i <= 5; i++ {
+        status := "pending"
+        if i%2 == 0 { status = "completed" }
+        task := models.Task{Title: fmt.Sprintf("Task %d", i), Status: status, UserID: u.ID}
+        if err := database.DB.Create(&task).Error; err != nil { t.Fatal(err) }
+    }
+    return u
+}
+
+func TestGetTasks_Pagination(t *testing.T) {
+    setupTaskDB(t)
+    seedTasks(t)
+
+    code, body := performListRequest(t, GetTasks, url.Values{"limit": {"2"}, "page": {"2"}})
+    if code != http.StatusOK { t.Fatalf("expected 200, got %d", code) }
+
+    var res taskListResult
+    if err := json.Unmarshal(body, &res); err != nil { t.Fatal(err) }
+    if res.Total != 5 || len(res.Data) != 2 { t.Fatalf("unexpected page: %+v", res) }
+    if res.Data[0].Title != "Task 3" { t.Fatalf("unexpected first item: %s", res.Data[0].Title) }
+    if res.Next == nil || res.Prev == nil { t.Fatalf("expected next and prev links: %+v", res) }
+}
+
+func TestGetTasks_FilterAndSort(t *testing.T) {
+    setupTaskDB(t)
+    seedTasks(t)
+
+    code, body := performListRequest(t, GetTasks, url.Values{"status": {"completed"}, "sort": {"title"}, "order": {"desc"}})
+    if code != http.StatusOK { t.Fatalf("expected 200, got %d", code) }
+
+    var res taskListResult
+    if err := json.Unmarshal(body, &res); err != nil { t.Fatal(err) }
+    if res.Total != 2 || res.Data[0].Title != "Task 4" || res.Data[1].Title != "Task 2" {
+        t.Fatalf("unexpected result: %+v", res)
+    }
+
+    code, body = performListRequest(t, GetTasks, url.Values{"q": {"task 5"}})
+    if err := json.Unmarshal(body, &res); err != nil { t.Fatal(err) }
+    if code != http.StatusOK || res.Total != 1 { t.Fatalf("expected 1 match, got %d (%d)", res.Total, code) }
+}
+
+func TestGetTasks_InvalidParams(t *testing.T) {
+    setupTaskDB(t)
+
+    for _, q := range []url.Values{
+        {"sort": {"password"}},
+        {"order": {"sideways"}},
+        {"page": {"0"}},
+        {"created_from": {"yesterday"}},
+    } {
+        if code, _ := performListRequest(t, GetTasks, q); code != http.StatusBadRequest {
+            t.Fatalf("expected 400 for %v, got %d", q, code)
+        }
+    }
+}
+
+func TestGetTasksByUser_UsesListEnvelope(t *testing.T) {
+    setupTaskDB(t)
+    u := seedTasks(t)
+
+    code, body := performListRequest(t, GetTasksByUser, url.Values{"status": {"pending"}},
+        gin.Param{Key: "id", Value: fmt.Sprint(u.ID)})
+    if code != http.StatusOK { t.Fatalf("expected 200, got %d", code) }
+
+    var res taskListResult
+    if err := json.Unmarshal(body, &res); err != nil { t.Fatal(err) }
+    if res.Total != 3 { t.Fatalf("expected 3 pending tasks, got %d", res.Total) }
+}
+
+func TestGetTasks_CursorPagination(t *testing.T) {
+    setupTaskDB(t)
+    seedTasks(t)
+
+    params := url.Values{"limit": {"2"}, "sort": {"title"}, "order": {"desc"}}
+    var titles []string
+    for i := 0; i < 5; i++ {
+        code, body := performListRequest(t, GetTasks, params)
+        if code != http.StatusOK { t.Fatalf("expected 200, got %d: %s", code, body) }
+
+        var res struct {
+            taskListResult
+            NextCursor *string `json:"next_cursor"`
+        }
+        if err := json.Unmarshal(body, &res); err != nil { t.Fatal(err) }
+        for _, task := range res.Data { titles = append(titles, task.Title) }
+        if res.NextCursor == nil { break }
+        params.Set("cursor", *res.NextCursor)
+    }
+
+    want := []string{"Task 5", "Task 4", "Task 3", "Task 2", "Task 1"}
+    if fmt.Sprint(titles) != fmt.Sprint(want) { t.Fatalf("unexpected order: %v", titles) }
+
+    // ソート条件を変えたカーソルや改ざんされたカーソルは拒否する
+    params.Set("order", "asc")
+    if code, _ := performListRequest(t, GetTasks, params); code != http.StatusBadRequest {
+        t.Fatalf("expected 400 for mismatched cursor, got %d", code)
+    }
+    if code, _ := performListRequest(t, GetTasks, url.Values{"cursor": {"bogus.cursor"}}); code != http.StatusBadRequest {
+        t.Fatalf("expected 400 for tampered cursor, got %d", code)
+    }
+}
+
+func TestGetTasks_CursorOverNullableColumn(t *testing.T) {
+    setupTaskDB(t)
+    u := seedTasks(t)
+
+    due := time.Now()
+    task := models.Task{Title: "Dated", DueAt: &due, UserID: u.ID}
+    if err := database.DB.Create(&task).Error; err != nil { t.Fatal(err) }
+
+    params := url.Values{"limit": {"2"}, "sort": {"due_at"}}
+    var titles []string
+    for i := 0; i < 5; i++ {
+        _, body := performListRequest(t, GetTasks, params)
+        var res struct {
+            taskListResult
+            NextCursor *string `json:"next_cursor"`
+        }
+        if err := json.Unmarshal(body, &res); err != nil { t.Fatal(err) }
+        for _, task := range res.Data { titles = append(titles, task.Title) }
+        if res.NextCursor == nil { break }
+        params.Set("cursor", *res.NextCursor)
+    }
+
+    // 期限ありのタスクが先頭、期限なしは末尾にID順で並ぶ
+    want := []string{"Dated", "Task 1", "Task 2", "Task 3", "Task 4", "Task 5"}
+    if fmt.Sprint(titles) != fmt.Sprint(want) { t.Fatalf("unexpected order: %v", titles) }
+}
+
+func TestGetTasks_CursorOverNullableInt(t *testing.T) {
+    setupTaskDB(t)
+    u := seedTasks(t)
+
+    for i, minutes := range []int{30, 15, 45} {
+        m := minutes
+        task := models.Task{Title: fmt.Sprintf("Estimated %d", i+1), EstimatedMinutes: &m, UserID: u.ID}
+        if err := database.DB.Create(&task).Error; err != nil { t.Fatal(err) }
+    }
+
+    params := url.Values{"limit": {"2"}, "sort": {"estimated_minutes"}}
+    var titles []string
+    for i := 0; i < 6; i++ {
+        code, body := performListRequest(t, GetTasks, params)
+        if code != http.StatusOK { t.Fatalf("expected 200 on page %d, got %d: %s", i+1, code, body) }
+        var res struct {
+            taskListResult
+            NextCursor *string `json:"next_cursor"`
+        }
+        if err := json.Unmarshal(body, &res); err != nil { t.Fatal(err) }
+        for _, task := range res.Data { titles = append(titles, task.Title) }
+        if res.NextCursor == nil { break }
+        params.Set("cursor", *res.NextCursor)
+    }
+
+    // 見積もりの小さい順、見積もりなしは末尾にID順で並ぶ
+    want := []string{"Estimated 2", "Estimated 1", "Estimated 3", "Task 1", "Task 2", "Task 3", "Task 4", "Task 5"}
+    if fmt.Sprint(titles) != fmt.Sprint(want) { t.Fatalf("unexpected order: %v", titles) }
+}
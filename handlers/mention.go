@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"log"
+	"strings"
+
+	"flux/database"
+	"flux/mailer"
+	"flux/models"
+	"flux/utils"
+
+	"gorm.io/gorm"
+)
+
+// notificationMailer 通知メールの送信に使うメーラー（未設定なら送信しない）
+var notificationMailer mailer.Mailer
+
+// SetMailer sets the mailer used for notification emails
+func SetMailer(m mailer.Mailer) {
+	notificationMailer = m
+}
+
+// recordMentions stores the mentions in text that are new for the task description
+// (commentID nil) or the comment, and creates a notification for each mentioned user
+// other than the author. Handles that do not resolve to exactly one user, and users
+// who cannot see the task, are ignored.
+func recordMentions(tx *gorm.DB, task *models.Task, commentID *uint, authorID uint, text string) ([]models.Notification, error) {
+	handles := utils.ExtractMentions(text)
+	if len(handles) == 0 {
+		return nil, nil
+	}
+
+	existing := tx.Model(&models.Mention{}).Where("task_id = ?", task.ID)
+	if commentID == nil {
+		existing = existing.Where("comment_id IS NULL")
+	} else {
+		existing = existing.Where("comment_id = ?", *commentID)
+	}
+	var mentioned []uint
+	if err := existing.Pluck("user_id", &mentioned).Error; err != nil {
+		return nil, err
+	}
+	seen := make(map[uint]bool, len(mentioned))
+	for _, id := range mentioned {
+		seen[id] = true
+	}
+
+	var notifications []models.Notification
+	for _, handle := range handles {
+		user, ok := resolveMention(tx, handle)
+		if !ok || seen[user.ID] || !canAccessTask(tx, task, user.ID, taskActionView) {
+			continue
+		}
+		seen[user.ID] = true
+
+		mention := models.Mention{TaskID: task.ID, CommentID: commentID, UserID: user.ID, AuthorID: authorID}
+		if err := tx.Create(&mention).Error; err != nil {
+			return nil, err
+		}
+		if user.ID == authorID {
+			continue
+		}
+		notification := models.Notification{
+			UserID: user.ID, Type: models.NotificationMention, ActorID: authorID, TaskID: task.ID, CommentID: commentID,
+		}
+		if err := tx.Create(&notification).Error; err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, notification)
+	}
+	return notifications, nil
+}
+
+// resolveMention finds the user for a handle: an email address, a user name or an
+// email local part, in that order. Ambiguous handles resolve to nobody.
+func resolveMention(db *gorm.DB, handle string) (models.User, bool) {
+	var users []models.User
+	if strings.Contains(handle, "@") {
+		db.Where("LOWER(email) = ?", handle).Limit(2).Find(&users)
+		return singleUser(users)
+	}
+	db.Where("LOWER(name) = ?", handle).Limit(2).Find(&users)
+	if len(users) > 0 {
+		return singleUser(users)
+	}
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(handle)
+	db.Where(`LOWER(email) LIKE ? ESCAPE '\'`, escaped+"@%").Limit(2).Find(&users)
+	return singleUser(users)
+}
+
+func singleUser(users []models.User) (models.User, bool) {
+	if len(users) != 1 {
+		return models.User{}, false
+	}
+	return users[0], true
+}
+
+// sendMentionEmails emails the recipients of mention notifications. It runs after
+// the notifications are committed, so delivery failures are only logged.
+func sendMentionEmails(task *models.Task, notifications []models.Notification) {
+	if notificationMailer == nil || len(notifications) == 0 {
+		return
+	}
+	var actor models.User
+	database.DB.First(&actor, notifications[0].ActorID)
+	for _, n := range notifications {
+		var user models.User
+		if err := database.DB.First(&user, n.UserID).Error; err != nil {
+			continue
+		}
+		if err := notificationMailer.SendMentionNotification(user.Email, user.Name, actor.Name, task.Title, task.ID); err != nil {
+			log.Printf("failed to send mention email to user %d: %v", user.ID, err)
+		}
+	}
+}
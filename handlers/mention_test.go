@@ -0,0 +1,75 @@
+package handlers
+
+import (
+    "encoding/json"
+    "net/http"
+    "strconv"
+    "testing"
+
+    "flux/database"
+    "flux/models"
+    "github.com/gin-gonic/gin"
+)
+
+func TestMentions_NotifyVisibleUsersOnce(t *testing.T) {
+    setupTaskDB(t)
+    m := &testMailer{}
+    SetMailer(m)
+    t.Cleanup(func() { SetMailer(nil) })
+
+    database.DB.Create(&models.User{Name: "owner", Email: "owner@example.com"})
+    database.DB.Create(&models.User{Name: "alice", Email: "alice@example.com"})
+    database.DB.Create(&models.User{Name: "mallory", Email: "mallory@example.com"})
+    org := createOrganizationAs(t, 1, "Acme")
+    addMemberAs(t, 1, org.ID, 2, models.RoleViewer)
+
+    // 組織外のユーザーへのメンションは無視される
+    w, c := performJSONRequest(CreateTask, http.MethodPost, models.Task{Title: "Launch", Description: "@alice and @mallory, see @owner", OrganizationID: &org.ID})
+    c.Set("user_id", uint(1))
+    CreateTask(c)
+    if w.Code != http.StatusCreated { t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String()) }
+    var task models.Task
+    if err := json.Unmarshal(w.Body.Bytes(), &task); err != nil { t.Fatal(err) }
+
+    var mentions []models.Mention
+    database.DB.Order("user_id").Find(&mentions)
+    if len(mentions) != 2 || mentions[0].UserID != 1 || mentions[1].UserID != 2 { t.Fatalf("unexpected mentions: %+v", mentions) }
+    if len(m.mentions) != 1 || m.mentions[0] != "alice@example.com" { t.Fatalf("unexpected mention emails: %v", m.mentions) }
+
+    // 説明を編集しても既にメンション済みのユーザーには再通知しない
+    w, c = performJSONRequest(UpdateTask, http.MethodPut, models.Task{Title: "Launch", Description: "@alice updated"})
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(task.ID))}}
+    c.Set("user_id", uint(1))
+    UpdateTask(c)
+    if w.Code != http.StatusOK { t.Fatalf("expected 200, got %d", w.Code) }
+    if len(m.mentions) != 1 { t.Fatalf("expected no new email, got %v", m.mentions) }
+
+    // コメント中のメンションは別に通知される（メールアドレス形式も可）
+    postComment(t, 1, task.ID, "ping @alice@example.com")
+    if len(m.mentions) != 2 { t.Fatalf("expected a second email, got %v", m.mentions) }
+
+    w, c = performJSONRequest(GetNotifications, http.MethodGet, nil)
+    c.Request.URL.RawQuery = "unread=true"
+    c.Set("user_id", uint(2))
+    GetNotifications(c)
+    var res struct {
+        Data []models.Notification `json:"data"`
+    }
+    if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil { t.Fatal(err) }
+    if len(res.Data) != 2 || res.Data[0].Type != models.NotificationMention || res.Data[1].CommentID == nil {
+        t.Fatalf("unexpected notifications: %+v", res.Data)
+    }
+
+    w, c = performJSONRequest(MarkNotificationRead, http.MethodPost, nil)
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(res.Data[0].ID))}}
+    c.Set("user_id", uint(3))
+    MarkNotificationRead(c)
+    if w.Code != http.StatusNotFound { t.Fatalf("expected 404 for another user's notification, got %d", w.Code) }
+
+    w, c = performJSONRequest(MarkAllNotificationsRead, http.MethodPost, nil)
+    c.Set("user_id", uint(2))
+    MarkAllNotificationsRead(c)
+    var unread int64
+    database.DB.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", 2).Count(&unread)
+    if unread != 0 { t.Fatalf("expected all notifications read, got %d unread", unread) }
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"flux/database"
+	"flux/middleware"
+	"flux/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+var notificationListSpec = listSpec{
+	sortFields: map[string]string{
+		"id":         "id",
+		"created_at": "created_at",
+	},
+	defaultSort: "id",
+	preloads:    []string{"Actor"},
+}
+
+// GetNotifications retrieves the caller's notifications (unread=true for unread only)
+func GetNotifications(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "認証が必要です"})
+		return
+	}
+
+	query := database.DB.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if c.Query("unread") == "true" {
+		query = query.Where("read_at IS NULL")
+	}
+	var notifications []models.Notification
+	respondList(c, query, notificationListSpec, &notifications)
+}
+
+// MarkNotificationRead marks one of the caller's notifications as read
+func MarkNotificationRead(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "認証が必要です"})
+		return
+	}
+
+	var notification models.Notification
+	if err := database.DB.Where("user_id = ?", userID).First(&notification, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+		return
+	}
+	if notification.ReadAt == nil {
+		now := time.Now()
+		notification.ReadAt = &now
+		if err := database.DB.Model(&notification).Update("read_at", now).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, notification)
+}
+
+// MarkAllNotificationsRead marks all of the caller's notifications as read
+func MarkAllNotificationsRead(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "認証が必要です"})
+		return
+	}
+
+	result := database.DB.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Update("read_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"updated": result.RowsAffected})
+}
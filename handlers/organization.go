@@ -0,0 +1,325 @@
+package handlers
+
+import (
+	"net/http"
+
+	"flux/database"
+	"flux/middleware"
+	"flux/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// MemberRequest メンバー追加・ロール変更リクエスト
+type MemberRequest struct {
+	UserID uint   `json:"user_id"`
+	Role   string `json:"role" binding:"required"`
+}
+
+// GetOrganizations retrieves the organizations the caller belongs to
+func GetOrganizations(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "認証が必要です"})
+		return
+	}
+
+	orgs := []models.Organization{}
+	if err := database.DB.Where("id IN (SELECT organization_id FROM organization_members WHERE user_id = ?)", userID).
+		Order("name").Find(&orgs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, orgs)
+}
+
+// GetOrganization retrieves an organization visible to its members
+func GetOrganization(c *gin.Context) {
+	org, _, ok := findMemberOrganization(c, false)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, org)
+}
+
+// CreateOrganization creates an organization with the caller as owner
+func CreateOrganization(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "認証が必要です"})
+		return
+	}
+
+	var org models.Organization
+	if err := c.ShouldBindJSON(&org); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	org.ID = 0
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&org).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.OrganizationMember{OrganizationID: org.ID, UserID: userID, Role: models.RoleOwner}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, org)
+}
+
+// UpdateOrganization renames an organization (owner/admin only)
+func UpdateOrganization(c *gin.Context) {
+	org, _, ok := findMemberOrganization(c, true)
+	if !ok {
+		return
+	}
+
+	var updateData models.Organization
+	if err := c.ShouldBindJSON(&updateData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	org.Name = updateData.Name
+	if err := database.DB.Save(&org).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, org)
+}
+
+// DeleteOrganization deletes an organization (owner only)
+func DeleteOrganization(c *gin.Context) {
+	org, member, ok := findMemberOrganization(c, true)
+	if !ok {
+		return
+	}
+	if member.Role != models.RoleOwner {
+		c.JSON(http.StatusForbidden, gin.H{"error": "権限がありません"})
+		return
+	}
+
+	// メンバーがいなくなるとゴミ箱から戻せる人もいないため、タスクはゴミ箱に入れず完全に削除する
+	var attachments []models.Attachment
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var taskIDs []uint
+		if err := tx.Unscoped().Model(&models.Task{}).Where("organization_id = ?", org.ID).Pluck("id", &taskIDs).Error; err != nil {
+			return err
+		}
+		var err error
+		if attachments, err = purgeTasks(tx, taskIDs); err != nil {
+			return err
+		}
+		if err := tx.Where("organization_id = ?", org.ID).Delete(&models.OrganizationMember{}).Error; err != nil {
+			return err
+		}
+		if err := deleteOrganizationLabels(tx, org.ID); err != nil {
+			return err
+		}
+		return tx.Delete(&org).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	removeAttachmentFiles(attachments)
+	c.JSON(http.StatusOK, gin.H{"message": "Organization deleted successfully"})
+}
+
+// GetOrganizationMembers lists the members of an organization
+func GetOrganizationMembers(c *gin.Context) {
+	org, _, ok := findMemberOrganization(c, false)
+	if !ok {
+		return
+	}
+
+	members := []models.OrganizationMember{}
+	if err := database.DB.Preload("User").Where("organization_id = ?", org.ID).Order("id").Find(&members).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, members)
+}
+
+// AddOrganizationMember adds a user to an organization (owner/admin only)
+func AddOrganizationMember(c *gin.Context) {
+	org, member, ok := findMemberOrganization(c, true)
+	if !ok {
+		return
+	}
+
+	var req MemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !validateRoleChange(c, member, req.Role) {
+		return
+	}
+	var user models.User
+	if err := database.DB.First(&user, req.UserID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+		return
+	}
+	if _, exists := findMember(database.DB, org.ID, user.ID); exists {
+		c.JSON(http.StatusConflict, gin.H{"error": "User is already a member"})
+		return
+	}
+
+	newMember := models.OrganizationMember{OrganizationID: org.ID, UserID: user.ID, Role: req.Role}
+	if err := database.DB.Create(&newMember).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, newMember)
+}
+
+// UpdateOrganizationMember changes a member's role (owner/admin only)
+func UpdateOrganizationMember(c *gin.Context) {
+	org, member, ok := findMemberOrganization(c, true)
+	if !ok {
+		return
+	}
+
+	var req MemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	target, ok := findTargetMember(c, org.ID)
+	if !ok || !validateRoleChange(c, member, req.Role) || !validateRoleChange(c, member, target.Role) {
+		return
+	}
+	if target.Role == models.RoleOwner && req.Role != models.RoleOwner && isLastOwner(org.ID) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "organization must keep at least one owner"})
+		return
+	}
+
+	target.Role = req.Role
+	if err := database.DB.Model(&target).Update("role", target.Role).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, target)
+}
+
+// RemoveOrganizationMember removes a member (owner/admin, or the member leaving)
+func RemoveOrganizationMember(c *gin.Context) {
+	org, member, ok := findMemberOrganization(c, false)
+	if !ok {
+		return
+	}
+	target, ok := findTargetMember(c, org.ID)
+	if !ok {
+		return
+	}
+	if target.UserID != member.UserID && !validateRoleChange(c, member, target.Role) {
+		return
+	}
+	if target.Role == models.RoleOwner && isLastOwner(org.ID) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "organization must keep at least one owner"})
+		return
+	}
+
+	if err := database.DB.Delete(&target).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed successfully"})
+}
+
+// GetOrganizationTasks retrieves the tasks owned by an organization
+func GetOrganizationTasks(c *gin.Context) {
+	org, _, ok := findMemberOrganization(c, false)
+	if !ok {
+		return
+	}
+
+	query, err := applyTaskFilters(c, database.DB.Model(&models.Task{}))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var tasks []models.Task
+	respondList(c, excludeArchivedProjects(c, query.Where("organization_id = ?", org.ID)), taskListSpec, &tasks)
+}
+
+// findMember returns the membership of userID in an organization
+func findMember(db *gorm.DB, orgID, userID uint) (models.OrganizationMember, bool) {
+	var member models.OrganizationMember
+	if userID == 0 {
+		return member, false
+	}
+	err := db.Where("organization_id = ? AND user_id = ?", orgID, userID).First(&member).Error
+	return member, err == nil
+}
+
+// findMemberOrganization loads the organization in the :id path parameter and the
+// caller's membership, requiring owner/admin when manage is set. Non-members get 404.
+// On failure the error response has already been written.
+func findMemberOrganization(c *gin.Context, manage bool) (models.Organization, models.OrganizationMember, bool) {
+	var org models.Organization
+	var member models.OrganizationMember
+
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "認証が必要です"})
+		return org, member, false
+	}
+	if err := database.DB.First(&org, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return org, member, false
+	}
+	member, ok = findMember(database.DB, org.ID, userID)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return org, member, false
+	}
+	if manage && !member.CanManage() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "権限がありません"})
+		return org, member, false
+	}
+	return org, member, true
+}
+
+// findTargetMember loads the member in the :user_id path parameter
+func findTargetMember(c *gin.Context, orgID uint) (models.OrganizationMember, bool) {
+	var target models.OrganizationMember
+	if err := database.DB.Where("organization_id = ? AND user_id = ?", orgID, c.Param("user_id")).First(&target).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return target, false
+	}
+	return target, true
+}
+
+// validateRoleChange checks that role is valid and that the caller may grant or
+// revoke it: only owners can manage owners and admins
+func validateRoleChange(c *gin.Context, member models.OrganizationMember, role string) bool {
+	if !models.IsValidRole(role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be owner, admin, member or viewer"})
+		return false
+	}
+	if (role == models.RoleOwner || role == models.RoleAdmin) && member.Role != models.RoleOwner {
+		c.JSON(http.StatusForbidden, gin.H{"error": "権限がありません"})
+		return false
+	}
+	return true
+}
+
+func isLastOwner(orgID uint) bool {
+	var owners int64
+	database.DB.Model(&models.OrganizationMember{}).Where("organization_id = ? AND role = ?", orgID, models.RoleOwner).Count(&owners)
+	return owners <= 1
+}
+
+// deleteOrganizationLabels deletes an organization's labels and detaches them from tasks
+func deleteOrganizationLabels(tx *gorm.DB, orgID uint) error {
+	labels := tx.Model(&models.Label{}).Select("id").Where("organization_id = ?", orgID)
+	if err := tx.Exec("DELETE FROM task_labels WHERE label_id IN (?)", labels).Error; err != nil {
+		return err
+	}
+	return tx.Where("organization_id = ?", orgID).Delete(&models.Label{}).Error
+}
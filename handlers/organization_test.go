@@ -0,0 +1,133 @@
+package handlers
+
+import (
+    "encoding/json"
+    "net/http"
+    "strconv"
+    "testing"
+
+    "flux/database"
+    "flux/models"
+    "github.com/gin-gonic/gin"
+)
+
+func createOrganizationAs(t *testing.T, userID uint, name string) models.Organization {
+    t.Helper()
+    w, c := performJSONRequest(CreateOrganization, http.MethodPost, models.Organization{Name: name})
+    c.Set("user_id", userID)
+    CreateOrganization(c)
+    if w.Code != http.StatusCreated { t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String()) }
+    var org models.Organization
+    if err := json.Unmarshal(w.Body.Bytes(), &org); err != nil { t.Fatal(err) }
+    return org
+}
+
+func addMemberAs(t *testing.T, actor uint, orgID uint, userID uint, role string) int {
+    t.Helper()
+    w, c := performJSONRequest(AddOrganizationMember, http.MethodPost, MemberRequest{UserID: userID, Role: role})
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(orgID))}}
+    c.Set("user_id", actor)
+    AddOrganizationMember(c)
+    return w.Code
+}
+
+func TestOrganizations_MembershipRoles(t *testing.T) {
+    setupTaskDB(t)
+    for i := 1; i <= 3; i++ {
+        database.DB.Create(&models.User{Name: "u" + strconv.Itoa(i), Email: "u" + strconv.Itoa(i) + "@example.com"})
+    }
+
+    org := createOrganizationAs(t, 1, "Acme")
+    if code := addMemberAs(t, 1, org.ID, 2, models.RoleMember); code != http.StatusCreated { t.Fatalf("expected 201, got %d", code) }
+    if code := addMemberAs(t, 1, org.ID, 2, models.RoleViewer); code != http.StatusConflict { t.Fatalf("expected 409, got %d", code) }
+    // 一般メンバーはメンバーを追加できない
+    if code := addMemberAs(t, 2, org.ID, 3, models.RoleViewer); code != http.StatusForbidden { t.Fatalf("expected 403, got %d", code) }
+    if code := addMemberAs(t, 1, org.ID, 3, "guest"); code != http.StatusBadRequest { t.Fatalf("expected 400, got %d", code) }
+
+    // 最後のオーナーは外せない
+    w, c := performJSONRequest(RemoveOrganizationMember, http.MethodDelete, nil)
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(org.ID))}, {Key: "user_id", Value: "1"}}
+    c.Set("user_id", uint(1))
+    RemoveOrganizationMember(c)
+    if w.Code != http.StatusUnprocessableEntity { t.Fatalf("expected 422, got %d", w.Code) }
+
+    // 非メンバーには組織自体が見えない
+    w, c = performJSONRequest(GetOrganization, http.MethodGet, nil)
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(org.ID))}}
+    c.Set("user_id", uint(3))
+    GetOrganization(c)
+    if w.Code != http.StatusNotFound { t.Fatalf("expected 404, got %d", w.Code) }
+
+    // メンバーは自分で脱退できる
+    w, c = performJSONRequest(RemoveOrganizationMember, http.MethodDelete, nil)
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(org.ID))}, {Key: "user_id", Value: "2"}}
+    c.Set("user_id", uint(2))
+    RemoveOrganizationMember(c)
+    if w.Code != http.StatusOK { t.Fatalf("expected 200, got %d", w.Code) }
+}
+
+func TestOrganizations_SharedTaskAuthorization(t *testing.T) {
+    setupTaskDB(t)
+    for i := 1; i <= 4; i++ {
+        database.DB.Create(&models.User{Name: "u" + strconv.Itoa(i), Email: "u" + strconv.Itoa(i) + "@example.com"})
+    }
+    org := createOrganizationAs(t, 1, "Acme")
+    addMemberAs(t, 1, org.ID, 2, models.RoleMember)
+    addMemberAs(t, 1, org.ID, 3, models.RoleViewer)
+
+    // 閲覧者は組織のタスクを作成できない
+    w, c := performJSONRequest(CreateTask, http.MethodPost, models.Task{Title: "Nope", OrganizationID: &org.ID})
+    c.Set("user_id", uint(3))
+    CreateTask(c)
+    if w.Code != http.StatusForbidden { t.Fatalf("expected 403, got %d", w.Code) }
+
+    w, c = performJSONRequest(CreateTask, http.MethodPost, models.Task{Title: "Shared", OrganizationID: &org.ID})
+    c.Set("user_id", uint(1))
+    CreateTask(c)
+    if w.Code != http.StatusCreated { t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String()) }
+    var task models.Task
+    if err := json.Unmarshal(w.Body.Bytes(), &task); err != nil { t.Fatal(err) }
+    id := strconv.Itoa(int(task.ID))
+
+    // メンバーは他人が作成したタスクも更新できる
+    w, c = performJSONRequest(UpdateTask, http.MethodPut, models.Task{Title: "Shared v2"})
+    c.Params = []gin.Param{{Key: "id", Value: id}}
+    c.Set("user_id", uint(2))
+    UpdateTask(c)
+    if w.Code != http.StatusOK { t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String()) }
+
+    // 閲覧者は更新できず、非メンバーにはタスクが見えない
+    w, c = performJSONRequest(UpdateTask, http.MethodPut, models.Task{Title: "Viewer"})
+    c.Params = []gin.Param{{Key: "id", Value: id}}
+    c.Set("user_id", uint(3))
+    UpdateTask(c)
+    if w.Code != http.StatusForbidden { t.Fatalf("expected 403, got %d", w.Code) }
+
+    w, c = performJSONRequest(GetTask, http.MethodGet, nil)
+    c.Params = []gin.Param{{Key: "id", Value: id}}
+    c.Set("user_id", uint(4))
+    GetTask(c)
+    if w.Code != http.StatusNotFound { t.Fatalf("expected 404, got %d", w.Code) }
+
+    w, c = performJSONRequest(GetTasks, http.MethodGet, nil)
+    GetTasks(c)
+    var res struct {
+        Total int64 `json:"total"`
+    }
+    if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil { t.Fatal(err) }
+    if res.Total != 0 { t.Fatalf("expected organization task to be hidden from anonymous list, got %d", res.Total) }
+
+    // 一般メンバーは他人のタスクを削除できない
+    w, c = performJSONRequest(DeleteTask, http.MethodDelete, nil)
+    c.Params = []gin.Param{{Key: "id", Value: id}}
+    c.Set("user_id", uint(2))
+    DeleteTask(c)
+    if w.Code != http.StatusForbidden { t.Fatalf("expected 403, got %d", w.Code) }
+
+    w, c = performJSONRequest(GetOrganizationTasks, http.MethodGet, nil)
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(org.ID))}}
+    c.Set("user_id", uint(3))
+    GetOrganizationTasks(c)
+    if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil { t.Fatal(err) }
+    if res.Total != 1 { t.Fatalf("expected 1 organization task, got %d", res.Total) }
+}
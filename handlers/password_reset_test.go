@@ -21,6 +21,7 @@ type testMailer struct{
     lastEmail string
     lastName string
     lastToken string
+    mentions []string
 }
 
 func (m *testMailer) SendPasswordReset(email, username, token string) error {
@@ -31,6 +32,11 @@ func (m *testMailer) SendPasswordReset(email, username, token string) error {
     return nil
 }
 
+func (m *testMailer) SendMentionNotification(email, username, actorName, taskTitle string, taskID uint) error {
+    m.mentions = append(m.mentions, email)
+    return nil
+}
+
 func newTestDB(t *testing.T) *gorm.DB {
     t.Helper()
     db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mergePatchContentType RFC 7396 のメディアタイプ
+const mergePatchContentType = "application/merge-patch+json"
+
+// parseMergePatch reads an RFC 7396 merge patch object from the request body.
+// Only keys listed in allowed may appear; non-null members are decoded into dest
+// and the returned maps report which keys were present and which were null.
+func parseMergePatch(c *gin.Context, allowed map[string]bool, dest interface{}) (fields, nulls map[string]bool, err error) {
+	// application/json と application/merge-patch+json のどちらも受け付ける
+	if ct := c.ContentType(); ct != "" && ct != "application/json" && ct != mergePatchContentType {
+		return nil, nil, fmt.Errorf("unsupported content type %q", ct)
+	}
+	body, err := c.GetRawData()
+	if err != nil {
+		return nil, nil, err
+	}
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(body, &members); err != nil || members == nil {
+		return nil, nil, errors.New("patch must be a JSON object")
+	}
+
+	var unknown []string
+	fields, nulls = map[string]bool{}, map[string]bool{}
+	values := map[string]json.RawMessage{}
+	for name, raw := range members {
+		if !allowed[name] {
+			unknown = append(unknown, name)
+			continue
+		}
+		fields[name] = true
+		if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+			// null はフィールドを消去する（dest はゼロ値のまま）
+			nulls[name] = true
+			continue
+		}
+		values[name] = raw
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, nil, fmt.Errorf("fields cannot be patched: %s", strings.Join(unknown, ", "))
+	}
+
+	subset, _ := json.Marshal(values)
+	if err := json.Unmarshal(subset, dest); err != nil {
+		return nil, nil, err
+	}
+	return fields, nulls, nil
+}
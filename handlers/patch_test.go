@@ -0,0 +1,131 @@
+package handlers
+
+import (
+    "net/http"
+    "strconv"
+    "testing"
+    "time"
+
+    "flux/database"
+    "flux/models"
+    "github.com/gin-gonic/gin"
+)
+
+func patchTask(id uint, userID uint, body interface{}) int {
+    w, c := performJSONRequest(PatchTask, http.MethodPatch, body)
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(id))}}
+    c.Request.Header.Set("Content-Type", "application/merge-patch+json")
+    c.Set("user_id", userID)
+    PatchTask(c)
+    return w.Code
+}
+
+func TestPatchTask_NullClearsFields(t *testing.T) {
+    setupTaskDB(t)
+    due := time.Now().Add(24 * time.Hour)
+    task := models.Task{Title: "Write report", Description: "draft", DueAt: &due, Recurrence: "FREQ=WEEKLY", UserID: 1}
+    if err := database.DB.Create(&task).Error; err != nil { t.Fatal(err) }
+
+    code := patchTask(task.ID, 1, map[string]interface{}{"description": nil, "due_at": nil, "recurrence": nil, "priority": "high"})
+    if code != http.StatusOK { t.Fatalf("expected 200, got %d", code) }
+
+    var saved models.Task
+    database.DB.First(&saved, task.ID)
+    if saved.Description != "" || saved.DueAt != nil || saved.Recurrence != "" { t.Fatalf("expected cleared fields, got %+v", saved) }
+    if saved.Title != "Write report" || saved.Priority != models.PriorityHigh { t.Fatalf("unexpected untouched fields: %q %d", saved.Title, saved.Priority) }
+
+    var events []models.TaskEvent
+    database.DB.Where("task_id = ? AND type = ?", task.ID, models.EventUpdated).Find(&events)
+    if len(events) != 1 || events[0].Changes["description"].To != "" { t.Fatalf("expected one update event clearing description, got %+v", events) }
+}
+
+func TestPatchTask_RejectsInvalidPatches(t *testing.T) {
+    setupTaskDB(t)
+    task := models.Task{Title: "Plan", UserID: 1}
+    database.DB.Create(&task)
+
+    cases := []map[string]interface{}{
+        {"user_id": 2},
+        {"title": nil},
+        {"status": nil},
+        {"title": "  "},
+        {"priority": ""},
+        {"priority": "critical"},
+    }
+    for _, body := range cases {
+        if code := patchTask(task.ID, 1, body); code != http.StatusBadRequest { t.Fatalf("expected 400 for %v, got %d", body, code) }
+    }
+    if code := patchTask(task.ID, 2, map[string]interface{}{"description": nil}); code != http.StatusForbidden { t.Fatalf("expected 403 for another user, got %d", code) }
+}
+
+func TestUpdateTask_ReplacesAllFields(t *testing.T) {
+    setupTaskDB(t)
+    due := time.Now().Add(24 * time.Hour)
+    estimate := 30
+    task := models.Task{Title: "Write report", Description: "draft", Status: models.StatusInProgress, Priority: models.PriorityHigh, DueAt: &due, EstimatedMinutes: &estimate, UserID: 1}
+    if err := database.DB.Create(&task).Error; err != nil { t.Fatal(err) }
+
+    put := func(body interface{}) int {
+        w, c := performJSONRequest(UpdateTask, http.MethodPut, body)
+        c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(task.ID))}}
+        c.Set("user_id", uint(1))
+        UpdateTask(c)
+        return w.Code
+    }
+    // タイトルは必須
+    if code := put(map[string]interface{}{"description": "no title"}); code != http.StatusBadRequest { t.Fatalf("expected 400, got %d", code) }
+
+    // 省略した項目は空になり、ステータスと優先度は既定値に戻る
+    if code := put(map[string]interface{}{"title": "Final report"}); code != http.StatusOK { t.Fatalf("expected 200, got %d", code) }
+    var saved models.Task
+    database.DB.First(&saved, task.ID)
+    if saved.Title != "Final report" || saved.Description != "" || saved.DueAt != nil || saved.EstimatedMinutes != nil { t.Fatalf("expected cleared fields, got %+v", saved) }
+    if saved.Status != models.StatusPending || saved.Priority != models.PriorityMedium { t.Fatalf("expected defaults, got %q %d", saved.Status, saved.Priority) }
+
+    // 置き換えた結果も検証する
+    start := due.Add(time.Hour)
+    if code := put(models.Task{Title: "Final report", StartAt: &start, DueAt: &due}); code != http.StatusBadRequest { t.Fatalf("expected 400 for start after due, got %d", code) }
+}
+
+func TestPatchUser_And_UpdateUser(t *testing.T) {
+    setupUserDB(t)
+    u := models.User{Name: "Ann", Email: "ann@example.com", Password: "x"}
+    other := models.User{Name: "Bob", Email: "bob@example.com", Password: "x"}
+    database.DB.Create(&u)
+    database.DB.Create(&other)
+    id := strconv.Itoa(int(u.ID))
+
+    patchAs := func(callerID uint, body interface{}) int {
+        w, c := performJSONRequest(PatchUser, http.MethodPatch, body)
+        c.Params = []gin.Param{{Key: "id", Value: id}}
+        if callerID != 0 {
+            c.Set("user_id", callerID)
+        }
+        PatchUser(c)
+        return w.Code
+    }
+    patch := func(body interface{}) int { return patchAs(u.ID, body) }
+    // 本人以外はメールアドレスを変更できない
+    if code := patchAs(0, map[string]interface{}{"email": "evil@example.com"}); code != http.StatusUnauthorized { t.Fatalf("expected 401, got %d", code) }
+    if code := patchAs(other.ID, map[string]interface{}{"email": "evil@example.com"}); code != http.StatusForbidden { t.Fatalf("expected 403, got %d", code) }
+    if code := patch(map[string]interface{}{"name": "Anna"}); code != http.StatusOK { t.Fatalf("expected 200, got %d", code) }
+    if code := patch(map[string]interface{}{"email": "bob@example.com"}); code != http.StatusConflict { t.Fatalf("expected 409, got %d", code) }
+    if code := patch(map[string]interface{}{"email": "not-an-email"}); code != http.StatusBadRequest { t.Fatalf("expected 400, got %d", code) }
+    if code := patch(map[string]interface{}{"password": "secret"}); code != http.StatusBadRequest { t.Fatalf("expected 400 for password, got %d", code) }
+
+    var saved models.User
+    database.DB.First(&saved, u.ID)
+    if saved.Name != "Anna" || saved.Email != "ann@example.com" { t.Fatalf("unexpected user: %+v", saved) }
+
+    // PUT は全項目必須
+    w, c := performJSONRequest(UpdateUser, http.MethodPut, map[string]string{"name": "Anne"})
+    c.Params = []gin.Param{{Key: "id", Value: id}}
+    c.Set("user_id", u.ID)
+    UpdateUser(c)
+    if w.Code != http.StatusBadRequest { t.Fatalf("expected 400 without email, got %d", w.Code) }
+    w, c = performJSONRequest(UpdateUser, http.MethodPut, map[string]string{"name": "Anne", "email": "evil@example.com"})
+    c.Params = []gin.Param{{Key: "id", Value: id}}
+    c.Set("user_id", other.ID)
+    UpdateUser(c)
+    if w.Code != http.StatusForbidden { t.Fatalf("expected 403 for another user, got %d", w.Code) }
+}
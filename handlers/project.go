@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"flux/database"
+	"flux/middleware"
+	"flux/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var projectListSpec = listSpec{
+	sortFields: map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"created_at": "created_at",
+		"updated_at": "updated_at",
+	},
+	defaultSort: "id",
+}
+
+// GetProjects retrieves the caller's projects (archived ones only with include_archived=true)
+func GetProjects(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "認証が必要です"})
+		return
+	}
+
+	query := database.DB.Model(&models.Project{}).Where("owner_id = ?", userID)
+	if c.Query("include_archived") != "true" {
+		query = query.Where("archived = ?", false)
+	}
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		query = query.Where("LOWER(name) LIKE ?", "%"+strings.ToLower(q)+"%")
+	}
+	var projects []models.Project
+	respondList(c, query, projectListSpec, &projects)
+}
+
+// GetProject retrieves a single project
+func GetProject(c *gin.Context) {
+	project, ok := findOwnedProject(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, project)
+}
+
+// CreateProject creates a new project owned by the caller
+func CreateProject(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "認証が必要です"})
+		return
+	}
+
+	var project models.Project
+	if err := c.ShouldBindJSON(&project); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	project.ID = 0
+	project.OwnerID = userID
+
+	if err := database.DB.Create(&project).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, project)
+}
+
+// UpdateProject updates a project's name, description or archived flag
+func UpdateProject(c *gin.Context) {
+	project, ok := findOwnedProject(c)
+	if !ok {
+		return
+	}
+
+	var updateData models.Project
+	if err := c.ShouldBindJSON(&updateData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	project.Name = updateData.Name
+	project.Description = updateData.Description
+	project.Archived = updateData.Archived
+	if err := database.DB.Save(&project).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, project)
+}
+
+// DeleteProject deletes a project with its custom fields and detaches its tasks
+func DeleteProject(c *gin.Context) {
+	project, ok := findOwnedProject(c)
+	if !ok {
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.Task{}).Where("project_id = ?", project.ID).
+			Updates(map[string]interface{}{"project_id": nil, "version": gorm.Expr("version + 1")}).Error
+		if err != nil {
+			return err
+		}
+		fields := tx.Model(&models.CustomField{}).Select("id").Where("project_id = ?", project.ID)
+		if err := tx.Where("field_id IN (?)", fields).Delete(&models.TaskFieldValue{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("project_id = ?", project.ID).Delete(&models.CustomField{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&project).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Project deleted successfully"})
+}
+
+// GetProjectTasks retrieves the tasks of a project, including archived ones
+func GetProjectTasks(c *gin.Context) {
+	project, ok := findOwnedProject(c)
+	if !ok {
+		return
+	}
+
+	query, err := applyTaskFilters(c, database.DB.Model(&models.Task{}))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var tasks []models.Task
+	respondList(c, visibleTasks(c, query.Where("project_id = ?", project.ID)), taskListSpec, &tasks)
+}
+
+// findOwnedProject loads the project in the :id path parameter and checks that the caller owns it.
+// On failure the error response has already been written.
+func findOwnedProject(c *gin.Context) (models.Project, bool) {
+	var project models.Project
+	if err := database.DB.First(&project, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return project, false
+	}
+
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "認証が必要です"})
+		return project, false
+	}
+	if project.OwnerID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "権限がありません"})
+		return project, false
+	}
+	return project, true
+}
+
+// validateProject checks that userID can place the task in the project: their own project,
+// or for an organization task, a project owned by a member of that organization
+func validateProject(db *gorm.DB, task *models.Task, projectID uint, userID uint) error {
+	var project models.Project
+	if err := db.First(&project, projectID).Error; err != nil {
+		return &hierarchyError{msg: "project not found"}
+	}
+	if project.OwnerID != userID {
+		if task.OrganizationID == nil {
+			return &hierarchyError{msg: "project not found"}
+		}
+		if _, ok := findMember(db, *task.OrganizationID, project.OwnerID); !ok {
+			return &hierarchyError{msg: "project not found"}
+		}
+	}
+	if project.Archived {
+		return &hierarchyError{msg: "project is archived"}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/url"
+    "strconv"
+    "testing"
+
+    "flux/database"
+    "flux/models"
+    "github.com/gin-gonic/gin"
+)
+
+func createProjectAs(t *testing.T, userID uint, name string) models.Project {
+    t.Helper()
+    w, c := performJSONRequest(CreateProject, http.MethodPost, models.Project{Name: name})
+    c.Set("user_id", userID)
+    CreateProject(c)
+    if w.Code != http.StatusCreated { t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String()) }
+    var project models.Project
+    if err := json.Unmarshal(w.Body.Bytes(), &project); err != nil { t.Fatal(err) }
+    return project
+}
+
+func TestProjects_CRUDAndOwnership(t *testing.T) {
+    setupTaskDB(t)
+
+    p := createProjectAs(t, 1, "Website")
+
+    w, c := performJSONRequest(GetProject, http.MethodGet, nil)
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(p.ID))}}
+    c.Set("user_id", uint(2))
+    GetProject(c)
+    if w.Code != http.StatusForbidden { t.Fatalf("expected 403, got %d", w.Code) }
+
+    w, c = performJSONRequest(UpdateProject, http.MethodPut, models.Project{Name: "Website v2", Description: "Relaunch"})
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(p.ID))}}
+    c.Set("user_id", uint(1))
+    UpdateProject(c)
+    if w.Code != http.StatusOK { t.Fatalf("expected 200, got %d", w.Code) }
+
+    w, c = performJSONRequest(GetProjects, http.MethodGet, nil)
+    c.Set("user_id", uint(1))
+    GetProjects(c)
+    var res struct {
+        Data []models.Project `json:"data"`
+    }
+    if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil { t.Fatal(err) }
+    if len(res.Data) != 1 || res.Data[0].Name != "Website v2" { t.Fatalf("unexpected projects: %+v", res.Data) }
+
+    // 削除するとタスクはプロジェクトから外れる
+    task := models.Task{Title: "T", UserID: 1, ProjectID: &p.ID}
+    if err := database.DB.Create(&task).Error; err != nil { t.Fatal(err) }
+    w, c = performJSONRequest(DeleteProject, http.MethodDelete, nil)
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(p.ID))}}
+    c.Set("user_id", uint(1))
+    DeleteProject(c)
+    if w.Code != http.StatusOK { t.Fatalf("expected 200, got %d", w.Code) }
+    if err := database.DB.First(&task, task.ID).Error; err != nil { t.Fatal(err) }
+    if task.ProjectID != nil { t.Fatalf("expected task to be detached, got %v", *task.ProjectID) }
+}
+
+func TestProjects_OrganizationTasksInMembersProjects(t *testing.T) {
+    setupTaskDB(t)
+    for i := 1; i <= 3; i++ {
+        database.DB.Create(&models.User{Name: "u" + strconv.Itoa(i), Email: "u" + strconv.Itoa(i) + "@example.com"})
+    }
+    org := createOrganizationAs(t, 1, "Acme")
+    if code := addMemberAs(t, 1, org.ID, 2, models.RoleMember); code != http.StatusCreated { t.Fatalf("expected 201, got %d", code) }
+    shared := createProjectAs(t, 1, "Launch")
+    outside := createProjectAs(t, 3, "Side project")
+
+    create := func(userID uint, task models.Task) int {
+        w, c := performJSONRequest(CreateTask, http.MethodPost, task)
+        c.Set("user_id", userID)
+        CreateTask(c)
+        return w.Code
+    }
+    // 組織のタスクは他のメンバーのプロジェクトにも入れられる
+    if code := create(2, models.Task{Title: "Team", OrganizationID: &org.ID, ProjectID: &shared.ID}); code != http.StatusCreated { t.Fatalf("expected 201, got %d", code) }
+    // 組織外のユーザーのプロジェクトや、個人のタスクを他人のプロジェクトには入れられない
+    if code := create(2, models.Task{Title: "Leak", OrganizationID: &org.ID, ProjectID: &outside.ID}); code != http.StatusBadRequest { t.Fatalf("expected 400, got %d", code) }
+    if code := create(2, models.Task{Title: "Mine", ProjectID: &shared.ID}); code != http.StatusBadRequest { t.Fatalf("expected 400, got %d", code) }
+}
+
+func TestProjects_ArchivedTasksHiddenByDefault(t *testing.T) {
+    setupTaskDB(t)
+
+    p := createProjectAs(t, 1, "Old")
+    w, c := performJSONRequest(CreateTask, http.MethodPost, models.Task{Title: "In project", ProjectID: &p.ID})
+    c.Set("user_id", uint(1))
+    CreateTask(c)
+    if w.Code != http.StatusCreated { t.Fatalf("expected 201, got %d", w.Code) }
+    createSubtask(t, 1, "Loose", nil)
+
+    // 他人のプロジェクトには追加できない
+    w, c = performJSONRequest(CreateTask, http.MethodPost, models.Task{Title: "Intruder", ProjectID: &p.ID})
+    c.Set("user_id", uint(2))
+    CreateTask(c)
+    if w.Code != http.StatusBadRequest { t.Fatalf("expected 400, got %d", w.Code) }
+
+    if err := database.DB.Model(&p).Update("archived", true).Error; err != nil { t.Fatal(err) }
+
+    total := func(h gin.HandlerFunc, q url.Values, params ...gin.Param) int64 {
+        w, c := performJSONRequest(h, http.MethodGet, nil)
+        c.Request.URL.RawQuery = q.Encode()
+        c.Params = params
+        c.Set("user_id", uint(1))
+        h(c)
+        var res taskListResult
+        if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil { t.Fatal(err) }
+        return res.Total
+    }
+
+    if n := total(GetTasks, url.Values{}); n != 1 { t.Fatalf("expected archived project task hidden, got %d", n) }
+    if n := total(GetTasks, url.Values{"include_archived": {"true"}}); n != 2 { t.Fatalf("expected 2 with include_archived, got %d", n) }
+    if n := total(GetProjectTasks, url.Values{}, gin.Param{Key: "id", Value: strconv.Itoa(int(p.ID))}); n != 1 {
+        t.Fatalf("expected project listing to include archived tasks, got %d", n)
+    }
+}
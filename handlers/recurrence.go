@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"flux/models"
+	"flux/utils"
+
+	"gorm.io/gorm"
+)
+
+// spawnNextOccurrence creates the next task of a recurring series when an
+// occurrence is completed, carrying over its estimate, labels, assignees and
+// custom field values. The rule is expanded from the series' first due date in
+// the series' time zone. It does nothing if the rule has no further
+// occurrences or the next one already exists.
+func spawnNextOccurrence(tx *gorm.DB, task *models.Task, now time.Time) error {
+	if task.Recurrence == "" || task.DueAt == nil {
+		return nil
+	}
+	rule, err := utils.ParseRRule(task.Recurrence)
+	if err != nil {
+		return err
+	}
+	loc, err := time.LoadLocation(task.TimeZone)
+	if err != nil {
+		return err
+	}
+	start, err := seriesStart(tx, task)
+	if err != nil {
+		return err
+	}
+	// 曜日や時刻が UTC でずれないよう、シリーズのタイムゾーンで展開する
+	next, ok := rule.Next(start.In(loc), task.DueAt.In(loc), task.OccurrenceIndex)
+	if !ok {
+		return nil
+	}
+	next = next.UTC()
+
+	// シリーズの最初のタスクは自身のIDをシリーズIDにする
+	if task.SeriesID == nil {
+		id := task.ID
+		task.SeriesID = &id
+	}
+
+	// 完了を取り消して再度完了した場合に重複して生成しない
+	var count int64
+	if err := tx.Model(&models.Task{}).Where("series_id = ? AND occurrence_index = ?", *task.SeriesID, task.OccurrenceIndex+1).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	occurrence := models.Task{
+		Title:           task.Title,
+		Description:     task.Description,
+		Priority:        task.Priority,
+		Recurrence:      task.Recurrence,
+		TimeZone:        task.TimeZone,
+		SeriesID:        task.SeriesID,
+		OccurrenceIndex: task.OccurrenceIndex + 1,
+		ParentID:        task.ParentID,
+		ProjectID:       task.ProjectID,
+		OrganizationID:  task.OrganizationID,
+		UserID:          task.UserID,
+		CreatorID:       task.CreatorID,
+		DueAt:           &next,
+	}
+	if task.EstimatedMinutes != nil {
+		estimate := *task.EstimatedMinutes
+		occurrence.EstimatedMinutes = &estimate
+	}
+	if task.StartAt != nil {
+		// 開始日時は期限との間隔を保ったままずらす
+		start := task.StartAt.Add(next.Sub(*task.DueAt))
+		occurrence.StartAt = &start
+	}
+	if err := occurrence.SetStatus(models.StatusPending, now); err != nil {
+		return err
+	}
+	if occurrence.Rank, err = appendRank(tx, &occurrence); err != nil {
+		return err
+	}
+	if err := tx.Create(&occurrence).Error; err != nil {
+		return err
+	}
+	if err := recordTaskEvent(tx, occurrence.ID, 0, models.EventCreated, nil); err != nil {
+		return err
+	}
+	if err := bumpRelatedVersions(tx, nil, &occurrence); err != nil {
+		return err
+	}
+
+	var labels []models.Label
+	if err := tx.Model(task).Association("Labels").Find(&labels); err != nil {
+		return err
+	}
+	if len(labels) > 0 {
+		if err := tx.Model(&occurrence).Association("Labels").Append(&labels); err != nil {
+			return err
+		}
+	}
+
+	// カスタムフィールドの値も引き継ぐ
+	var values []models.TaskFieldValue
+	if err := tx.Where("task_id = ?", task.ID).Order("id").Find(&values).Error; err != nil {
+		return err
+	}
+	for i := range values {
+		values[i].ID = 0
+		values[i].TaskID = occurrence.ID
+	}
+	if len(values) > 0 {
+		if err := tx.Create(&values).Error; err != nil {
+			return err
+		}
+	}
+
+	var assignees []models.User
+	if err := tx.Model(task).Association("Assignees").Find(&assignees); err != nil {
+		return err
+	}
+	if len(assignees) > 0 {
+		return tx.Model(&occurrence).Association("Assignees").Append(&assignees)
+	}
+	return nil
+}
+
+// seriesStart returns the due date of the first task of task's series, which the rule is
+// expanded from. Without one, such as for the first task itself, it is task's due date.
+func seriesStart(tx *gorm.DB, task *models.Task) (time.Time, error) {
+	if task.SeriesID == nil || *task.SeriesID == task.ID {
+		return *task.DueAt, nil
+	}
+	var first models.Task
+	err := tx.Unscoped().Select("id", "due_at").First(&first, *task.SeriesID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && first.DueAt == nil) {
+		return *task.DueAt, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return *first.DueAt, nil
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+    "net/http"
+    "strconv"
+    "testing"
+    "time"
+
+    "flux/database"
+    "flux/models"
+)
+
+func TestRecurringTask_CompletionSpawnsNextOccurrence(t *testing.T) {
+    setupTaskDB(t)
+
+    due := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC) // 月曜日
+    start := due.Add(-2 * time.Hour)
+    body := models.Task{Title: "Chores", StartAt: &start, DueAt: &due, Recurrence: "FREQ=WEEKLY;BYDAY=MO,TH;COUNT=2"}
+    w, c := performJSONRequest(CreateTask, http.MethodPost, body)
+    c.Set("user_id", uint(1))
+    CreateTask(c)
+    if w.Code != http.StatusCreated { t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String()) }
+    var first models.Task
+    if err := database.DB.Order("id").First(&first).Error; err != nil { t.Fatal(err) }
+
+    if code, _ := updateTaskAs(t, 1, first.ID, map[string]string{"status": "completed"}, ""); code != http.StatusOK {
+        t.Fatalf("expected 200, got %d", code)
+    }
+
+    var series []models.Task
+    if err := database.DB.Where("series_id = ?", first.ID).Order("occurrence_index").Find(&series).Error; err != nil { t.Fatal(err) }
+    if len(series) != 2 { t.Fatalf("expected original and next occurrence in series, got %d", len(series)) }
+    next := series[1]
+    if next.Status != models.StatusPending || next.OccurrenceIndex != 2 { t.Fatalf("unexpected next occurrence: %+v", next) }
+    if !next.DueAt.Equal(time.Date(2025, 1, 9, 9, 0, 0, 0, time.UTC)) { t.Fatalf("unexpected next due: %v", next.DueAt) }
+    if !next.StartAt.Equal(time.Date(2025, 1, 9, 7, 0, 0, 0, time.UTC)) { t.Fatalf("unexpected next start: %v", next.StartAt) }
+
+    // 再オープンして再完了しても重複しない
+    updateTaskAs(t, 1, first.ID, map[string]string{"status": "in_progress"}, "")
+    updateTaskAs(t, 1, first.ID, map[string]string{"status": "completed"}, "")
+    // COUNT=2 のため2回目の完了では生成しない
+    updateTaskAs(t, 1, next.ID, map[string]string{"status": "completed"}, "")
+
+    var total int64
+    database.DB.Model(&models.Task{}).Count(&total)
+    if total != 2 { t.Fatalf("expected 2 tasks, got %d", total) }
+}
+
+func TestRecurringTask_NextOccurrenceKeepsEstimateAndFields(t *testing.T) {
+    setupTaskDB(t)
+    p := createProjectAs(t, 1, "Ops")
+    tier := createFieldAs(t, 1, p.ID, map[string]interface{}{"name": "Tags", "type": "multi_select", "options": []string{"a", "b"}})
+    points := createFieldAs(t, 1, p.ID, map[string]interface{}{"name": "Points", "type": "number"})
+
+    due := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+    estimate := 45
+    first := models.Task{Title: "Backup", DueAt: &due, Recurrence: "FREQ=DAILY", EstimatedMinutes: &estimate, ProjectID: &p.ID, UserID: 1, Status: models.StatusPending}
+    if err := database.DB.Create(&first).Error; err != nil { t.Fatal(err) }
+    body := map[string]interface{}{strconv.Itoa(int(tier.ID)): []string{"a", "b"}, strconv.Itoa(int(points.ID)): 3}
+    if w, _ := setTaskFields(first.ID, 1, body); w.Code != http.StatusOK { t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String()) }
+
+    if code, _ := updateTaskAs(t, 1, first.ID, map[string]string{"status": "completed"}, ""); code != http.StatusOK { t.Fatalf("expected 200, got %d", code) }
+
+    var next models.Task
+    if err := database.DB.Where("series_id = ? AND occurrence_index = 2", first.ID).First(&next).Error; err != nil { t.Fatal(err) }
+    if next.EstimatedMinutes == nil || *next.EstimatedMinutes != 45 { t.Fatalf("expected estimate to carry over, got %v", next.EstimatedMinutes) }
+    var values []models.TaskFieldValue
+    database.DB.Where("task_id = ?", next.ID).Find(&values)
+    if len(values) != 3 { t.Fatalf("expected 3 custom field rows on the next occurrence, got %+v", values) }
+}
+
+func TestRecurringTask_ExpandsInSeriesTimeZoneFromFirstDue(t *testing.T) {
+    setupTaskDB(t)
+    tokyo, err := time.LoadLocation("Asia/Tokyo")
+    if err != nil { t.Skip("time zone data not available") }
+
+    // 東京の月曜 8:00 は UTC では日曜 23:00
+    due := time.Date(2026, 3, 2, 8, 0, 0, 0, tokyo).UTC()
+    w, c := performJSONRequest(CreateTask, http.MethodPost, models.Task{Title: "Weekly sync", DueAt: &due, Recurrence: "FREQ=WEEKLY;BYDAY=MO"})
+    c.Request.Header.Set("X-Timezone", "Asia/Tokyo")
+    c.Set("user_id", uint(1))
+    CreateTask(c)
+    if w.Code != http.StatusCreated { t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String()) }
+    var first models.Task
+    database.DB.Order("id").First(&first)
+    if first.TimeZone != "Asia/Tokyo" { t.Fatalf("expected the caller's time zone, got %q", first.TimeZone) }
+
+    if code, _ := updateTaskAs(t, 1, first.ID, map[string]string{"status": "completed"}, ""); code != http.StatusOK { t.Fatalf("expected 200, got %d", code) }
+    var second models.Task
+    database.DB.Where("occurrence_index = ?", 2).First(&second)
+    if want := time.Date(2026, 3, 9, 8, 0, 0, 0, tokyo); !second.DueAt.Equal(want) { t.Fatalf("expected %v, got %v", want, second.DueAt.In(tokyo)) }
+
+    // 発生を後ろにずらしても、次回はシリーズの最初の期限を起点にした月曜になる
+    moved := second.DueAt.AddDate(0, 0, 2)
+    if code, _ := updateTaskAs(t, 1, second.ID, map[string]interface{}{"due_at": moved}, ""); code != http.StatusOK { t.Fatalf("expected 200, got %d", code) }
+    if code, _ := updateTaskAs(t, 1, second.ID, map[string]string{"status": "completed"}, ""); code != http.StatusOK { t.Fatalf("expected 200, got %d", code) }
+    var third models.Task
+    database.DB.Where("occurrence_index = ?", 3).First(&third)
+    if want := time.Date(2026, 3, 16, 8, 0, 0, 0, tokyo); !third.DueAt.Equal(want) { t.Fatalf("expected %v, got %v", want, third.DueAt.In(tokyo)) }
+    if third.TimeZone != "Asia/Tokyo" { t.Fatalf("expected the time zone to carry over, got %q", third.TimeZone) }
+}
+
+func TestRecurringTask_Validation(t *testing.T) {
+    setupTaskDB(t)
+
+    due := time.Now()
+    for _, body := range []models.Task{
+        {Title: "No due", Recurrence: "FREQ=DAILY"},
+        {Title: "Bad rule", DueAt: &due, Recurrence: "FREQ=HOURLY"},
+        {Title: "Bad zone", DueAt: &due, Recurrence: "FREQ=DAILY", TimeZone: "Mars/Olympus"},
+    } {
+        w, c := performJSONRequest(CreateTask, http.MethodPost, body)
+        c.Set("user_id", uint(1))
+        CreateTask(c)
+        if w.Code != http.StatusBadRequest { t.Fatalf("expected 400 for %q, got %d", body.Title, w.Code) }
+    }
+}
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"flux/database"
+	"flux/models"
+	"flux/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// hierarchyError 親子関係の指定が不正
+type hierarchyError struct {
+	msg string
+}
+
+func (e *hierarchyError) Error() string { return e.msg }
+
+// openSubtasksError 未完了のサブタスクが残っている親タスクを完了しようとした
+type openSubtasksError struct {
+	open int64
+}
+
+func (e *openSubtasksError) Error() string {
+	return fmt.Sprintf("task has %d open subtasks (use cascade=true to complete them)", e.open)
+}
+
+// maxTaskDepth サブタスクの最大階層数（TASK_MAX_DEPTH で変更可能）
+func maxTaskDepth() int {
+	return utils.GetEnvInt("TASK_MAX_DEPTH", 5)
+}
+
+// GetSubtasks retrieves the direct subtasks of a task
+func GetSubtasks(c *gin.Context) {
+	parent, ok := findVisibleTask(c)
+	if !ok {
+		return
+	}
+
+	query, err := applyTaskFilters(c, database.DB.Model(&models.Task{}))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var tasks []models.Task
+	respondList(c, excludeArchivedProjects(c, visibleTasks(c, query.Where("parent_id = ?", parent.ID))), taskListSpec, &tasks)
+}
+
+// validateParent checks that parentID can become the parent of task without
+// crossing owners, creating a cycle or exceeding the depth limit
+func validateParent(tx *gorm.DB, task *models.Task, parentID uint) error {
+	var parent models.Task
+	if err := tx.First(&parent, parentID).Error; err != nil {
+		return &hierarchyError{msg: "parent task not found"}
+	}
+	if !sameTaskScope(&parent, task) {
+		return &hierarchyError{msg: "parent task must belong to the same owner or organization"}
+	}
+
+	// 親から根まで辿り、自身が含まれていないか確認しつつ深さを数える
+	depth := 0
+	current := parent
+	for {
+		if task.ID != 0 && current.ID == task.ID {
+			return &hierarchyError{msg: "parent would create a cycle"}
+		}
+		depth++
+		if current.ParentID == nil || depth > maxTaskDepth() {
+			break
+		}
+		var next models.Task
+		if err := tx.First(&next, *current.ParentID).Error; err != nil {
+			break
+		}
+		current = next
+	}
+
+	height, err := subtreeHeight(tx, task.ID)
+	if err != nil {
+		return err
+	}
+	if depth+height > maxTaskDepth() {
+		return &hierarchyError{msg: fmt.Sprintf("subtasks cannot be nested more than %d levels", maxTaskDepth())}
+	}
+	return nil
+}
+
+// subtreeHeight 自身を含む配下の階層数（新規タスクは1）
+func subtreeHeight(tx *gorm.DB, taskID uint) (int, error) {
+	height := 1
+	if taskID == 0 {
+		return height, nil
+	}
+	level := []uint{taskID}
+	for {
+		var children []uint
+		if err := tx.Model(&models.Task{}).Where("parent_id IN ?", level).Pluck("id", &children).Error; err != nil {
+			return 0, err
+		}
+		if len(children) == 0 || height > maxTaskDepth() {
+			return height, nil
+		}
+		height++
+		level = children
+	}
+}
+
+// completeSubtasks blocks completing a task with open subtasks, or completes
+// every open descendant in tx when cascade is set
+func completeSubtasks(tx *gorm.DB, task *models.Task, cascade bool, now time.Time) error {
+	if !cascade {
+		var open int64
+		if err := tx.Model(&models.Task{}).Where("parent_id = ? AND status <> ?", task.ID, models.StatusCompleted).
+			Count(&open).Error; err != nil {
+			return err
+		}
+		if open > 0 {
+			return &openSubtasksError{open: open}
+		}
+		return nil
+	}
+
+	level := []uint{task.ID}
+	for depth := 0; len(level) > 0 && depth <= maxTaskDepth(); depth++ {
+		var children []models.Task
+		if err := tx.Where("parent_id IN ?", level).Find(&children).Error; err != nil {
+			return err
+		}
+		level = level[:0]
+		for i := range children {
+			child := &children[i]
+			level = append(level, child.ID)
+			if child.Status == models.StatusCompleted {
+				continue
+			}
+			before := *child
+			if err := child.SetStatus(models.StatusCompleted, now); err != nil {
+				return err
+			}
+			if err := saveTaskVersioned(tx, child); err != nil {
+				return err
+			}
+			// 直下のサブタスクの親は呼び出し側で保存する
+			if *child.ParentID == task.ID {
+				if err := bumpBlockedVersions(tx, child.ID); err != nil {
+					return err
+				}
+			} else if err := bumpRelatedVersions(tx, &before, child); err != nil {
+				return err
+			}
+			if err := recordTaskUpdate(tx, &before, child, 0); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// decorateTasks fills computed fields such as subtask progress, blocked state, comment count, logged time and custom field values on a page of tasks
+func decorateTasks(db *gorm.DB, tasks []models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(tasks))
+	for i, t := range tasks {
+		ids[i] = t.ID
+	}
+
+	var rows []struct {
+		ParentID  uint
+		Total     int64
+		Completed int64
+	}
+	err := db.Model(&models.Task{}).
+		Select("parent_id, COUNT(*) AS total, SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS completed", models.StatusCompleted).
+		Where("parent_id IN ?", ids).Group("parent_id").Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	progress := make(map[uint]*models.TaskProgress, len(rows))
+	for _, r := range rows {
+		progress[r.ParentID] = &models.TaskProgress{Completed: r.Completed, Total: r.Total}
+	}
+
+	blockers, err := openBlockers(db, ids)
+	if err != nil {
+		return err
+	}
+	comments, err := commentCounts(db, ids)
+	if err != nil {
+		return err
+	}
+	logged, err := loggedMinutes(db, ids)
+	if err != nil {
+		return err
+	}
+	fieldValues, err := taskFieldValues(db, tasks)
+	if err != nil {
+		return err
+	}
+	for i := range tasks {
+		tasks[i].Progress = progress[tasks[i].ID]
+		tasks[i].IsBlocked = len(blockers[tasks[i].ID]) > 0
+		tasks[i].CommentCount = comments[tasks[i].ID]
+		tasks[i].LoggedMinutes = logged[tasks[i].ID]
+		tasks[i].CustomFields = fieldValues[tasks[i].ID]
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/url"
+    "strconv"
+    "testing"
+
+    "flux/database"
+    "flux/models"
+    "github.com/gin-gonic/gin"
+)
+
+func createSubtask(t *testing.T, userID uint, title string, parentID *uint) (int, models.Task) {
+    t.Helper()
+    w, c := performJSONRequest(CreateTask, http.MethodPost, models.Task{Title: title, ParentID: parentID})
+    c.Set("user_id", userID)
+    CreateTask(c)
+    var task models.Task
+    _ = json.Unmarshal(w.Body.Bytes(), &task)
+    return w.Code, task
+}
+
+func updateTaskAs(t *testing.T, userID, taskID uint, body interface{}, query string) (int, map[string]interface{}) {
+    t.Helper()
+    w, c := performJSONRequest(PatchTask, http.MethodPatch, body)
+    c.Request.URL.RawQuery = query
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(taskID))}}
+    c.Set("user_id", userID)
+    PatchTask(c)
+    var res map[string]interface{}
+    _ = json.Unmarshal(w.Body.Bytes(), &res)
+    return w.Code, res
+}
+
+func TestSubtasks_ListAndProgress(t *testing.T) {
+    setupTaskDB(t)
+
+    _, parent := createSubtask(t, 1, "Parent", nil)
+    _, a := createSubtask(t, 1, "A", &parent.ID)
+    createSubtask(t, 1, "B", &parent.ID)
+
+    if code, _ := updateTaskAs(t, 1, a.ID, map[string]string{"status": "completed"}, ""); code != http.StatusOK {
+        t.Fatalf("expected 200, got %d", code)
+    }
+
+    code, body := performListRequest(t, GetSubtasks, url.Values{}, gin.Param{Key: "id", Value: strconv.Itoa(int(parent.ID))})
+    if code != http.StatusOK { t.Fatalf("expected 200, got %d", code) }
+    var res taskListResult
+    if err := json.Unmarshal(body, &res); err != nil { t.Fatal(err) }
+    if res.Total != 2 { t.Fatalf("expected 2 subtasks, got %d", res.Total) }
+
+    w, c := performJSONRequest(GetTask, http.MethodGet, nil)
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(parent.ID))}}
+    GetTask(c)
+    var got models.Task
+    if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil { t.Fatal(err) }
+    if got.Progress == nil || got.Progress.Completed != 1 || got.Progress.Total != 2 {
+        t.Fatalf("unexpected progress: %+v", got.Progress)
+    }
+}
+
+func TestSubtasks_CycleAndDepth(t *testing.T) {
+    setupTaskDB(t)
+    t.Setenv("TASK_MAX_DEPTH", "3")
+
+    _, root := createSubtask(t, 1, "Root", nil)
+    _, child := createSubtask(t, 1, "Child", &root.ID)
+    _, grandchild := createSubtask(t, 1, "Grandchild", &child.ID)
+
+    if code, _ := createSubtask(t, 1, "Too deep", &grandchild.ID); code != http.StatusBadRequest {
+        t.Fatalf("expected 400 for depth limit, got %d", code)
+    }
+    if code, _ := updateTaskAs(t, 1, root.ID, map[string]uint{"parent_id": grandchild.ID}, ""); code != http.StatusBadRequest {
+        t.Fatalf("expected 400 for cycle, got %d", code)
+    }
+    if code, _ := updateTaskAs(t, 1, root.ID, map[string]uint{"parent_id": root.ID}, ""); code != http.StatusBadRequest {
+        t.Fatalf("expected 400 for self parent, got %d", code)
+    }
+    if code, _ := createSubtask(t, 2, "Other owner", &root.ID); code != http.StatusBadRequest {
+        t.Fatalf("expected 400 for other owner's parent, got %d", code)
+    }
+    // 親から外す
+    if code, res := updateTaskAs(t, 1, grandchild.ID, map[string]uint{"parent_id": 0}, ""); code != http.StatusOK || res["parent_id"] != nil {
+        t.Fatalf("expected detached task, got %d %v", code, res)
+    }
+}
+
+func TestSubtasks_CompletionBlockedOrCascaded(t *testing.T) {
+    setupTaskDB(t)
+
+    _, parent := createSubtask(t, 1, "Parent", nil)
+    _, child := createSubtask(t, 1, "Child", &parent.ID)
+    _, grandchild := createSubtask(t, 1, "Grandchild", &child.ID)
+
+    code, res := updateTaskAs(t, 1, parent.ID, map[string]string{"status": "completed"}, "")
+    if code != http.StatusUnprocessableEntity || res["open_subtasks"] != float64(1) {
+        t.Fatalf("expected 422 with open_subtasks, got %d %v", code, res)
+    }
+
+    if code, _ := updateTaskAs(t, 1, parent.ID, map[string]string{"status": "completed"}, "cascade=true"); code != http.StatusOK {
+        t.Fatalf("expected 200 with cascade, got %d", code)
+    }
+    var stored models.Task
+    if err := database.DB.First(&stored, grandchild.ID).Error; err != nil { t.Fatal(err) }
+    if stored.Status != models.StatusCompleted || stored.CompletedAt == nil {
+        t.Fatalf("expected grandchild to be completed, got %s", stored.Status)
+    }
+}
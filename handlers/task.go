@@ -1,36 +1,194 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 	"flux/database"
 	"flux/models"
 	"flux/middleware"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-// GetTasks retrieves all tasks
+// GetTasks retrieves tasks with filtering, sorting and pagination
 func GetTasks(c *gin.Context) {
-	var tasks []models.Task
-	result := database.DB.Preload("User").Find(&tasks)
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+	query, err := applyTaskFilters(c, database.DB.Model(&models.Task{}))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, tasks)
+	var tasks []models.Task
+	respondList(c, excludeArchivedProjects(c, visibleTasks(c, query)), taskListSpec, &tasks)
+}
+
+// applyTaskFilters applies the task list query parameters to db
+func applyTaskFilters(c *gin.Context, db *gorm.DB) (*gorm.DB, error) {
+	if status := c.Query("status"); status != "" {
+		db = db.Where("status IN ?", strings.Split(status, ","))
+	}
+	if v := c.Query("priority"); v != "" {
+		var priorities []models.Priority
+		for _, name := range strings.Split(v, ",") {
+			p, err := models.ParsePriority(name)
+			if err != nil {
+				return nil, err
+			}
+			priorities = append(priorities, p)
+		}
+		db = db.Where("priority IN ?", priorities)
+	}
+	if v := c.Query("labels"); v != "" {
+		var err error
+		if db, err = applyLabelFilter(db, v, c.DefaultQuery("label_match", "any")); err != nil {
+			return nil, err
+		}
+	}
+	if v := c.Query("project_id"); v != "" {
+		pid, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, errors.New("invalid project_id")
+		}
+		db = db.Where("project_id = ?", pid)
+	}
+	if v := c.Query("assignee_id"); v != "" {
+		aid, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, errors.New("invalid assignee_id")
+		}
+		db = db.Where("id IN (SELECT task_id FROM task_assignees WHERE user_id = ?)", aid)
+	}
+	if v := c.Query("organization_id"); v != "" {
+		oid, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, errors.New("invalid organization_id")
+		}
+		db = db.Where("organization_id = ?", oid)
+	}
+	if v := c.Query("series_id"); v != "" {
+		sid, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, errors.New("invalid series_id")
+		}
+		db = db.Where("series_id = ? OR id = ?", sid, sid)
+	}
+	if v := c.Query("user_id"); v != "" {
+		uid, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, errors.New("invalid user_id")
+		}
+		db = db.Where("user_id = ?", uid)
+	}
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		db = db.Where("LOWER(title) LIKE ?", "%"+strings.ToLower(q)+"%")
+	}
+
+	var err error
+	if db, err = applyTimeRange(c, db, "created", "created_at"); err != nil {
+		return nil, err
+	}
+	if db, err = applyTimeRange(c, db, "updated", "updated_at"); err != nil {
+		return nil, err
+	}
+	if db, err = applyTimeRange(c, db, "due", "due_at"); err != nil {
+		return nil, err
+	}
+	if due := c.Query("due"); due != "" {
+		if db, err = applyDueFilter(c, db, due); err != nil {
+			return nil, err
+		}
+	}
+	return applyCustomFieldFilters(c, db)
+}
+
+// excludeArchivedProjects hides tasks of archived projects unless include_archived=true
+func excludeArchivedProjects(c *gin.Context, db *gorm.DB) *gorm.DB {
+	if c.Query("include_archived") == "true" {
+		return db
+	}
+	return db.Where("(project_id IS NULL OR project_id NOT IN (SELECT id FROM projects WHERE archived = ?))", true)
+}
+
+// applyDueFilter applies the overdue / today / week shortcuts in the caller's time zone
+func applyDueFilter(c *gin.Context, db *gorm.DB, due string) (*gorm.DB, error) {
+	loc, err := callerLocation(c)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+
+	switch due {
+	case "overdue":
+		return db.Where("due_at < ? AND status <> ?", now, models.StatusCompleted), nil
+	case "today":
+		return db.Where("due_at >= ? AND due_at < ?", today, today.AddDate(0, 0, 1)), nil
+	case "week":
+		// 週は月曜始まり
+		offset := (int(today.Weekday()) + 6) % 7
+		monday := today.AddDate(0, 0, -offset)
+		return db.Where("due_at >= ? AND due_at < ?", monday, monday.AddDate(0, 0, 7)), nil
+	}
+	return nil, errors.New("due must be one of overdue, today, week")
+}
+
+// callerLocation returns the time zone from the tz query parameter or X-Timezone header
+func callerLocation(c *gin.Context) (*time.Location, error) {
+	name := c.Query("tz")
+	if name == "" {
+		name = c.GetHeader("X-Timezone")
+	}
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, errors.New("invalid time zone: " + name)
+	}
+	return loc, nil
+}
+
+// defaultTimeZone gives a recurring task without a time zone the caller's, so its rule
+// is expanded in local time. On failure the error response has already been written.
+func defaultTimeZone(c *gin.Context, task *models.Task) bool {
+	if task.Recurrence == "" || task.TimeZone != "" {
+		return true
+	}
+	loc, err := callerLocation(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if loc != time.UTC {
+		task.TimeZone = loc.String()
+	}
+	return true
 }
 
 // GetTask retrieves a single task by ID
 func GetTask(c *gin.Context) {
-	id := c.Param("id")
-	var task models.Task
-	result := database.DB.Preload("User").First(&task, id)
-	if result.Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+	task, ok := findVisibleTask(c)
+	if !ok {
 		return
 	}
-	c.JSON(http.StatusOK, task)
+	if err := database.DB.Preload("User").Preload("Labels").Preload("Assignees").First(&task, task.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("ETag", taskETag(&task))
+	if header := c.GetHeader("If-None-Match"); header != "" && etagMatches(header, taskETag(&task)) {
+		c.AbortWithStatus(http.StatusNotModified)
+		return
+	}
+	tasks := []models.Task{task}
+	if err := decorateTasks(database.DB, tasks); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tasks[0])
 }
 
 // CreateTask creates a new task
@@ -50,84 +208,456 @@ func CreateTask(c *gin.Context) {
 
 	// リクエストボディの user_id を無視し、認証ユーザーを強制
 	task.UserID = userID
+	task.CreatorID = userID
+	// ID とバージョンはサーバー側で決める
+	task.ID, task.Version = 0, 1
+	// 関連はボディから保存せず、権限を確認する専用のエンドポイントで付け替える
+	task.User = models.User{}
+	task.Labels = nil
+	task.Assignees = nil
+
+	// 組織のタスクは編集権限を持つメンバーのみ作成できる
+	if task.OrganizationID != nil && *task.OrganizationID == 0 {
+		task.OrganizationID = nil
+	}
+	if task.OrganizationID != nil {
+		member, ok := findMember(database.DB, *task.OrganizationID, userID)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "organization not found"})
+			return
+		}
+		if !member.CanEdit() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "権限がありません"})
+			return
+		}
+	}
+
+	if !defaultTimeZone(c, &task) {
+		return
+	}
+	if err := task.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	result := database.DB.Create(&task)
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+	// ステータスは遷移ルールを通して設定し、日時はサーバー側で記録する
+	status := task.Status
+	if status == "" {
+		status = models.StatusPending
+	}
+	task.Status = ""
+	task.PendingAt, task.InProgressAt, task.CompletedAt = nil, nil, nil
+	task.SeriesID, task.OccurrenceIndex = nil, 0
+	if err := task.SetStatus(status, time.Now()); err != nil {
+		respondTaskError(c, err)
+		return
+	}
+
+	if task.ParentID != nil && *task.ParentID == 0 {
+		task.ParentID = nil
+	}
+	if task.ParentID != nil {
+		if err := validateParent(database.DB, &task, *task.ParentID); err != nil {
+			respondTaskError(c, err)
+			return
+		}
+		// プロジェクト未指定のサブタスクは親のプロジェクトを引き継ぐ
+		if task.ProjectID == nil {
+			var parent models.Task
+			database.DB.Select("project_id").First(&parent, *task.ParentID)
+			task.ProjectID = parent.ProjectID
+		}
+	}
+	if task.ProjectID != nil && *task.ProjectID == 0 {
+		task.ProjectID = nil
+	}
+	if task.ProjectID != nil {
+		if err := validateProject(database.DB, &task, *task.ProjectID, userID); err != nil {
+			respondTaskError(c, err)
+			return
+		}
+	}
+
+	var notifications []models.Notification
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// 並び順はサーバー側で採番し、ボードの同じステータス列の末尾に追加する
+		var err error
+		if task.Rank, err = appendRank(tx, &task); err != nil {
+			return err
+		}
+		if err := tx.Create(&task).Error; err != nil {
+			return err
+		}
+		if err := recordTaskEvent(tx, task.ID, userID, models.EventCreated, nil); err != nil {
+			return err
+		}
+		if err := bumpRelatedVersions(tx, nil, &task); err != nil {
+			return err
+		}
+		notifications, err = recordMentions(tx, &task, nil, userID, task.Description)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	sendMentionEmails(&task, notifications)
 
+	c.Header("ETag", taskETag(&task))
 	c.JSON(http.StatusCreated, task)
 }
 
-// UpdateTask updates an existing task
+// UpdateTask replaces a task's fields with the body. Omitted fields are cleared, except that
+// status defaults to pending and priority to medium; use PatchTask to change single fields.
 func UpdateTask(c *gin.Context) {
-	id := c.Param("id")
-	var task models.Task
+	task, ok := findAuthorizedTask(c, taskActionStatus)
+	if !ok || !checkIfMatch(c, &task) {
+		return
+	}
 
-	if result := database.DB.First(&task, id); result.Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+	var updateData models.Task
+	if err := c.ShouldBindJSON(&updateData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 所有者チェック
-	userID, ok := middleware.GetUserID(c)
-	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "認証が必要です"})
+	// 全項目を置き換える。省略された項目は作成時と同じ既定値にする
+	if strings.TrimSpace(updateData.Title) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "title is required"})
 		return
 	}
-	if task.UserID != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "権限がありません"})
+	if updateData.Status == "" {
+		updateData.Status = models.StatusPending
+	}
+	if updateData.Priority == 0 {
+		updateData.Priority = models.PriorityMedium
+	}
+
+	// 担当者がステータスだけを変更できるよう、値が変わる項目だけを反映する
+	fields := make(map[string]bool)
+	for name := range models.DiffTasks(&task, &updateData) {
+		fields[name] = true
+	}
+	applyTaskUpdate(c, task, &updateData, fields)
+}
+
+// PatchTask applies an RFC 7396 JSON merge patch to a task; null clears a field
+func PatchTask(c *gin.Context) {
+	task, ok := findAuthorizedTask(c, taskActionStatus)
+	if !ok || !checkIfMatch(c, &task) {
 		return
 	}
 
-	var updateData models.Task
-	if err := c.ShouldBindJSON(&updateData); err != nil {
+	var patch models.Task
+	fields, nulls, err := parseMergePatch(c, taskPatchFields, &patch)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	for _, name := range []string{"title", "status", "priority"} {
+		if nulls[name] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": name + " cannot be null"})
+			return
+		}
+	}
+	if fields["title"] && strings.TrimSpace(patch.Title) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "title cannot be empty"})
+		return
+	}
+	if fields["priority"] && patch.Priority == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "priority must be one of low, medium, high, urgent"})
+		return
+	}
+	applyTaskUpdate(c, task, &patch, fields)
+}
 
-	// 更新可能なフィールドのみ反映
-	if updateData.Title != "" { task.Title = updateData.Title }
-	if updateData.Description != "" { task.Description = updateData.Description }
-	if updateData.Status != "" { task.Status = updateData.Status }
+// taskPatchFields PATCH で変更できる項目
+var taskPatchFields = map[string]bool{
+	"title": true, "description": true, "status": true, "priority": true, "start_at": true,
+	"due_at": true, "recurrence": true, "time_zone": true, "parent_id": true, "project_id": true, "estimated_minutes": true,
+}
 
-	if err := database.DB.Save(&task).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+// applyTaskUpdate copies the named fields from updateData onto task, enforces the
+// task rules and saves it with its history, writing the response
+func applyTaskUpdate(c *gin.Context, task models.Task, updateData *models.Task, fields map[string]bool) {
+	before := task
+
+	// 担当者はステータスのみ変更できる
+	userID, _ := middleware.GetUserID(c)
+	statusOnly := len(fields) == 0 || (len(fields) == 1 && fields["status"])
+	if !statusOnly && !canAccessTask(database.DB, &task, userID, taskActionEdit) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "権限がありません"})
+		return
+	}
+
+	if fields["title"] { task.Title = updateData.Title }
+	if fields["description"] { task.Description = updateData.Description }
+	completing, starting := false, false
+	if fields["status"] {
+		completing = updateData.Status == models.StatusCompleted && task.Status != models.StatusCompleted
+		starting = updateData.Status == models.StatusInProgress && task.Status != models.StatusInProgress
+		if err := task.SetStatus(updateData.Status, time.Now()); err != nil {
+			respondTaskError(c, err)
+			return
+		}
+	}
+	if fields["parent_id"] {
+		// parent_id に 0 または null を指定すると親から外す
+		if updateData.ParentID == nil || *updateData.ParentID == 0 {
+			task.ParentID = nil
+		} else if err := validateParent(database.DB, &task, *updateData.ParentID); err != nil {
+			respondTaskError(c, err)
+			return
+		} else {
+			task.ParentID = updateData.ParentID
+		}
+	}
+	if fields["project_id"] {
+		// project_id に 0 または null を指定するとプロジェクトから外す
+		if updateData.ProjectID == nil || *updateData.ProjectID == 0 {
+			task.ProjectID = nil
+		} else if err := validateProject(database.DB, &task, *updateData.ProjectID, userID); err != nil {
+			respondTaskError(c, err)
+			return
+		} else {
+			task.ProjectID = updateData.ProjectID
+		}
+	}
+	if fields["priority"] { task.Priority = updateData.Priority }
+	if fields["start_at"] { task.StartAt = updateData.StartAt }
+	if fields["due_at"] { task.DueAt = updateData.DueAt }
+	if fields["recurrence"] { task.Recurrence = updateData.Recurrence }
+	if fields["time_zone"] { task.TimeZone = updateData.TimeZone }
+	if (fields["recurrence"] || fields["time_zone"]) && !defaultTimeZone(c, &task) {
 		return
 	}
+	if fields["estimated_minutes"] { task.EstimatedMinutes = updateData.EstimatedMinutes }
 
+	if err := task.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 着手時はブロックしているタスクを確認（force=true で無視）
+	// 完了時は未完了のサブタスクを確認（cascade=true なら一緒に完了させる）
+	var notifications []models.Notification
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if starting {
+			if err := checkNotBlocked(tx, &task, c.Query("force") == "true"); err != nil {
+				return err
+			}
+		}
+		if completing {
+			if err := completeSubtasks(tx, &task, c.Query("cascade") == "true", time.Now()); err != nil {
+				return err
+			}
+			if err := spawnNextOccurrence(tx, &task, time.Now()); err != nil {
+				return err
+			}
+		}
+		if err := saveTaskVersioned(tx, &task); err != nil {
+			return err
+		}
+		if err := bumpRelatedVersions(tx, &before, &task); err != nil {
+			return err
+		}
+		if err := recordTaskUpdate(tx, &before, &task, userID); err != nil {
+			return err
+		}
+		if fields["description"] && task.Description != "" {
+			var err error
+			notifications, err = recordMentions(tx, &task, nil, userID, task.Description)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		respondTaskError(c, err)
+		return
+	}
+	sendMentionEmails(&task, notifications)
+
+	c.Header("ETag", taskETag(&task))
 	c.JSON(http.StatusOK, task)
 }
 
 // DeleteTask deletes a task
 func DeleteTask(c *gin.Context) {
-	id := c.Param("id")
+	task, ok := findAuthorizedTask(c, taskActionDelete)
+	if !ok || !checkIfMatch(c, &task) {
+		return
+	}
+
+	// ゴミ箱へ移動（添付ファイルなどは完全削除まで残す）
+	userID, _ := middleware.GetUserID(c)
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// 読み込み後に更新されていたら削除しない
+		result := tx.Where("version = ?", task.Version).Delete(&task)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errVersionConflict
+		}
+		if err := bumpRelatedVersions(tx, nil, &task); err != nil {
+			return err
+		}
+		return recordTaskEvent(tx, task.ID, userID, models.EventDeleted, nil)
+	})
+	if err != nil {
+		respondTaskError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Task deleted successfully"})
+}
 
-	// タスク取得
+// respondTaskError maps errors from task rules to responses: 422 for workflow
+// violations, 400 for invalid references, 412 for concurrent edits and 500 otherwise
+func respondTaskError(c *gin.Context, err error) {
+	var terr *models.StatusTransitionError
+	var oerr *openSubtasksError
+	var berr *blockedTaskError
+	body := gin.H{"error": err.Error()}
+	switch {
+	case errors.As(err, &terr):
+		body["allowed"] = terr.Allowed
+	case errors.As(err, &oerr):
+		body["open_subtasks"] = oerr.open
+	case errors.As(err, &berr):
+		body["blocked_by"] = berr.blockers
+	}
+	c.JSON(taskErrorStatus(err), body)
+}
+
+// taskErrorStatus returns the HTTP status for an error from the task rules
+func taskErrorStatus(err error) int {
+	var terr *models.StatusTransitionError
+	var oerr *openSubtasksError
+	var herr *hierarchyError
+	var nerr *neighbourError
+	var berr *blockedTaskError
+	switch {
+	case errors.As(err, &terr), errors.As(err, &oerr), errors.As(err, &berr):
+		return http.StatusUnprocessableEntity
+	case errors.As(err, &herr), errors.As(err, &nerr):
+		return http.StatusBadRequest
+	case errors.Is(err, errVersionConflict):
+		return http.StatusPreconditionFailed
+	}
+	return http.StatusInternalServerError
+}
+
+// タスクに対する操作の種類
+const (
+	taskActionView = iota
+	taskActionStatus
+	taskActionEdit
+	taskActionDelete
+)
+
+// canAccessTask reports whether userID may perform action on task.
+// Personal tasks are public to read and writable only by their owner;
+// organization tasks follow the caller's membership role. Assignees may
+// additionally change the status of tasks they can see.
+func canAccessTask(db *gorm.DB, task *models.Task, userID uint, action int) bool {
+	if action == taskActionStatus && userID != 0 && isAssignee(db, task.ID, userID) {
+		return canAccessTask(db, task, userID, taskActionView)
+	}
+	if task.OrganizationID == nil {
+		return action == taskActionView || (userID != 0 && task.UserID == userID)
+	}
+	member, ok := findMember(db, *task.OrganizationID, userID)
+	if !ok {
+		return false
+	}
+	switch action {
+	case taskActionView:
+		return true
+	case taskActionStatus, taskActionEdit:
+		return member.CanEdit()
+	case taskActionDelete:
+		// 削除は管理者か、作成者本人のメンバーのみ
+		return member.CanManage() || (member.CanEdit() && task.CreatorID == userID)
+	}
+	return false
+}
+
+// findAuthorizedTask loads the task in the :id path parameter and checks that the
+// caller may perform action on it. On failure the error response has already been written.
+func findAuthorizedTask(c *gin.Context, action int) (models.Task, bool) {
 	var task models.Task
-	if err := database.DB.First(&task, id).Error; err != nil {
+	if err := database.DB.First(&task, c.Param("id")).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
-		return
+		return task, false
 	}
 
-	// 所有者チェック
 	userID, ok := middleware.GetUserID(c)
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "認証が必要です"})
-		return
+		return task, false
 	}
-	if task.UserID != userID {
+	if !canAccessTask(database.DB, &task, userID, action) {
+		// 閲覧できない組織のタスクは存在自体を隠す
+		if !canAccessTask(database.DB, &task, userID, taskActionView) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+			return task, false
+		}
 		c.JSON(http.StatusForbidden, gin.H{"error": "権限がありません"})
-		return
+		return task, false
 	}
+	return task, true
+}
 
-	// 削除実行
-	if err := database.DB.Delete(&task).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+// findVisibleTask loads the task in the :id path parameter, hiding organization
+// tasks from non-members. On failure the error response has already been written.
+func findVisibleTask(c *gin.Context) (models.Task, bool) {
+	var task models.Task
+	if err := database.DB.First(&task, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return task, false
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "Task deleted successfully"})
+	userID, _ := middleware.GetUserID(c)
+	if !canAccessTask(database.DB, &task, userID, taskActionView) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return task, false
+	}
+	return task, true
+}
+
+// visibleTasks limits a task query to personal tasks and tasks of the caller's organizations
+func visibleTasks(c *gin.Context, db *gorm.DB) *gorm.DB {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		return db.Where("organization_id IS NULL")
+	}
+	return db.Where("(organization_id IS NULL OR organization_id IN (SELECT organization_id FROM organization_members WHERE user_id = ?))", userID)
+}
+
+// actableTasks restricts a task query to the tasks userID may perform action on, following
+// canAccessTask: their own personal tasks, and organization tasks their role allows
+func actableTasks(db *gorm.DB, userID uint, action int) *gorm.DB {
+	const orgsWithRole = "SELECT organization_id FROM organization_members WHERE user_id = ? AND role IN ?"
+	editors := []string{models.RoleOwner, models.RoleAdmin, models.RoleMember}
+	managers := []string{models.RoleOwner, models.RoleAdmin}
+	switch action {
+	case taskActionStatus:
+		// 担当者は閲覧できるタスクのステータスを変更できる
+		return db.Where("((organization_id IS NULL AND user_id = ?) OR organization_id IN ("+orgsWithRole+") OR "+
+			"(id IN (SELECT task_id FROM task_assignees WHERE user_id = ?) AND (organization_id IS NULL OR organization_id IN (SELECT organization_id FROM organization_members WHERE user_id = ?))))",
+			userID, userID, editors, userID, userID)
+	case taskActionDelete:
+		return db.Where("((organization_id IS NULL AND user_id = ?) OR organization_id IN ("+orgsWithRole+") OR (creator_id = ? AND organization_id IN ("+orgsWithRole+")))",
+			userID, userID, managers, userID, userID, editors)
+	}
+	return db.Where("((organization_id IS NULL AND user_id = ?) OR organization_id IN ("+orgsWithRole+"))", userID, userID, editors)
+}
+
+// sameTaskScope reports whether two tasks belong to the same owner or organization
+func sameTaskScope(a, b *models.Task) bool {
+	if a.OrganizationID != nil || b.OrganizationID != nil {
+		return a.OrganizationID != nil && b.OrganizationID != nil && *a.OrganizationID == *b.OrganizationID
+	}
+	return a.UserID == b.UserID
 }
 
 // GetTasksByUser retrieves all tasks for a specific user
@@ -138,11 +668,11 @@ func GetTasksByUser(c *gin.Context) {
 		return
 	}
 
-	var tasks []models.Task
-	result := database.DB.Where("user_id = ?", userID).Find(&tasks)
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+	query, err := applyTaskFilters(c, database.DB.Model(&models.Task{}))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, tasks)
+	var tasks []models.Task
+	respondList(c, excludeArchivedProjects(c, visibleTasks(c, query.Where("user_id = ?", userID))), taskListSpec, &tasks)
 }
@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"flux/database"
+	"flux/middleware"
+	"flux/models"
+	"flux/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// MoveTaskRequest タスク並び替えリクエスト
+// after_id / before_id は移動先で直前・直後に来るタスク（どちらか省略可、両方省略で末尾）
+type MoveTaskRequest struct {
+	Status   string `json:"status"`
+	AfterID  *uint  `json:"after_id"`
+	BeforeID *uint  `json:"before_id"`
+}
+
+// MoveTask repositions a task between two neighbours, optionally in another status column
+func MoveTask(c *gin.Context) {
+	task, ok := findAuthorizedTask(c, taskActionStatus)
+	if !ok || !checkIfMatch(c, &task) {
+		return
+	}
+
+	var req MoveTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Status == "" {
+		req.Status = task.Status
+	}
+	before := task
+	completing := req.Status == models.StatusCompleted && task.Status != models.StatusCompleted
+	starting := req.Status == models.StatusInProgress && task.Status != models.StatusInProgress
+	if err := task.SetStatus(req.Status, time.Now()); err != nil {
+		respondTaskError(c, err)
+		return
+	}
+
+	userID, _ := middleware.GetUserID(c)
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		rank, err := rankForMove(tx, &task, userID, req)
+		if errors.Is(err, utils.ErrInvalidRank) {
+			// 既存データのランクが重複している場合はボードの列を採番し直してから再計算する
+			if err := rebalanceRanks(tx, &task); err != nil {
+				return err
+			}
+			if rank, err = rankForMove(tx, &task, userID, req); errors.Is(err, utils.ErrInvalidRank) {
+				return &neighbourError{msg: "after_id must come before before_id"}
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if starting {
+			if err := checkNotBlocked(tx, &task, c.Query("force") == "true"); err != nil {
+				return err
+			}
+		}
+		if completing {
+			if err := completeSubtasks(tx, &task, c.Query("cascade") == "true", time.Now()); err != nil {
+				return err
+			}
+			if err := spawnNextOccurrence(tx, &task, time.Now()); err != nil {
+				return err
+			}
+		}
+		task.Rank = rank
+		if err := saveTaskVersioned(tx, &task); err != nil {
+			return err
+		}
+		if err := bumpRelatedVersions(tx, &before, &task); err != nil {
+			return err
+		}
+		return recordTaskUpdate(tx, &before, &task, userID)
+	})
+	if err != nil {
+		respondTaskError(c, err)
+		return
+	}
+
+	c.Header("ETag", taskETag(&task))
+	c.JSON(http.StatusOK, task)
+}
+
+// neighbourError 指定された隣接タスクが不正
+type neighbourError struct {
+	msg string
+}
+
+func (e *neighbourError) Error() string { return e.msg }
+
+// rankForMove 隣接タスクのランクから、task のボードの移動先の列でのランクを求める
+func rankForMove(tx *gorm.DB, task *models.Task, userID uint, req MoveTaskRequest) (string, error) {
+	column := boardTasks(tx, task).Where("id <> ?", task.ID)
+
+	neighbour := func(id *uint) (string, error) {
+		var t models.Task
+		if err := boardTasks(tx, task).Where("id = ?", *id).First(&t).Error; err != nil ||
+			!canAccessTask(tx, &t, userID, taskActionView) {
+			return "", &neighbourError{msg: "neighbour task not found in target status"}
+		}
+		if t.ID == task.ID {
+			return "", &neighbourError{msg: "a task cannot be its own neighbour"}
+		}
+		return t.Rank, nil
+	}
+
+	var lower, upper string
+	var err error
+	switch {
+	case req.AfterID != nil && req.BeforeID != nil:
+		if lower, err = neighbour(req.AfterID); err != nil {
+			return "", err
+		}
+		if upper, err = neighbour(req.BeforeID); err != nil {
+			return "", err
+		}
+	case req.AfterID != nil:
+		if lower, err = neighbour(req.AfterID); err != nil {
+			return "", err
+		}
+		upper, err = adjacentRank(column, "rank > ?", lower, "rank")
+	case req.BeforeID != nil:
+		if upper, err = neighbour(req.BeforeID); err != nil {
+			return "", err
+		}
+		lower, err = adjacentRank(column, "rank < ?", upper, "rank DESC")
+	default:
+		lower, err = lastRank(tx, task)
+	}
+	if err != nil {
+		return "", err
+	}
+	if (req.AfterID != nil && lower == "") || (req.BeforeID != nil && upper == "") {
+		// 明示された隣接タスクにランクが無い
+		return "", utils.ErrInvalidRank
+	}
+	if upper == "" {
+		rank, err := utils.RankAfter(lower)
+		if errors.Is(err, utils.ErrRankExhausted) {
+			// 振り直して再計算する
+			return "", utils.ErrInvalidRank
+		}
+		return rank, err
+	}
+	return utils.RankBetween(lower, upper)
+}
+
+// adjacentRank 条件に合う最も近いランクを返す（無ければ空文字）
+func adjacentRank(column *gorm.DB, cond, rank, order string) (string, error) {
+	var ranks []string
+	err := column.Session(&gorm.Session{}).Where(cond, rank).Order(order).Limit(1).Pluck("rank", &ranks).Error
+	if err != nil || len(ranks) == 0 {
+		return "", err
+	}
+	return ranks[0], nil
+}
+
+// boardTasks task と同じボードの列のタスクを選ぶ。ボードは組織（個人のタスクは所有者）と
+// プロジェクトごとに分かれ、列は task のステータス
+func boardTasks(db *gorm.DB, task *models.Task) *gorm.DB {
+	query := db.Model(&models.Task{}).Where("status = ?", task.Status)
+	if task.OrganizationID != nil {
+		query = query.Where("organization_id = ?", *task.OrganizationID)
+	} else {
+		query = query.Where("organization_id IS NULL AND user_id = ?", task.UserID)
+	}
+	if task.ProjectID != nil {
+		return query.Where("project_id = ?", *task.ProjectID)
+	}
+	return query.Where("project_id IS NULL")
+}
+
+// appendRank task のボードの列の末尾に追加するランクを返す。固定長のランクの余地が
+// 無くなった列は振り直してから採番する
+func appendRank(tx *gorm.DB, task *models.Task) (string, error) {
+	last, err := lastRank(tx, task)
+	if err != nil {
+		return "", err
+	}
+	rank, err := utils.RankAfter(last)
+	if !errors.Is(err, utils.ErrRankExhausted) {
+		return rank, err
+	}
+	if err := rebalanceRanks(tx, task); err != nil {
+		return "", err
+	}
+	if last, err = lastRank(tx, task); err != nil {
+		return "", err
+	}
+	return utils.RankAfter(last)
+}
+
+// lastRank task のボードの列の末尾のランクを返す（task 自身は除く）
+func lastRank(db *gorm.DB, task *models.Task) (string, error) {
+	var ranks []string
+	err := boardTasks(db, task).Where("id <> ?", task.ID).Order("rank DESC").Limit(1).Pluck("rank", &ranks).Error
+	if err != nil || len(ranks) == 0 {
+		return "", err
+	}
+	return ranks[0], nil
+}
+
+// rebalanceRanks task のボードの列のランクを現在の並び順のまま等間隔の固定長に振り直す。
+// 振り直したタスクはバージョンを上げ、task 自身は呼び出し側で保存する
+func rebalanceRanks(tx *gorm.DB, task *models.Task) error {
+	var tasks []models.Task
+	if err := boardTasks(tx, task).Select("id", "rank").Where("id <> ?", task.ID).Order("rank, id").Find(&tasks).Error; err != nil {
+		return err
+	}
+	ranks := utils.RankSequence(len(tasks))
+	for i, t := range tasks {
+		rank := ranks[i]
+		if rank != t.Rank {
+			err := tx.Model(&models.Task{}).Where("id = ?", t.ID).
+				Updates(map[string]interface{}{"rank": rank, "version": gorm.Expr("version + 1")}).Error
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/url"
+    "strconv"
+    "testing"
+
+    "flux/database"
+    "flux/models"
+    "github.com/gin-gonic/gin"
+)
+
+func createRankedTasks(t *testing.T, u models.User, titles ...string) []models.Task {
+    t.Helper()
+    var tasks []models.Task
+    for _, title := range titles {
+        w, c := performJSONRequest(CreateTask, http.MethodPost, models.Task{Title: title})
+        c.Set("user_id", u.ID)
+        CreateTask(c)
+        if w.Code != http.StatusCreated { t.Fatalf("expected 201, got %d", w.Code) }
+        var task models.Task
+        if err := json.Unmarshal(w.Body.Bytes(), &task); err != nil { t.Fatal(err) }
+        tasks = append(tasks, task)
+    }
+    return tasks
+}
+
+func moveTask(t *testing.T, userID, taskID uint, req MoveTaskRequest) int {
+    t.Helper()
+    w, c := performJSONRequest(MoveTask, http.MethodPost, req)
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(taskID))}}
+    c.Set("user_id", userID)
+    MoveTask(c)
+    return w.Code
+}
+
+func rankedTitles(t *testing.T, status string) string {
+    t.Helper()
+    _, body := performListRequest(t, GetTasks, url.Values{"status": {status}, "sort": {"rank"}})
+    var res taskListResult
+    if err := json.Unmarshal(body, &res); err != nil { t.Fatal(err) }
+    var titles []string
+    for _, task := range res.Data { titles = append(titles, task.Title) }
+    return fmt.Sprint(titles)
+}
+
+func TestCreateTask_DefaultPriorityAndRank(t *testing.T) {
+    setupTaskDB(t)
+    u := models.User{Name: "U", Email: "rank@example.com", Password: "Password1!"}
+    if err := database.DB.Create(&u).Error; err != nil { t.Fatal(err) }
+
+    tasks := createRankedTasks(t, u, "A", "B")
+    if tasks[0].Priority != models.PriorityMedium { t.Fatalf("expected medium priority, got %v", tasks[0].Priority) }
+    if tasks[0].Rank == "" || tasks[0].Rank >= tasks[1].Rank { t.Fatalf("unexpected ranks: %q %q", tasks[0].Rank, tasks[1].Rank) }
+
+    w, c := performJSONRequest(CreateTask, http.MethodPost, map[string]string{"title": "C", "priority": "critical"})
+    c.Set("user_id", u.ID)
+    CreateTask(c)
+    if w.Code != http.StatusBadRequest { t.Fatalf("expected 400 for invalid priority, got %d", w.Code) }
+}
+
+func TestCreateTask_RanksStayShort(t *testing.T) {
+    setupTaskDB(t)
+    u := models.User{Name: "U", Email: "append@example.com", Password: "Password1!"}
+    if err := database.DB.Create(&u).Error; err != nil { t.Fatal(err) }
+
+    // 末尾への追加を繰り返してもランクは伸びない
+    titles := make([]string, 3000)
+    for i := range titles { titles[i] = fmt.Sprintf("T%d", i) }
+    tasks := createRankedTasks(t, u, titles...)
+    for i, task := range tasks {
+        if len(task.Rank) > 6 { t.Fatalf("rank %d grew to %d characters: %q", i, len(task.Rank), task.Rank) }
+        if i > 0 && task.Rank <= tasks[i-1].Rank { t.Fatalf("ranks out of order at %d: %q %q", i, tasks[i-1].Rank, task.Rank) }
+    }
+}
+
+func TestCreateTask_RebalancesExhaustedColumn(t *testing.T) {
+    setupTaskDB(t)
+    u := models.User{Name: "U", Email: "exhausted@example.com", Password: "Password1!"}
+    if err := database.DB.Create(&u).Error; err != nil { t.Fatal(err) }
+
+    // 以前の採番で末尾の余地を使い切った列
+    for _, task := range []models.Task{{Title: "A", UserID: u.ID, Rank: "zzzzzzzzi"}, {Title: "B", UserID: u.ID, Rank: "zzzzzzzzr"}} {
+        if err := database.DB.Create(&task).Error; err != nil { t.Fatal(err) }
+    }
+    created := createRankedTasks(t, u, "C")[0]
+    if len(created.Rank) > 6 { t.Fatalf("expected a fixed-width rank, got %q", created.Rank) }
+    if got := rankedTitles(t, "pending"); got != "[A B C]" { t.Fatalf("unexpected order: %s", got) }
+}
+
+func TestMoveTask_BetweenNeighbours(t *testing.T) {
+    setupTaskDB(t)
+    u := models.User{Name: "U", Email: "move@example.com", Password: "Password1!"}
+    if err := database.DB.Create(&u).Error; err != nil { t.Fatal(err) }
+    tasks := createRankedTasks(t, u, "A", "B", "C", "D")
+
+    // D を A と B の間へ
+    if code := moveTask(t, u.ID, tasks[3].ID, MoveTaskRequest{AfterID: &tasks[0].ID, BeforeID: &tasks[1].ID}); code != http.StatusOK {
+        t.Fatalf("expected 200, got %d", code)
+    }
+    if got := rankedTitles(t, "pending"); got != "[A D B C]" { t.Fatalf("unexpected order: %s", got) }
+
+    // A を C の後ろへ（before_id 省略）
+    if code := moveTask(t, u.ID, tasks[0].ID, MoveTaskRequest{AfterID: &tasks[2].ID}); code != http.StatusOK {
+        t.Fatalf("expected 200, got %d", code)
+    }
+    if got := rankedTitles(t, "pending"); got != "[D B C A]" { t.Fatalf("unexpected order: %s", got) }
+
+    // 別のステータス列の末尾へ
+    if code := moveTask(t, u.ID, tasks[1].ID, MoveTaskRequest{Status: "in_progress"}); code != http.StatusOK {
+        t.Fatalf("expected 200, got %d", code)
+    }
+    if got := rankedTitles(t, "in_progress"); got != "[B]" { t.Fatalf("unexpected order: %s", got) }
+
+    // 逆順の隣接指定は400
+    if code := moveTask(t, u.ID, tasks[2].ID, MoveTaskRequest{AfterID: &tasks[0].ID, BeforeID: &tasks[3].ID}); code != http.StatusBadRequest {
+        t.Fatalf("expected 400, got %d", code)
+    }
+    // 他人のタスクは403
+    if code := moveTask(t, u.ID+1, tasks[2].ID, MoveTaskRequest{}); code != http.StatusForbidden {
+        t.Fatalf("expected 403, got %d", code)
+    }
+}
+
+func TestMoveTask_RebalancesLegacyRanks(t *testing.T) {
+    setupTaskDB(t)
+    u := models.User{Name: "U", Email: "legacy@example.com", Password: "Password1!"}
+    if err := database.DB.Create(&u).Error; err != nil { t.Fatal(err) }
+
+    // ランク未設定の既存データ
+    var tasks []models.Task
+    for _, title := range []string{"A", "B", "C"} {
+        task := models.Task{Title: title, UserID: u.ID}
+        if err := database.DB.Create(&task).Error; err != nil { t.Fatal(err) }
+        tasks = append(tasks, task)
+    }
+
+    if code := moveTask(t, u.ID, tasks[2].ID, MoveTaskRequest{AfterID: &tasks[0].ID, BeforeID: &tasks[1].ID}); code != http.StatusOK {
+        t.Fatalf("expected 200, got %d", code)
+    }
+    if got := rankedTitles(t, "pending"); got != "[A C B]" { t.Fatalf("unexpected order: %s", got) }
+}
+
+func TestMoveTask_RanksArePerBoard(t *testing.T) {
+    setupTaskDB(t)
+    u := models.User{Name: "U", Email: "board@example.com", Password: "Password1!"}
+    other := models.User{Name: "O", Email: "other-board@example.com", Password: "Password1!"}
+    database.DB.Create(&u)
+    database.DB.Create(&other)
+
+    // 別のユーザーのボードには重複したランクがあっても触れない
+    theirs := []models.Task{{Title: "X", UserID: other.ID, Rank: "m"}, {Title: "Y", UserID: other.ID, Rank: "m"}}
+    for i := range theirs { database.DB.Create(&theirs[i]) }
+
+    // 自分のボードは重複したランクのため採番し直しになる
+    mine := []models.Task{{Title: "A", UserID: u.ID, Rank: "m"}, {Title: "B", UserID: u.ID, Rank: "m"}, {Title: "C", UserID: u.ID, Rank: "n"}}
+    for i := range mine { database.DB.Create(&mine[i]) }
+
+    w, c := performJSONRequest(MoveTask, http.MethodPost, MoveTaskRequest{AfterID: &mine[0].ID, BeforeID: &mine[1].ID})
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(mine[2].ID))}}
+    c.Set("user_id", u.ID)
+    MoveTask(c)
+    if w.Code != http.StatusOK { t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String()) }
+    if w.Header().Get("ETag") == "" { t.Fatal("expected an ETag header") }
+
+    var saved []models.Task
+    database.DB.Where("user_id = ?", u.ID).Order("rank, id").Find(&saved)
+    var titles []string
+    for _, task := range saved { titles = append(titles, task.Title) }
+    if fmt.Sprint(titles) != "[A C B]" { t.Fatalf("unexpected order: %v", titles) }
+    // 採番し直したタスクはバージョンが上がる
+    for _, task := range saved {
+        if task.Title == "B" && task.Version != 2 { t.Fatalf("expected rebalanced task version 2, got %d", task.Version) }
+    }
+
+    var untouched []models.Task
+    database.DB.Where("user_id = ?", other.ID).Find(&untouched)
+    for _, task := range untouched {
+        if task.Rank != "m" || task.Version != 1 { t.Fatalf("another board was rewritten: %+v", task) }
+    }
+
+    // 別のボードのタスクは隣接タスクに指定できない
+    if code := moveTask(t, u.ID, mine[0].ID, MoveTaskRequest{AfterID: &theirs[0].ID}); code != http.StatusBadRequest {
+        t.Fatalf("expected 400, got %d", code)
+    }
+}
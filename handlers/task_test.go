@@ -2,9 +2,12 @@ package handlers
 
 import (
     "encoding/json"
+    "fmt"
     "net/http"
     "strconv"
+    "net/url"
     "testing"
+    "time"
 
     "flux/database"
     "flux/models"
@@ -17,7 +20,7 @@ func setupTaskDB(t *testing.T) *gorm.DB {
     t.Helper()
     db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
     if err != nil { t.Fatalf("open db: %v", err) }
-    if err := db.AutoMigrate(&models.User{}, &models.Task{}); err != nil { t.Fatalf("migrate: %v", err) }
+    if err := db.AutoMigrate(&models.User{}, &models.Task{}, &models.PasswordReset{}, &models.Label{}, &models.TaskDependency{}, &models.Project{}, &models.Organization{}, &models.OrganizationMember{}, &models.Comment{}, &models.Mention{}, &models.Notification{}, &models.Attachment{}, &models.TaskEvent{}, &models.CalendarFeed{}, &models.TimeEntry{}, &models.CustomField{}, &models.TaskFieldValue{}); err != nil { t.Fatalf("migrate: %v", err) }
     database.DB = db
     return db
 }
@@ -42,9 +45,12 @@ func TestCreateAndGetTask(t *testing.T) {
     GetTasks(c2)
     if w2.Code != http.StatusOK { t.Fatalf("expected 200, got %d", w2.Code) }
 
-    var tasks []models.Task
-    if err := json.Unmarshal(w2.Body.Bytes(), &tasks); err != nil { t.Fatal(err) }
-    if len(tasks) != 1 || tasks[0].Title != "T" { t.Fatalf("unexpected tasks: %+v", tasks) }
+    var res struct {
+        Data  []models.Task `json:"data"`
+        Total int64         `json:"total"`
+    }
+    if err := json.Unmarshal(w2.Body.Bytes(), &res); err != nil { t.Fatal(err) }
+    if res.Total != 1 || len(res.Data) != 1 || res.Data[0].Title != "T" { t.Fatalf("unexpected tasks: %+v", res) }
 }
 
 func TestGetTask_NotFoundAndFound(t *testing.T) {
@@ -101,3 +107,81 @@ func TestUpdateAndDeleteTask(t *testing.T) {
     DeleteTask(c3)
     if w3.Code != http.StatusNotFound { t.Fatalf("expected 404, got %d", w3.Code) }
 }
+
+func TestCreateTask_InvalidDates(t *testing.T) {
+    setupTaskDB(t)
+
+    due := time.Now()
+    start := due.Add(time.Hour)
+    body := models.Task{Title: "T", StartAt: &start, DueAt: &due}
+    w, c := performJSONRequest(CreateTask, http.MethodPost, body)
+    c.Set("user_id", uint(1))
+    CreateTask(c)
+    if w.Code != http.StatusBadRequest { t.Fatalf("expected 400, got %d", w.Code) }
+}
+
+func TestGetTasks_DueFilters(t *testing.T) {
+    setupTaskDB(t)
+
+    u := models.User{Name: "U", Email: "due@example.com", Password: "Password1!"}
+    if err := database.DB.Create(&u).Error; err != nil { t.Fatal(err) }
+
+    loc, _ := time.LoadLocation("Asia/Tokyo")
+    now := time.Now().In(loc)
+    yesterday := now.AddDate(0, 0, -1)
+    later := time.Date(now.Year(), now.Month(), now.Day(), 23, 59, 0, 0, loc)
+    for _, task := range []models.Task{
+        {Title: "overdue", DueAt: &yesterday, UserID: u.ID},
+        {Title: "done", DueAt: &yesterday, Status: "completed", UserID: u.ID},
+        {Title: "today", DueAt: &later, UserID: u.ID},
+        {Title: "none", UserID: u.ID},
+    } {
+        if err := database.DB.Create(&task).Error; err != nil { t.Fatal(err) }
+    }
+
+    var res taskListResult
+    code, body := performListRequest(t, GetTasks, url.Values{"due": {"overdue"}, "tz": {"Asia/Tokyo"}})
+    if err := json.Unmarshal(body, &res); err != nil { t.Fatal(err) }
+    if code != http.StatusOK || res.Total != 1 || res.Data[0].Title != "overdue" || !res.Data[0].Overdue {
+        t.Fatalf("unexpected overdue result: %d %+v", code, res)
+    }
+
+    code, body = performListRequest(t, GetTasks, url.Values{"due": {"today"}, "tz": {"Asia/Tokyo"}})
+    if err := json.Unmarshal(body, &res); err != nil { t.Fatal(err) }
+    if code != http.StatusOK || res.Total != 1 || res.Data[0].Title != "today" {
+        t.Fatalf("unexpected today result: %d %+v", code, res)
+    }
+
+    if code, _ := performListRequest(t, GetTasks, url.Values{"due": {"today"}, "tz": {"Mars/Olympus"}}); code != http.StatusBadRequest {
+        t.Fatalf("expected 400 for invalid tz, got %d", code)
+    }
+}
+
+func TestUpdateTask_StatusWorkflow(t *testing.T) {
+    setupTaskDB(t)
+
+    u := models.User{Name: "U", Email: "flow@example.com", Password: "Password1!"}
+    if err := database.DB.Create(&u).Error; err != nil { t.Fatal(err) }
+    task := models.Task{Title: "Flow", Status: models.StatusCompleted, UserID: u.ID}
+    if err := database.DB.Create(&task).Error; err != nil { t.Fatal(err) }
+
+    update := func(status string) (int, map[string]interface{}) {
+        w, c := performJSONRequest(UpdateTask, http.MethodPut, map[string]string{"title": "Flow", "status": status})
+        c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(task.ID))}}
+        c.Set("user_id", u.ID)
+        UpdateTask(c)
+        var body map[string]interface{}
+        _ = json.Unmarshal(w.Body.Bytes(), &body)
+        return w.Code, body
+    }
+
+    if code, body := update("complete"); code != http.StatusUnprocessableEntity || body["allowed"] == nil {
+        t.Fatalf("expected 422 with allowed states for typo, got %d %v", code, body)
+    }
+    if code, body := update("pending"); code != http.StatusUnprocessableEntity || fmt.Sprint(body["allowed"]) != "[in_progress]" {
+        t.Fatalf("expected 422 listing in_progress, got %d %v", code, body)
+    }
+    if code, body := update("in_progress"); code != http.StatusOK || body["in_progress_at"] == nil {
+        t.Fatalf("expected 200 with in_progress_at, got %d %v", code, body)
+    }
+}
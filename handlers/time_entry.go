@@ -0,0 +1,571 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"flux/database"
+	"flux/middleware"
+	"flux/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// timeEntryMaxMinutes 手動で記録できる1件あたりの最大の作業時間
+const timeEntryMaxMinutes = 24 * 60
+
+// timeReportGroups 作業時間レポートで集計できる単位
+var timeReportGroups = map[string]bool{"user": true, "project": true, "task": true, "date": true}
+
+// TimeEntryRequest 作業時間の手動記録・編集リクエスト（ended_at と minutes はどちらか一方）
+type TimeEntryRequest struct {
+	StartedAt *time.Time `json:"started_at" binding:"required"`
+	EndedAt   *time.Time `json:"ended_at"`
+	Minutes   *int       `json:"minutes"`
+	Note      string     `json:"note" binding:"max=500"`
+}
+
+var timeEntryListSpec = listSpec{
+	sortFields: map[string]string{
+		"id":         "id",
+		"started_at": "started_at",
+		"minutes":    "minutes",
+	},
+	defaultSort: "started_at",
+	preloads:    []string{"User"},
+}
+
+// GetTaskTimeEntries lists the time logged on a task, including running timers
+func GetTaskTimeEntries(c *gin.Context) {
+	task, ok := findAuthorizedTask(c, taskActionView)
+	if !ok {
+		return
+	}
+	var entries []models.TimeEntry
+	respondList(c, database.DB.Model(&models.TimeEntry{}).Where("task_id = ?", task.ID), timeEntryListSpec, &entries)
+}
+
+// CreateTaskTimeEntry logs time on a task manually
+func CreateTaskTimeEntry(c *gin.Context) {
+	task, ok := findAuthorizedTask(c, taskActionStatus)
+	if !ok {
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+	entry := models.TimeEntry{TaskID: task.ID, UserID: userID}
+	if !bindTimeEntry(c, &entry) {
+		return
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&entry).Error; err != nil {
+			return err
+		}
+		// 作業時間の合計が変わる
+		return bumpTaskVersion(tx, entry.TaskID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respondTimeEntry(c, http.StatusCreated, entry)
+}
+
+// UpdateTaskTimeEntry replaces a stopped time entry; only the person who logged it may
+func UpdateTaskTimeEntry(c *gin.Context) {
+	_, entry, ok := findTaskTimeEntry(c)
+	if !ok {
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+	if entry.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "権限がありません"})
+		return
+	}
+	if entry.Running() {
+		c.JSON(http.StatusConflict, gin.H{"error": "stop the timer before editing the entry"})
+		return
+	}
+	if !bindTimeEntry(c, &entry) {
+		return
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entry).Select("started_at", "ended_at", "minutes", "note").Updates(&entry).Error; err != nil {
+			return err
+		}
+		return bumpTaskVersion(tx, entry.TaskID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respondTimeEntry(c, http.StatusOK, entry)
+}
+
+// DeleteTaskTimeEntry deletes a time entry, discarding it if the timer is running.
+// The person who logged it or anyone who may delete the task can.
+func DeleteTaskTimeEntry(c *gin.Context) {
+	task, entry, ok := findTaskTimeEntry(c)
+	if !ok {
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+	if entry.UserID != userID && !canAccessTask(database.DB, &task, userID, taskActionDelete) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "権限がありません"})
+		return
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&entry).Error; err != nil {
+			return err
+		}
+		return bumpTaskVersion(tx, entry.TaskID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Time entry deleted successfully"})
+}
+
+// StartTaskTimer starts a timer on a task. A user can run only one timer at a
+// time, so starting a second one responds 409 with the running timer.
+func StartTaskTimer(c *gin.Context) {
+	task, ok := findAuthorizedTask(c, taskActionStatus)
+	if !ok {
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+
+	entry := models.TimeEntry{TaskID: task.ID, UserID: userID, StartedAt: time.Now()}
+	var running models.TimeEntry
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ? AND ended_at IS NULL", userID).First(&running).Error
+		if err == nil {
+			return errTimerRunning
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		// 同時に開始された場合は一意インデックスで2件目の作成が失敗する
+		return tx.Create(&entry).Error
+	})
+	if errors.Is(err, errTimerRunning) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "timer": running})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respondTimeEntry(c, http.StatusCreated, entry)
+}
+
+// StopTaskTimer stops the caller's running timer on a task and records the elapsed minutes
+func StopTaskTimer(c *gin.Context) {
+	task, ok := findAuthorizedTask(c, taskActionView)
+	if !ok {
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+
+	var entry models.TimeEntry
+	if err := database.DB.Where("task_id = ? AND user_id = ? AND ended_at IS NULL", task.ID, userID).First(&entry).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no running timer on this task"})
+		return
+	}
+	stopTimer(c, entry)
+}
+
+// StopRunningTimer stops the caller's running timer whichever task it is on. The
+// task is not checked, so a timer on a deleted task or one the caller can no
+// longer see can still be stopped.
+func StopRunningTimer(c *gin.Context) {
+	entry, ok := findRunningTimer(c)
+	if !ok {
+		return
+	}
+	stopTimer(c, entry)
+}
+
+// DiscardRunningTimer deletes the caller's running timer without logging the time,
+// whichever task it is on
+func DiscardRunningTimer(c *gin.Context) {
+	entry, ok := findRunningTimer(c)
+	if !ok {
+		return
+	}
+	if err := database.DB.Delete(&entry).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Timer discarded successfully"})
+}
+
+// findRunningTimer loads the authenticated caller's running timer. On failure the
+// error response has already been written.
+func findRunningTimer(c *gin.Context) (models.TimeEntry, bool) {
+	var entry models.TimeEntry
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "認証が必要です"})
+		return entry, false
+	}
+	if err := database.DB.Where("user_id = ? AND ended_at IS NULL", userID).First(&entry).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no running timer"})
+		return entry, false
+	}
+	return entry, true
+}
+
+// stopTimer stops a running timer, records the elapsed minutes and writes the response
+func stopTimer(c *gin.Context, entry models.TimeEntry) {
+	if err := entry.Stop(time.Now()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entry).Select("ended_at", "minutes").Updates(&entry).Error; err != nil {
+			return err
+		}
+		return bumpTaskVersion(tx, entry.TaskID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respondTimeEntry(c, http.StatusOK, entry)
+}
+
+// GetRunningTimer returns the caller's running timer, or null if none is running
+func GetRunningTimer(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "認証が必要です"})
+		return
+	}
+	var entry models.TimeEntry
+	err := database.DB.Where("user_id = ? AND ended_at IS NULL", userID).First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusOK, gin.H{"timer": nil})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"timer": entry})
+}
+
+// errTimerRunning is returned when the caller starts a timer while another one runs
+var errTimerRunning = errors.New("a timer is already running")
+
+// bindTimeEntry reads a TimeEntryRequest into entry, deriving ended_at or minutes
+// from the other. On failure the error response has already been written.
+func bindTimeEntry(c *gin.Context, entry *models.TimeEntry) bool {
+	var req TimeEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if (req.EndedAt == nil) == (req.Minutes == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "specify exactly one of ended_at or minutes"})
+		return false
+	}
+
+	entry.StartedAt = *req.StartedAt
+	entry.Note = strings.TrimSpace(req.Note)
+	if req.Minutes != nil {
+		if *req.Minutes < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "minutes must not be negative"})
+			return false
+		}
+		end := req.StartedAt.Add(time.Duration(*req.Minutes) * time.Minute)
+		req.EndedAt = &end
+	}
+	if err := entry.Stop(*req.EndedAt); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if entry.Minutes > timeEntryMaxMinutes {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("an entry can be at most %d minutes", timeEntryMaxMinutes)})
+		return false
+	}
+	return true
+}
+
+// findTaskTimeEntry loads the task in :id and its time entry in :entry_id for an
+// authenticated caller who can see the task. On failure the error response has already been written.
+func findTaskTimeEntry(c *gin.Context) (models.Task, models.TimeEntry, bool) {
+	var entry models.TimeEntry
+	task, ok := findAuthorizedTask(c, taskActionView)
+	if !ok {
+		return task, entry, false
+	}
+	if err := database.DB.Where("task_id = ?", task.ID).First(&entry, c.Param("entry_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Time entry not found"})
+		return task, entry, false
+	}
+	return task, entry, true
+}
+
+func respondTimeEntry(c *gin.Context, status int, entry models.TimeEntry) {
+	if err := database.DB.Preload("User").First(&entry, entry.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(status, entry)
+}
+
+// loggedMinutes sums the stopped time entries of each task
+func loggedMinutes(db *gorm.DB, taskIDs []uint) (map[uint]int64, error) {
+	var rows []struct {
+		TaskID  uint
+		Minutes int64
+	}
+	err := db.Model(&models.TimeEntry{}).Select("task_id, SUM(minutes) AS minutes").
+		Where("task_id IN ? AND ended_at IS NOT NULL", taskIDs).Group("task_id").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	minutes := make(map[uint]int64, len(rows))
+	for _, r := range rows {
+		minutes[r.TaskID] = r.Minutes
+	}
+	return minutes, nil
+}
+
+// TimeReportRow 作業時間レポートの1行（group_by に含まれない項目は省略する）
+type TimeReportRow struct {
+	UserID           *uint  `json:"user_id,omitempty"`
+	UserName         string `json:"user_name,omitempty"`
+	ProjectID        *uint  `json:"project_id,omitempty"` // プロジェクトに属さないタスクは省略
+	ProjectName      string `json:"project_name,omitempty"`
+	TaskID           *uint  `json:"task_id,omitempty"`
+	TaskTitle        string `json:"task_title,omitempty"`
+	EstimatedMinutes *int   `json:"estimated_minutes,omitempty"`
+	Date             string `json:"date,omitempty"`
+	Minutes          int64  `json:"minutes"`
+	Entries          int64  `json:"entries"`
+}
+
+// TimeReport 作業時間レポート
+type TimeReport struct {
+	From         *time.Time      `json:"from"`
+	To           *time.Time      `json:"to"`
+	TimeZone     string          `json:"time_zone"`
+	GroupBy      []string        `json:"group_by"`
+	TotalMinutes int64           `json:"total_minutes"`
+	Rows         []TimeReportRow `json:"rows"`
+}
+
+// timeReportKey 集計のキー（group_by に含まれない項目はゼロ値のまま）
+type timeReportKey struct {
+	userID    uint
+	projectID uint
+	taskID    uint
+	date      string
+}
+
+// GetTimeReport aggregates stopped time entries by user, project, task and/or date.
+// It covers the entries the caller logged, those on the caller's personal tasks and
+// those on tasks of the caller's organizations.
+func GetTimeReport(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "認証が必要です"})
+		return
+	}
+	loc, err := callerLocation(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	groups := map[string]bool{}
+	report := TimeReport{TimeZone: loc.String(), GroupBy: []string{}, Rows: []TimeReportRow{}}
+	for _, g := range strings.Split(c.DefaultQuery("group_by", "user,project"), ",") {
+		if g = strings.TrimSpace(g); g == "" || groups[g] {
+			continue
+		}
+		if !timeReportGroups[g] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "group_by must be a list of user, project, task, date"})
+			return
+		}
+		groups[g] = true
+		report.GroupBy = append(report.GroupBy, g)
+	}
+
+	query := database.DB.Table("time_entries").
+		Select("time_entries.user_id, time_entries.task_id, tasks.project_id, time_entries.started_at, time_entries.minutes").
+		Joins("JOIN tasks ON tasks.id = time_entries.task_id AND tasks.deleted_at IS NULL").
+		Where("time_entries.ended_at IS NOT NULL").
+		Where("(time_entries.user_id = ? OR (tasks.organization_id IS NULL AND tasks.user_id = ?) OR tasks.organization_id IN (SELECT organization_id FROM organization_members WHERE user_id = ?))", userID, userID, userID)
+	for _, f := range []struct{ param, column string }{
+		{"user_id", "time_entries.user_id"},
+		{"project_id", "tasks.project_id"},
+		{"task_id", "time_entries.task_id"},
+	} {
+		if v := c.Query(f.param); v != "" {
+			id, err := strconv.Atoi(v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + f.param})
+				return
+			}
+			query = query.Where(f.column+" = ?", id)
+		}
+	}
+	if v := c.Query("from"); v != "" {
+		from, err := parseReportTime(v, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from: " + v})
+			return
+		}
+		report.From = &from
+		query = query.Where("time_entries.started_at >= ?", from)
+	}
+	if v := c.Query("to"); v != "" {
+		to, err := parseReportTime(v, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to: " + v})
+			return
+		}
+		// 日付のみ指定された場合はその日の終わりまでを含める
+		if len(v) == len("2006-01-02") {
+			to = to.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+		report.To = &to
+		query = query.Where("time_entries.started_at <= ?", to)
+	}
+
+	var entries []struct {
+		UserID    uint
+		TaskID    uint
+		ProjectID *uint
+		StartedAt time.Time
+		Minutes   int64
+	}
+	if err := query.Scan(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	totals := map[timeReportKey]*TimeReportRow{}
+	var keys []timeReportKey
+	for _, e := range entries {
+		var key timeReportKey
+		if groups["user"] {
+			key.userID = e.UserID
+		}
+		if groups["project"] && e.ProjectID != nil {
+			key.projectID = *e.ProjectID
+		}
+		if groups["task"] {
+			key.taskID = e.TaskID
+		}
+		if groups["date"] {
+			key.date = e.StartedAt.In(loc).Format("2006-01-02")
+		}
+		row, ok := totals[key]
+		if !ok {
+			row = &TimeReportRow{Date: key.date}
+			totals[key] = row
+			keys = append(keys, key)
+		}
+		row.Minutes += e.Minutes
+		row.Entries++
+		report.TotalMinutes += e.Minutes
+	}
+
+	if err := nameTimeReportRows(database.DB, groups, keys, totals); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.date != b.date {
+			return a.date < b.date
+		}
+		if a.userID != b.userID {
+			return a.userID < b.userID
+		}
+		if a.projectID != b.projectID {
+			return a.projectID < b.projectID
+		}
+		return a.taskID < b.taskID
+	})
+	for _, key := range keys {
+		report.Rows = append(report.Rows, *totals[key])
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// nameTimeReportRows fills the ids and names of the grouped users, projects and tasks
+func nameTimeReportRows(db *gorm.DB, groups map[string]bool, keys []timeReportKey, totals map[timeReportKey]*TimeReportRow) error {
+	var userIDs, projectIDs, taskIDs []uint
+	for _, key := range keys {
+		userIDs = append(userIDs, key.userID)
+		projectIDs = append(projectIDs, key.projectID)
+		taskIDs = append(taskIDs, key.taskID)
+	}
+
+	users := map[uint]models.User{}
+	projects := map[uint]models.Project{}
+	tasks := map[uint]models.Task{}
+	if groups["user"] {
+		var list []models.User
+		// 削除済みのユーザーの記録も名前を表示する
+		if err := db.Unscoped().Select("id", "name").Where("id IN ?", uniqueIDs(userIDs)).Find(&list).Error; err != nil {
+			return err
+		}
+		for _, u := range list {
+			users[u.ID] = u
+		}
+	}
+	if groups["project"] {
+		var list []models.Project
+		if err := db.Unscoped().Select("id", "name").Where("id IN ?", uniqueIDs(projectIDs)).Find(&list).Error; err != nil {
+			return err
+		}
+		for _, p := range list {
+			projects[p.ID] = p
+		}
+	}
+	if groups["task"] {
+		var list []models.Task
+		if err := db.Select("id", "title", "estimated_minutes").Where("id IN ?", uniqueIDs(taskIDs)).Find(&list).Error; err != nil {
+			return err
+		}
+		for _, t := range list {
+			tasks[t.ID] = t
+		}
+	}
+
+	for _, key := range keys {
+		row := totals[key]
+		if groups["user"] {
+			id := key.userID
+			row.UserID, row.UserName = &id, users[id].Name
+		}
+		if groups["project"] && key.projectID != 0 {
+			id := key.projectID
+			row.ProjectID, row.ProjectName = &id, projects[id].Name
+		}
+		if groups["task"] {
+			id := key.taskID
+			row.TaskID, row.TaskTitle, row.EstimatedMinutes = &id, tasks[id].Title, tasks[id].EstimatedMinutes
+		}
+	}
+	return nil
+}
+
+// parseReportTime parses an RFC3339 time or a date, taking the date in loc
+func parseReportTime(v string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", v, loc)
+}
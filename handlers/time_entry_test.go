@@ -0,0 +1,215 @@
+package handlers
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strconv"
+    "testing"
+    "time"
+
+    "flux/database"
+    "flux/models"
+    "github.com/gin-gonic/gin"
+)
+
+func timeEntryRequest(h gin.HandlerFunc, userID uint, params map[string]uint, body interface{}) *httptest.ResponseRecorder {
+    w, c := performJSONRequest(h, http.MethodPost, body)
+    for k, v := range params {
+        c.Params = append(c.Params, gin.Param{Key: k, Value: strconv.Itoa(int(v))})
+    }
+    c.Set("user_id", userID)
+    h(c)
+    return w
+}
+
+func TestTimer_OneRunningPerUser(t *testing.T) {
+    setupTaskDB(t)
+    a := models.Task{Title: "A", UserID: 1}
+    b := models.Task{Title: "B", UserID: 1}
+    database.DB.Create(&a)
+    database.DB.Create(&b)
+
+    w := timeEntryRequest(StartTaskTimer, 1, map[string]uint{"id": a.ID}, nil)
+    if w.Code != http.StatusCreated { t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String()) }
+    var started models.TimeEntry
+    json.Unmarshal(w.Body.Bytes(), &started)
+    if started.EndedAt != nil || started.TaskID != a.ID { t.Fatalf("unexpected timer: %+v", started) }
+
+    // 別のタスクでも2つ目のタイマーは開始できない
+    w = timeEntryRequest(StartTaskTimer, 1, map[string]uint{"id": b.ID}, nil)
+    if w.Code != http.StatusConflict { t.Fatalf("expected 409, got %d", w.Code) }
+    // 他人のタスクではタイマーを使えない
+    if w = timeEntryRequest(StartTaskTimer, 2, map[string]uint{"id": b.ID}, nil); w.Code != http.StatusForbidden { t.Fatalf("expected 403, got %d", w.Code) }
+
+    w = timeEntryRequest(GetRunningTimer, 1, nil, nil)
+    var current struct{ Timer *models.TimeEntry `json:"timer"` }
+    json.Unmarshal(w.Body.Bytes(), &current)
+    if current.Timer == nil || current.Timer.ID != started.ID { t.Fatalf("unexpected running timer: %s", w.Body.String()) }
+
+    if w = timeEntryRequest(StopTaskTimer, 1, map[string]uint{"id": b.ID}, nil); w.Code != http.StatusNotFound { t.Fatalf("expected 404, got %d", w.Code) }
+    // 30分前に開始したことにして止める
+    database.DB.Model(&models.TimeEntry{}).Where("id = ?", started.ID).Update("started_at", time.Now().Add(-30*time.Minute))
+    w = timeEntryRequest(StopTaskTimer, 1, map[string]uint{"id": a.ID}, nil)
+    var stopped models.TimeEntry
+    json.Unmarshal(w.Body.Bytes(), &stopped)
+    if w.Code != http.StatusOK || stopped.EndedAt == nil || stopped.Minutes != 30 { t.Fatalf("unexpected stop: %d %s", w.Code, w.Body.String()) }
+
+    if w = timeEntryRequest(StartTaskTimer, 1, map[string]uint{"id": b.ID}, nil); w.Code != http.StatusCreated { t.Fatalf("expected 201 after stopping, got %d", w.Code) }
+}
+
+func TestTimer_StopAfterTaskDeleted(t *testing.T) {
+    setupTaskDB(t)
+    a := models.Task{Title: "A", UserID: 1}
+    b := models.Task{Title: "B", UserID: 1}
+    database.DB.Create(&a)
+    database.DB.Create(&b)
+
+    if w := timeEntryRequest(StartTaskTimer, 1, map[string]uint{"id": a.ID}, nil); w.Code != http.StatusCreated { t.Fatalf("expected 201, got %d", w.Code) }
+    database.DB.Delete(&a)
+    // ゴミ箱のタスクのタイマーはタスク経由では止められない
+    if w := timeEntryRequest(StopTaskTimer, 1, map[string]uint{"id": a.ID}, nil); w.Code != http.StatusNotFound { t.Fatalf("expected 404, got %d", w.Code) }
+    if w := timeEntryRequest(StartTaskTimer, 1, map[string]uint{"id": b.ID}, nil); w.Code != http.StatusConflict { t.Fatalf("expected 409, got %d", w.Code) }
+
+    // 自分のタイマーはタスクに関係なく止められる
+    if w := timeEntryRequest(StopRunningTimer, 2, nil, nil); w.Code != http.StatusNotFound { t.Fatalf("expected 404 for another user, got %d", w.Code) }
+    w := timeEntryRequest(StopRunningTimer, 1, nil, nil)
+    if w.Code != http.StatusOK { t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String()) }
+    if w = timeEntryRequest(StartTaskTimer, 1, map[string]uint{"id": b.ID}, nil); w.Code != http.StatusCreated { t.Fatalf("expected 201 after stopping, got %d", w.Code) }
+
+    // 破棄すると記録は残らない
+    if w = timeEntryRequest(DiscardRunningTimer, 1, nil, nil); w.Code != http.StatusOK { t.Fatalf("expected 200, got %d", w.Code) }
+    var count int64
+    database.DB.Model(&models.TimeEntry{}).Where("task_id = ?", b.ID).Count(&count)
+    if count != 0 { t.Fatalf("expected the timer to be discarded, got %d entries", count) }
+    if w = timeEntryRequest(DiscardRunningTimer, 1, nil, nil); w.Code != http.StatusNotFound { t.Fatalf("expected 404 without a running timer, got %d", w.Code) }
+}
+
+func TestTimeEntries_ManualAndTotals(t *testing.T) {
+    setupTaskDB(t)
+    estimate := 120
+    task := models.Task{Title: "Design", UserID: 1, EstimatedMinutes: &estimate}
+    database.DB.Create(&task)
+    start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+    end := start.Add(45 * time.Minute)
+    minutes := 30
+    params := map[string]uint{"id": task.ID}
+
+    for _, body := range []map[string]interface{}{
+        {"minutes": 10},
+        {"started_at": start},
+        {"started_at": start, "ended_at": end, "minutes": minutes},
+        {"started_at": start, "ended_at": start.Add(-time.Minute)},
+        {"started_at": start, "minutes": -5},
+        {"started_at": start, "minutes": timeEntryMaxMinutes + 1},
+    } {
+        if w := timeEntryRequest(CreateTaskTimeEntry, 1, params, body); w.Code != http.StatusBadRequest { t.Fatalf("expected 400 for %v, got %d", body, w.Code) }
+    }
+
+    w := timeEntryRequest(CreateTaskTimeEntry, 1, params, TimeEntryRequest{StartedAt: &start, EndedAt: &end, Note: " review "})
+    var first models.TimeEntry
+    json.Unmarshal(w.Body.Bytes(), &first)
+    if w.Code != http.StatusCreated || first.Minutes != 45 || first.Note != "review" { t.Fatalf("unexpected entry: %d %s", w.Code, w.Body.String()) }
+    w = timeEntryRequest(CreateTaskTimeEntry, 1, params, TimeEntryRequest{StartedAt: &start, Minutes: &minutes})
+    var second models.TimeEntry
+    json.Unmarshal(w.Body.Bytes(), &second)
+    if w.Code != http.StatusCreated || second.EndedAt == nil || !second.EndedAt.Equal(start.Add(30*time.Minute)) { t.Fatalf("unexpected entry: %d %s", w.Code, w.Body.String()) }
+
+    // 記録した本人以外は編集できない
+    minutes = 15
+    if w = timeEntryRequest(UpdateTaskTimeEntry, 2, map[string]uint{"id": task.ID, "entry_id": second.ID}, TimeEntryRequest{StartedAt: &start, Minutes: &minutes}); w.Code != http.StatusForbidden { t.Fatalf("expected 403, got %d", w.Code) }
+    if w = timeEntryRequest(UpdateTaskTimeEntry, 1, map[string]uint{"id": task.ID, "entry_id": second.ID}, TimeEntryRequest{StartedAt: &start, Minutes: &minutes}); w.Code != http.StatusOK { t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String()) }
+
+    // 計測中のタイマーは合計に含めない
+    timeEntryRequest(StartTaskTimer, 1, params, nil)
+    w, c := performJSONRequest(GetTask, http.MethodGet, nil)
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(task.ID))}}
+    GetTask(c)
+    var got models.Task
+    json.Unmarshal(w.Body.Bytes(), &got)
+    if got.LoggedMinutes != 60 || got.EstimatedMinutes == nil || *got.EstimatedMinutes != 120 { t.Fatalf("unexpected totals: %s", w.Body.String()) }
+
+    w, c = performJSONRequest(GetTaskTimeEntries, http.MethodGet, nil)
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(task.ID))}}
+    c.Set("user_id", uint(1))
+    GetTaskTimeEntries(c)
+    var list struct{ Total int64 `json:"total"` }
+    json.Unmarshal(w.Body.Bytes(), &list)
+    if list.Total != 3 { t.Fatalf("expected 3 entries, got %s", w.Body.String()) }
+
+    w = timeEntryRequest(DeleteTaskTimeEntry, 1, map[string]uint{"id": task.ID, "entry_id": first.ID}, nil)
+    if w.Code != http.StatusOK { t.Fatalf("expected 200, got %d", w.Code) }
+}
+
+func TestEstimatedMinutes_Validation(t *testing.T) {
+    setupTaskDB(t)
+    task := models.Task{Title: "T", UserID: 1}
+    database.DB.Create(&task)
+
+    if code := patchTask(task.ID, 1, map[string]interface{}{"estimated_minutes": -1}); code != http.StatusBadRequest { t.Fatalf("expected 400, got %d", code) }
+    if code := patchTask(task.ID, 1, map[string]interface{}{"estimated_minutes": 90}); code != http.StatusOK { t.Fatalf("expected 200, got %d", code) }
+    var saved models.Task
+    database.DB.First(&saved, task.ID)
+    if saved.EstimatedMinutes == nil || *saved.EstimatedMinutes != 90 { t.Fatalf("unexpected estimate: %v", saved.EstimatedMinutes) }
+
+    if code := patchTask(task.ID, 1, map[string]interface{}{"estimated_minutes": nil}); code != http.StatusOK { t.Fatalf("expected 200, got %d", code) }
+    var cleared models.Task
+    database.DB.First(&cleared, task.ID)
+    if cleared.EstimatedMinutes != nil { t.Fatalf("expected the estimate to be cleared, got %d", *cleared.EstimatedMinutes) }
+}
+
+func TestTimeReport(t *testing.T) {
+    setupTaskDB(t)
+    alice := models.User{Name: "Alice", Email: "alice@example.com", Password: "Password1!"}
+    bob := models.User{Name: "Bob", Email: "bob@example.com", Password: "Password1!"}
+    database.DB.Create(&alice)
+    database.DB.Create(&bob)
+    project := models.Project{Name: "Acme", OwnerID: alice.ID}
+    database.DB.Create(&project)
+    billable := models.Task{Title: "Billable", UserID: alice.ID, ProjectID: &project.ID}
+    internal := models.Task{Title: "Internal", UserID: alice.ID}
+    foreign := models.Task{Title: "Bob's", UserID: bob.ID}
+    database.DB.Create(&billable)
+    database.DB.Create(&internal)
+    database.DB.Create(&foreign)
+
+    day := time.Date(2026, 3, 2, 23, 30, 0, 0, time.UTC)
+    logEntry := func(userID, taskID uint, at time.Time, minutes int) {
+        e := models.TimeEntry{TaskID: taskID, UserID: userID, StartedAt: at}
+        e.Stop(at.Add(time.Duration(minutes) * time.Minute))
+        if err := database.DB.Create(&e).Error; err != nil { t.Fatal(err) }
+    }
+    logEntry(alice.ID, billable.ID, day, 60)
+    logEntry(bob.ID, billable.ID, day, 30)
+    logEntry(alice.ID, internal.ID, day.AddDate(0, 0, 1), 15)
+    logEntry(bob.ID, foreign.ID, day, 45)
+    // 計測中のタイマーは集計しない
+    database.DB.Create(&models.TimeEntry{TaskID: billable.ID, UserID: alice.ID, StartedAt: day})
+
+    report := func(query string) (int, TimeReport) {
+        w, c := performJSONRequest(GetTimeReport, http.MethodGet, nil)
+        c.Request.URL.RawQuery = query
+        c.Set("user_id", alice.ID)
+        GetTimeReport(c)
+        var r TimeReport
+        json.Unmarshal(w.Body.Bytes(), &r)
+        return w.Code, r
+    }
+
+    // Bob の個人タスクの記録は Alice には見えない
+    code, r := report("group_by=user,project")
+    if code != http.StatusOK || r.TotalMinutes != 105 || len(r.Rows) != 3 { t.Fatalf("unexpected report: %d %+v", code, r) }
+    if row := r.Rows[0]; *row.UserID != alice.ID || row.ProjectID != nil || row.Minutes != 15 { t.Fatalf("unexpected row: %+v", row) }
+    if row := r.Rows[1]; *row.UserID != alice.ID || row.ProjectID == nil || row.ProjectName != "Acme" || row.Minutes != 60 { t.Fatalf("unexpected row: %+v", row) }
+    if row := r.Rows[2]; row.UserName != "Bob" || row.Minutes != 30 { t.Fatalf("unexpected row: %+v", row) }
+
+    // 日付は tz で区切る（UTC 23:30 は東京では翌日）
+    _, r = report("group_by=date&tz=Asia/Tokyo")
+    if len(r.Rows) != 2 || r.Rows[0].Date != "2026-03-03" || r.Rows[0].Minutes != 90 || r.Rows[1].Date != "2026-03-04" { t.Fatalf("unexpected dates: %+v", r.Rows) }
+
+    _, r = report("group_by=task&from=2026-03-02&to=2026-03-02&project_id=" + strconv.Itoa(int(project.ID)))
+    if r.TotalMinutes != 90 || len(r.Rows) != 1 || r.Rows[0].TaskTitle != "Billable" || r.Rows[0].Entries != 2 { t.Fatalf("unexpected filtered report: %+v", r) }
+
+    if code, _ = report("group_by=month"); code != http.StatusBadRequest { t.Fatalf("expected 400, got %d", code) }
+    if code, _ = report("from=yesterday"); code != http.StatusBadRequest { t.Fatalf("expected 400, got %d", code) }
+}
@@ -0,0 +1,435 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"flux/database"
+	"flux/middleware"
+	"flux/models"
+	"flux/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// trashRetention ゴミ箱に残す期間（TRASH_RETENTION_DAYS、既定30日）
+func trashRetention() time.Duration {
+	return time.Duration(utils.GetEnvInt("TRASH_RETENTION_DAYS", 30)) * 24 * time.Hour
+}
+
+// GetTrashedTasks retrieves the deleted tasks the caller may restore or purge
+func GetTrashedTasks(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "認証が必要です"})
+		return
+	}
+
+	// canAccessTask の削除権限と同じ条件
+	query := database.DB.Unscoped().Model(&models.Task{}).Where("deleted_at IS NOT NULL").Where(
+		"(organization_id IS NULL AND user_id = ?) OR organization_id IN (SELECT organization_id FROM organization_members WHERE user_id = ? AND role IN ?) OR (creator_id = ? AND organization_id IN (SELECT organization_id FROM organization_members WHERE user_id = ? AND role = ?))",
+		userID, userID, []string{models.RoleOwner, models.RoleAdmin}, userID, userID, models.RoleMember)
+	if q := c.Query("q"); q != "" {
+		query = query.Where("LOWER(title) LIKE LOWER(?)", "%"+q+"%")
+	}
+	var tasks []models.Task
+	respondList(c, query, taskListSpec, &tasks)
+}
+
+// RestoreTask brings a deleted task back. Links to a parent or project that no
+// longer exists are dropped.
+func RestoreTask(c *gin.Context) {
+	task, ok := findTrashedTask(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := middleware.GetUserID(c)
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{"deleted_at": nil, "version": gorm.Expr("version + 1")}
+		if task.ParentID != nil {
+			var count int64
+			tx.Model(&models.Task{}).Where("id = ?", *task.ParentID).Count(&count)
+			if count == 0 {
+				updates["parent_id"] = nil
+			}
+		}
+		if task.ProjectID != nil {
+			var count int64
+			tx.Model(&models.Project{}).Where("id = ?", *task.ProjectID).Count(&count)
+			if count == 0 {
+				updates["project_id"] = nil
+			}
+		}
+		if err := tx.Unscoped().Model(&task).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := bumpRelatedVersions(tx, nil, &task); err != nil {
+			return err
+		}
+		return recordTaskEvent(tx, task.ID, userID, models.EventRestored, nil)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var restored models.Task
+	if err := database.DB.Preload("User").Preload("Labels").Preload("Assignees").First(&restored, task.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, restored)
+}
+
+// PurgeTask permanently deletes a task that is in the trash
+func PurgeTask(c *gin.Context) {
+	task, ok := findTrashedTask(c)
+	if !ok {
+		return
+	}
+
+	var attachments []models.Attachment
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		attachments, err = purgeTasks(tx, []uint{task.ID})
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	removeAttachmentFiles(attachments)
+	c.JSON(http.StatusOK, gin.H{"message": "Task purged successfully"})
+}
+
+// GetTrashedUsers retrieves the caller's own account if it is in the trash
+func GetTrashedUsers(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "認証が必要です"})
+		return
+	}
+	var users []models.User
+	respondList(c, database.DB.Unscoped().Model(&models.User{}).Where("deleted_at IS NOT NULL AND id = ?", userID), userListSpec, &users)
+}
+
+// RestoreUser brings the caller's own deleted account back
+func RestoreUser(c *gin.Context) {
+	id, ok := requireSelf(c)
+	if !ok {
+		return
+	}
+	var user models.User
+	if err := database.DB.Unscoped().Where("deleted_at IS NOT NULL").First(&user, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found in trash"})
+		return
+	}
+	if err := database.DB.Unscoped().Model(&user).Update("deleted_at", nil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	user.DeletedAt = gorm.DeletedAt{}
+	c.JSON(http.StatusOK, user)
+}
+
+// PurgeUser permanently deletes the caller's own account from the trash, with their personal data
+func PurgeUser(c *gin.Context) {
+	id, ok := requireSelf(c)
+	if !ok {
+		return
+	}
+	var user models.User
+	if err := database.DB.Unscoped().Where("deleted_at IS NOT NULL").First(&user, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found in trash"})
+		return
+	}
+
+	var attachments []models.Attachment
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		attachments, err = purgeUsers(tx, []uint{user.ID})
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	removeAttachmentFiles(attachments)
+	c.JSON(http.StatusOK, gin.H{"message": "User purged successfully"})
+}
+
+// findTrashedTask loads the deleted task in the :id path parameter and checks that the
+// caller could have deleted it. On failure the error response has already been written.
+func findTrashedTask(c *gin.Context) (models.Task, bool) {
+	var task models.Task
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "認証が必要です"})
+		return task, false
+	}
+	err := database.DB.Unscoped().Where("deleted_at IS NOT NULL").First(&task, c.Param("id")).Error
+	if err != nil || !canAccessTask(database.DB, &task, userID, taskActionDelete) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found in trash"})
+		return task, false
+	}
+	return task, true
+}
+
+// purgeTasks permanently deletes tasks and the rows that belong to them. Subtasks
+// are detached rather than deleted, and recurring series keep going from their
+// earliest remaining occurrence. The attachments are returned so their files can
+// be removed once the transaction has committed.
+func purgeTasks(tx *gorm.DB, ids []uint) ([]models.Attachment, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	if err := reassignSeries(tx, ids); err != nil {
+		return nil, err
+	}
+	attachments, err := deleteTaskAttachments(tx, ids)
+	if err != nil {
+		return nil, err
+	}
+	steps := []func() *gorm.DB{
+		func() *gorm.DB { return tx.Exec("DELETE FROM task_labels WHERE task_id IN ?", ids) },
+		func() *gorm.DB { return tx.Exec("DELETE FROM task_assignees WHERE task_id IN ?", ids) },
+		func() *gorm.DB {
+			return tx.Where("task_id IN ? OR blocked_by_id IN ?", ids, ids).Delete(&models.TaskDependency{})
+		},
+		func() *gorm.DB { return tx.Unscoped().Where("task_id IN ?", ids).Delete(&models.Comment{}) },
+		func() *gorm.DB { return tx.Where("task_id IN ?", ids).Delete(&models.Mention{}) },
+		func() *gorm.DB { return tx.Where("task_id IN ?", ids).Delete(&models.Notification{}) },
+		func() *gorm.DB { return tx.Where("task_id IN ?", ids).Delete(&models.TaskEvent{}) },
+		func() *gorm.DB { return tx.Where("task_id IN ?", ids).Delete(&models.TimeEntry{}) },
+		func() *gorm.DB { return tx.Where("task_id IN ?", ids).Delete(&models.TaskFieldValue{}) },
+		func() *gorm.DB {
+			return tx.Unscoped().Model(&models.Task{}).Where("parent_id IN ?", ids).Update("parent_id", nil)
+		},
+		func() *gorm.DB { return tx.Unscoped().Delete(&models.Task{}, ids) },
+	}
+	for _, step := range steps {
+		if err := step().Error; err != nil {
+			return nil, err
+		}
+	}
+	return attachments, nil
+}
+
+// purgeUsers permanently deletes users with their personal tasks, labels, projects and their
+// custom fields, memberships, notifications and calendar feeds. The comments, attachments,
+// time entries and mentions they left on other tasks are deleted too. Organization tasks
+// they created are kept and handed over to an owner of the organization.
+func purgeUsers(tx *gorm.DB, ids []uint) ([]models.Attachment, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var taskIDs []uint
+	if err := tx.Unscoped().Model(&models.Task{}).Where("user_id IN ? AND organization_id IS NULL", ids).Pluck("id", &taskIDs).Error; err != nil {
+		return nil, err
+	}
+	attachments, err := purgeTasks(tx, taskIDs)
+	if err != nil {
+		return nil, err
+	}
+	orphaned, err := handOverOrganizations(tx, ids)
+	if err != nil {
+		return nil, err
+	}
+	attachments = append(attachments, orphaned...)
+
+	// 他人のタスクに残したアップロード
+	var uploads []models.Attachment
+	if err := tx.Where("user_id IN ?", ids).Find(&uploads).Error; err != nil {
+		return nil, err
+	}
+	attachments = append(attachments, uploads...)
+
+	projects := tx.Unscoped().Model(&models.Project{}).Select("id").Where("owner_id IN ?", ids)
+	fields := tx.Model(&models.CustomField{}).Select("id").Where("project_id IN (?)", projects)
+	labels := tx.Model(&models.Label{}).Select("id").Where("organization_id IS NULL AND user_id IN ?", ids)
+	comments := tx.Unscoped().Model(&models.Comment{}).Select("id").Where("user_id IN ?", ids)
+	steps := []func() *gorm.DB{
+		func() *gorm.DB {
+			return tx.Where("user_id IN ? OR author_id IN ? OR comment_id IN (?)", ids, ids, comments).Delete(&models.Mention{})
+		},
+		func() *gorm.DB {
+			return tx.Where("actor_id IN ? OR comment_id IN (?)", ids, comments).Delete(&models.Notification{})
+		},
+		func() *gorm.DB { return tx.Unscoped().Where("user_id IN ?", ids).Delete(&models.Comment{}) },
+		func() *gorm.DB { return tx.Where("user_id IN ?", ids).Delete(&models.Attachment{}) },
+		func() *gorm.DB { return tx.Where("user_id IN ?", ids).Delete(&models.TimeEntry{}) },
+		func() *gorm.DB {
+			return tx.Model(&models.TaskEvent{}).Where("actor_id IN ?", ids).Update("actor_id", nil)
+		},
+		func() *gorm.DB {
+			return tx.Where("field_id IN (?) OR user_value IN ?", fields, ids).Delete(&models.TaskFieldValue{})
+		},
+		func() *gorm.DB { return tx.Where("project_id IN (?)", projects).Delete(&models.CustomField{}) },
+		func() *gorm.DB {
+			return tx.Unscoped().Model(&models.Task{}).Where("project_id IN (?)", projects).Update("project_id", nil)
+		},
+		func() *gorm.DB { return tx.Unscoped().Where("owner_id IN ?", ids).Delete(&models.Project{}) },
+		func() *gorm.DB { return tx.Exec("DELETE FROM task_labels WHERE label_id IN (?)", labels) },
+		func() *gorm.DB { return tx.Where("organization_id IS NULL AND user_id IN ?", ids).Delete(&models.Label{}) },
+		func() *gorm.DB { return tx.Exec("DELETE FROM task_assignees WHERE user_id IN ?", ids) },
+		func() *gorm.DB { return tx.Where("user_id IN ?", ids).Delete(&models.OrganizationMember{}) },
+		func() *gorm.DB { return tx.Where("user_id IN ?", ids).Delete(&models.Notification{}) },
+		func() *gorm.DB { return tx.Where("user_id IN ?", ids).Delete(&models.PasswordReset{}) },
+		func() *gorm.DB { return tx.Where("user_id IN ?", ids).Delete(&models.CalendarFeed{}) },
+		func() *gorm.DB { return tx.Unscoped().Delete(&models.User{}, ids) },
+	}
+	for _, step := range steps {
+		if err := step().Error; err != nil {
+			return nil, err
+		}
+	}
+	return attachments, nil
+}
+
+// reassignSeries points the remaining occurrences of series whose first task is about to
+// be purged at their earliest remaining occurrence, which becomes the series' first task
+func reassignSeries(tx *gorm.DB, ids []uint) error {
+	var rest []models.Task
+	err := tx.Unscoped().Select("id", "series_id").Where("series_id IN ? AND id NOT IN ?", ids, ids).
+		Order("occurrence_index, id").Find(&rest).Error
+	if err != nil {
+		return err
+	}
+	heads := map[uint]bool{}
+	for _, t := range rest {
+		if heads[*t.SeriesID] {
+			continue
+		}
+		heads[*t.SeriesID] = true
+		err := tx.Unscoped().Model(&models.Task{}).Where("series_id = ? AND id NOT IN ?", *t.SeriesID, ids).
+			UpdateColumn("series_id", t.ID).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handOverOrganizations keeps the organizations of users about to be purged usable. When
+// no owner would remain, the longest-standing remaining member becomes the owner (admins
+// first), and the organization tasks and labels the users owned or created are handed over
+// to that owner. Organizations left without members are deleted with their tasks and labels.
+func handOverOrganizations(tx *gorm.DB, ids []uint) ([]models.Attachment, error) {
+	var orgIDs []uint
+	if err := tx.Model(&models.OrganizationMember{}).Where("user_id IN ?", ids).Distinct().Pluck("organization_id", &orgIDs).Error; err != nil {
+		return nil, err
+	}
+	var attachments []models.Attachment
+	for _, orgID := range orgIDs {
+		var remaining []models.OrganizationMember
+		if err := tx.Where("organization_id = ? AND user_id NOT IN ?", orgID, ids).Order("id").Find(&remaining).Error; err != nil {
+			return nil, err
+		}
+		if len(remaining) == 0 {
+			var taskIDs []uint
+			if err := tx.Unscoped().Model(&models.Task{}).Where("organization_id = ?", orgID).Pluck("id", &taskIDs).Error; err != nil {
+				return nil, err
+			}
+			purged, err := purgeTasks(tx, taskIDs)
+			if err != nil {
+				return nil, err
+			}
+			attachments = append(attachments, purged...)
+			if err := deleteOrganizationLabels(tx, orgID); err != nil {
+				return nil, err
+			}
+			if err := tx.Unscoped().Delete(&models.Organization{}, orgID).Error; err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		owner := successor(remaining)
+		if owner.Role != models.RoleOwner {
+			if err := tx.Model(&owner).Update("role", models.RoleOwner).Error; err != nil {
+				return nil, err
+			}
+		}
+		for _, column := range []string{"user_id", "creator_id"} {
+			err := tx.Unscoped().Model(&models.Task{}).Where("organization_id = ? AND "+column+" IN ?", orgID, ids).
+				Update(column, owner.UserID).Error
+			if err != nil {
+				return nil, err
+			}
+		}
+		err := tx.Model(&models.Label{}).Where("organization_id = ? AND user_id IN ?", orgID, ids).Update("user_id", owner.UserID).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	return attachments, nil
+}
+
+// successor returns an existing owner, or else the first member with the highest role
+func successor(members []models.OrganizationMember) models.OrganizationMember {
+	for _, role := range []string{models.RoleOwner, models.RoleAdmin, models.RoleMember} {
+		for _, m := range members {
+			if m.Role == role {
+				return m
+			}
+		}
+	}
+	return members[0]
+}
+
+// PurgeExpiredTrash permanently deletes tasks and users that were deleted before cutoff
+func PurgeExpiredTrash(db *gorm.DB, cutoff time.Time) (tasks, users int, err error) {
+	var taskIDs, userIDs []uint
+	if err := db.Unscoped().Model(&models.Task{}).Where("deleted_at < ?", cutoff).Pluck("id", &taskIDs).Error; err != nil {
+		return 0, 0, err
+	}
+	if err := db.Unscoped().Model(&models.User{}).Where("deleted_at < ?", cutoff).Pluck("id", &userIDs).Error; err != nil {
+		return 0, 0, err
+	}
+
+	var attachments []models.Attachment
+	err = db.Transaction(func(tx *gorm.DB) error {
+		purged, err := purgeTasks(tx, taskIDs)
+		if err != nil {
+			return err
+		}
+		attachments = append(attachments, purged...)
+		if purged, err = purgeUsers(tx, userIDs); err != nil {
+			return err
+		}
+		attachments = append(attachments, purged...)
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	removeAttachmentFiles(attachments)
+	return len(taskIDs), len(userIDs), nil
+}
+
+// StartTrashPurger purges expired trash now and then every TRASH_PURGE_INTERVAL_MINUTES
+// (default 60) until ctx is cancelled
+func StartTrashPurger(ctx context.Context) {
+	interval := time.Duration(utils.GetEnvInt("TRASH_PURGE_INTERVAL_MINUTES", 60)) * time.Minute
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			tasks, users, err := PurgeExpiredTrash(database.DB, time.Now().Add(-trashRetention()))
+			if err != nil {
+				log.Printf("failed to purge trash: %v", err)
+			} else if tasks > 0 || users > 0 {
+				log.Printf("purged %d tasks and %d users from trash", tasks, users)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
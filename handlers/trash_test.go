@@ -0,0 +1,209 @@
+package handlers
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strconv"
+    "testing"
+    "time"
+
+    "flux/database"
+    "flux/models"
+    "github.com/gin-gonic/gin"
+)
+
+func deleteTaskAs(t *testing.T, userID, taskID uint) {
+    t.Helper()
+    w, c := performJSONRequest(DeleteTask, http.MethodDelete, nil)
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(taskID))}}
+    c.Set("user_id", userID)
+    DeleteTask(c)
+    if w.Code != http.StatusOK { t.Fatalf("expected 200, got %d", w.Code) }
+}
+
+func TestTrash_ListRestoreAndPurge(t *testing.T) {
+    setupTaskDB(t)
+    _, parent := createSubtask(t, 1, "Parent", nil)
+    _, child := createSubtask(t, 1, "Child", &parent.ID)
+    _, other := createSubtask(t, 2, "Other", nil)
+    postComment(t, 1, child.ID, "note")
+
+    deleteTaskAs(t, 1, parent.ID)
+    deleteTaskAs(t, 1, child.ID)
+    deleteTaskAs(t, 2, other.ID)
+
+    w, c := performJSONRequest(GetTrashedTasks, http.MethodGet, nil)
+    c.Set("user_id", uint(1))
+    GetTrashedTasks(c)
+    var res struct {
+        Data []models.Task `json:"data"`
+    }
+    if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil { t.Fatal(err) }
+    if len(res.Data) != 2 { t.Fatalf("expected only the caller's 2 trashed tasks, got %+v", res.Data) }
+
+    // 他人のタスクは復元できない
+    w, c = performJSONRequest(RestoreTask, http.MethodPost, nil)
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(other.ID))}}
+    c.Set("user_id", uint(1))
+    RestoreTask(c)
+    if w.Code != http.StatusNotFound { t.Fatalf("expected 404, got %d", w.Code) }
+
+    // 親がゴミ箱にある間は親から外して復元する
+    w, c = performJSONRequest(RestoreTask, http.MethodPost, nil)
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(child.ID))}}
+    c.Set("user_id", uint(1))
+    RestoreTask(c)
+    if w.Code != http.StatusOK { t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String()) }
+    var restored models.Task
+    if err := json.Unmarshal(w.Body.Bytes(), &restored); err != nil { t.Fatal(err) }
+    if restored.ParentID != nil { t.Fatalf("expected restored task to be detached, got parent %v", *restored.ParentID) }
+
+    w, c = performJSONRequest(PurgeTask, http.MethodDelete, nil)
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(child.ID))}}
+    c.Set("user_id", uint(1))
+    PurgeTask(c)
+    if w.Code != http.StatusNotFound { t.Fatalf("expected 404 for a task not in trash, got %d", w.Code) }
+
+    w, c = performJSONRequest(PurgeTask, http.MethodDelete, nil)
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(parent.ID))}}
+    c.Set("user_id", uint(1))
+    PurgeTask(c)
+    if w.Code != http.StatusOK { t.Fatalf("expected 200, got %d", w.Code) }
+    var count int64
+    database.DB.Unscoped().Model(&models.Task{}).Where("id = ?", parent.ID).Count(&count)
+    if count != 0 { t.Fatal("expected task to be permanently deleted") }
+}
+
+func TestTrash_PurgeKeepsSeriesLinked(t *testing.T) {
+    setupTaskDB(t)
+    due := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+    first := models.Task{Title: "Standup", UserID: 1, DueAt: &due, Recurrence: "FREQ=DAILY"}
+    database.DB.Create(&first)
+    if code, _ := updateTaskAs(t, 1, first.ID, map[string]string{"status": "completed"}, ""); code != http.StatusOK { t.Fatalf("expected 200, got %d", code) }
+    var second models.Task
+    database.DB.Where("occurrence_index = ?", 2).First(&second)
+    if code, _ := updateTaskAs(t, 1, second.ID, map[string]string{"status": "completed"}, ""); code != http.StatusOK { t.Fatalf("expected 200, got %d", code) }
+
+    // シリーズの最初のタスクを完全削除すると、残った最初の発生がシリーズの起点になる
+    deleteTaskAs(t, 1, first.ID)
+    w, c := performJSONRequest(PurgeTask, http.MethodDelete, nil)
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(first.ID))}}
+    c.Set("user_id", uint(1))
+    PurgeTask(c)
+    if w.Code != http.StatusOK { t.Fatalf("expected 200, got %d", w.Code) }
+
+    var rest []models.Task
+    database.DB.Order("occurrence_index").Find(&rest)
+    if len(rest) != 2 { t.Fatalf("expected 2 remaining occurrences, got %d", len(rest)) }
+    for _, task := range rest {
+        if task.SeriesID == nil || *task.SeriesID != second.ID { t.Fatalf("expected occurrence %d to belong to series %d, got %v", task.OccurrenceIndex, second.ID, task.SeriesID) }
+    }
+}
+
+func TestTrash_PurgeExpired(t *testing.T) {
+    setupTaskDB(t)
+    database.DB.Create(&models.User{Name: "gone", Email: "gone@example.com"})
+    database.DB.Create(&models.User{Name: "recent", Email: "recent@example.com"})
+    old := models.Task{Title: "Old", UserID: 1}
+    recent := models.Task{Title: "Recent", UserID: 2}
+    database.DB.Create(&old)
+    database.DB.Create(&recent)
+    database.DB.Create(&models.Label{Name: "mine", UserID: 1})
+
+    database.DB.Delete(&models.User{}, 1)
+    database.DB.Delete(&models.User{}, 2)
+    database.DB.Delete(&recent)
+    longAgo := time.Now().AddDate(0, 0, -40)
+    database.DB.Unscoped().Model(&models.User{}).Where("id = ?", 1).Update("deleted_at", longAgo)
+
+    tasks, users, err := PurgeExpiredTrash(database.DB, time.Now().AddDate(0, 0, -30))
+    if err != nil { t.Fatal(err) }
+    if tasks != 0 || users != 1 { t.Fatalf("expected 0 tasks and 1 user purged, got %d and %d", tasks, users) }
+
+    // 完全削除したユーザーの個人タスクとラベルも消える
+    var count int64
+    database.DB.Unscoped().Model(&models.Task{}).Where("id = ?", old.ID).Count(&count)
+    if count != 0 { t.Fatal("expected the purged user's tasks to be deleted") }
+    database.DB.Model(&models.Label{}).Count(&count)
+    if count != 0 { t.Fatal("expected the purged user's labels to be deleted") }
+    database.DB.Unscoped().Model(&models.Task{}).Where("id = ?", recent.ID).Count(&count)
+    if count != 1 { t.Fatal("expected recently deleted task to be kept") }
+
+    // 復元できるのは本人のアカウントのみ
+    if w := userTrashRequest(RestoreUser, 3, 2); w.Code != http.StatusForbidden { t.Fatalf("expected 403, got %d", w.Code) }
+    if w := userTrashRequest(RestoreUser, 2, 2); w.Code != http.StatusOK { t.Fatalf("expected 200, got %d", w.Code) }
+    var user models.User
+    if err := database.DB.First(&user, 2).Error; err != nil { t.Fatalf("expected user to be restored: %v", err) }
+}
+
+func userTrashRequest(h gin.HandlerFunc, callerID, userID uint) *httptest.ResponseRecorder {
+    w, c := performJSONRequest(h, http.MethodPost, nil)
+    c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(userID))}}
+    c.Set("user_id", callerID)
+    h(c)
+    return w
+}
+
+func TestTrash_PurgeUserCleansUpSharedData(t *testing.T) {
+    setupTaskDB(t)
+    gone := models.User{Name: "Gone", Email: "gone@example.com", Password: "Password1!"}
+    admin := models.User{Name: "Admin", Email: "admin@example.com", Password: "Password1!"}
+    other := models.User{Name: "Other", Email: "other@example.com", Password: "Password1!"}
+    database.DB.Create(&gone)
+    database.DB.Create(&admin)
+    database.DB.Create(&other)
+
+    team := models.Organization{Name: "Team"}
+    solo := models.Organization{Name: "Solo"}
+    database.DB.Create(&team)
+    database.DB.Create(&solo)
+    database.DB.Create(&models.OrganizationMember{OrganizationID: team.ID, UserID: gone.ID, Role: models.RoleOwner})
+    database.DB.Create(&models.OrganizationMember{OrganizationID: team.ID, UserID: other.ID, Role: models.RoleMember})
+    database.DB.Create(&models.OrganizationMember{OrganizationID: team.ID, UserID: admin.ID, Role: models.RoleAdmin})
+    database.DB.Create(&models.OrganizationMember{OrganizationID: solo.ID, UserID: gone.ID, Role: models.RoleOwner})
+    teamTask := models.Task{Title: "Team task", UserID: gone.ID, OrganizationID: &team.ID}
+    soloTask := models.Task{Title: "Solo task", UserID: gone.ID, OrganizationID: &solo.ID}
+    othersTask := models.Task{Title: "Other's task", UserID: other.ID}
+    database.DB.Create(&teamTask)
+    database.DB.Create(&soloTask)
+    database.DB.Create(&othersTask)
+
+    // 他人のタスクに残したデータ
+    comment := models.Comment{TaskID: othersTask.ID, UserID: gone.ID, Body: "hi @Other"}
+    database.DB.Create(&comment)
+    database.DB.Create(&models.Mention{TaskID: othersTask.ID, CommentID: &comment.ID, UserID: other.ID, AuthorID: gone.ID})
+    database.DB.Create(&models.Notification{UserID: other.ID, Type: models.NotificationMention, ActorID: gone.ID, TaskID: othersTask.ID, CommentID: &comment.ID})
+    database.DB.Create(&models.Attachment{TaskID: othersTask.ID, UserID: gone.ID, FileName: "a.txt", ContentType: "text/plain", StorageKey: "k1"})
+    entry := models.TimeEntry{TaskID: othersTask.ID, UserID: gone.ID, StartedAt: time.Now().Add(-time.Hour)}
+    entry.Stop(time.Now())
+    database.DB.Create(&entry)
+    database.DB.Create(&models.TaskEvent{TaskID: othersTask.ID, ActorID: &gone.ID, Type: models.EventCommented})
+
+    database.DB.Delete(&gone)
+    if w := userTrashRequest(PurgeUser, other.ID, gone.ID); w.Code != http.StatusForbidden { t.Fatalf("expected 403, got %d", w.Code) }
+    if w := userTrashRequest(PurgeUser, gone.ID, gone.ID); w.Code != http.StatusOK { t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String()) }
+
+    for _, m := range []interface{}{&models.Comment{}, &models.Mention{}, &models.Notification{}, &models.Attachment{}, &models.TimeEntry{}} {
+        var count int64
+        database.DB.Unscoped().Model(m).Count(&count)
+        if count != 0 { t.Fatalf("expected no %T rows left, got %d", m, count) }
+    }
+    var event models.TaskEvent
+    database.DB.Where("task_id = ?", othersTask.ID).First(&event)
+    if event.ActorID != nil { t.Fatalf("expected the actor to be cleared, got %d", *event.ActorID) }
+
+    // 管理者が新しいオーナーになり、組織のタスクを引き継ぐ
+    var owner models.OrganizationMember
+    database.DB.Where("organization_id = ? AND role = ?", team.ID, models.RoleOwner).First(&owner)
+    if owner.UserID != admin.ID { t.Fatalf("expected the admin to become owner, got user %d", owner.UserID) }
+    database.DB.First(&teamTask, teamTask.ID)
+    if teamTask.UserID != admin.ID || teamTask.CreatorID != admin.ID { t.Fatalf("unexpected task owner: %+v", teamTask) }
+
+    // メンバーがいなくなった組織はタスクごと削除する
+    var count int64
+    database.DB.Unscoped().Model(&models.Organization{}).Where("id = ?", solo.ID).Count(&count)
+    if count != 0 { t.Fatal("expected the empty organization to be deleted") }
+    database.DB.Unscoped().Model(&models.Task{}).Where("id = ?", soloTask.ID).Count(&count)
+    if count != 0 { t.Fatal("expected the empty organization's tasks to be deleted") }
+}
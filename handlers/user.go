@@ -2,28 +2,34 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 	"flux/database"
+	"flux/middleware"
 	"flux/models"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
 )
 
-// GetUsers retrieves all users
+// GetUsers retrieves users with sorting and pagination
 func GetUsers(c *gin.Context) {
-	var users []models.User
-	result := database.DB.Find(&users)
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
-		return
+	query := database.DB.Model(&models.User{})
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		like := "%" + strings.ToLower(q) + "%"
+		query = query.Where("LOWER(name) LIKE ? OR LOWER(email) LIKE ?", like, like)
 	}
-	c.JSON(http.StatusOK, users)
+	var users []models.User
+	respondList(c, query, userListSpec, &users)
 }
 
 // GetUser retrieves a single user by ID
 func GetUser(c *gin.Context) {
 	id := c.Param("id")
 	var user models.User
-	result := database.DB.Preload("Tasks").First(&user, id)
+	// 組織のタスクはその組織のメンバーにだけ含める
+	result := database.DB.Preload("Tasks", func(db *gorm.DB) *gorm.DB { return visibleTasks(c, db) }).First(&user, id)
 	if result.Error != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
@@ -48,29 +54,113 @@ func CreateUser(c *gin.Context) {
 	c.JSON(http.StatusCreated, user)
 }
 
-// UpdateUser updates an existing user
+// UserRequest ユーザー更新リクエスト（PUT は全項目必須）
+type UserRequest struct {
+	Name  string `json:"name" binding:"required,max=100"`
+	Email string `json:"email" binding:"required,email,max=100"`
+}
+
+// userPatchFields PATCH で変更できる項目
+var userPatchFields = map[string]bool{"name": true, "email": true}
+
+// UpdateUser replaces the caller's profile; name and email are both required
 func UpdateUser(c *gin.Context) {
-	id := c.Param("id")
-	var user models.User
+	user, ok := findUser(c)
+	if !ok {
+		return
+	}
 
-	if result := database.DB.First(&user, id); result.Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	var req UserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	saveUserProfile(c, &user, req)
+}
 
-	var updateData models.User
-	if err := c.ShouldBindJSON(&updateData); err != nil {
+// PatchUser applies an RFC 7396 JSON merge patch to the caller's profile
+func PatchUser(c *gin.Context) {
+	user, ok := findUser(c)
+	if !ok {
+		return
+	}
+
+	// 現在の値にパッチを重ねてから PUT と同じ検証を行う
+	req := UserRequest{Name: user.Name, Email: user.Email}
+	_, nulls, err := parseMergePatch(c, userPatchFields, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for name := range nulls {
+		c.JSON(http.StatusBadRequest, gin.H{"error": name + " cannot be null"})
+		return
+	}
+	if err := binding.Validator.ValidateStruct(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	saveUserProfile(c, &user, req)
+}
+
+// findUser loads the caller's own user named by the :id path parameter, responding 404 if missing
+func findUser(c *gin.Context) (models.User, bool) {
+	var user models.User
+	id, ok := requireSelf(c)
+	if !ok {
+		return user, false
+	}
+	if err := database.DB.First(&user, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return user, false
+	}
+	return user, true
+}
+
+// saveUserProfile stores the requested name and email, rejecting an email taken by another user
+func saveUserProfile(c *gin.Context, user *models.User, req UserRequest) {
+	req.Name, req.Email = strings.TrimSpace(req.Name), strings.TrimSpace(req.Email)
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name cannot be empty"})
+		return
+	}
+	var count int64
+	database.DB.Model(&models.User{}).Unscoped().
+		Where("email = ? AND id <> ?", req.Email, user.ID).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "このメールアドレスは既に使用されています"})
+		return
+	}
 
-	database.DB.Model(&user).Updates(updateData)
+	user.Name, user.Email = req.Name, req.Email
+	if err := database.DB.Model(user).Select("name", "email").Updates(user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 	c.JSON(http.StatusOK, user)
 }
 
-// DeleteUser deletes a user
+// requireSelf checks that the :id path parameter is the caller's own user ID.
+// On failure the error response has already been written.
+func requireSelf(c *gin.Context) (uint, bool) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "認証が必要です"})
+		return 0, false
+	}
+	if c.Param("id") != strconv.FormatUint(uint64(userID), 10) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "権限がありません"})
+		return 0, false
+	}
+	return userID, true
+}
+
+// DeleteUser moves the caller's own account to the trash
 func DeleteUser(c *gin.Context) {
-	id := c.Param("id")
+	id, ok := requireSelf(c)
+	if !ok {
+		return
+	}
 	result := database.DB.Delete(&models.User{}, id)
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+    "encoding/json"
     "net/http"
     "strconv"
     "testing"
@@ -33,6 +34,32 @@ func TestCreateUser_And_GetUsers(t *testing.T) {
     if w2.Code != http.StatusOK { t.Fatalf("expected 200, got %d", w2.Code) }
 }
 
+func TestGetUser_HidesOrganizationTasksFromNonMembers(t *testing.T) {
+    setupTaskDB(t)
+
+    owner := models.User{Name: "Owner", Email: "owner@example.com"}
+    database.DB.Create(&owner)
+    org := models.Organization{Name: "Acme"}
+    database.DB.Create(&org)
+    database.DB.Create(&models.OrganizationMember{OrganizationID: org.ID, UserID: owner.ID, Role: models.RoleOwner})
+    database.DB.Create(&models.Task{Title: "Personal", UserID: owner.ID, CreatorID: owner.ID})
+    database.DB.Create(&models.Task{Title: "Secret", UserID: owner.ID, CreatorID: owner.ID, OrganizationID: &org.ID})
+
+    taskCount := func(callerID uint) int {
+        w, c := performJSONRequest(GetUser, http.MethodGet, nil)
+        c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(owner.ID))}}
+        if callerID != 0 { c.Set("user_id", callerID) }
+        GetUser(c)
+        if w.Code != http.StatusOK { t.Fatalf("expected 200, got %d", w.Code) }
+        var user models.User
+        if err := json.Unmarshal(w.Body.Bytes(), &user); err != nil { t.Fatal(err) }
+        return len(user.Tasks)
+    }
+    if n := taskCount(0); n != 1 { t.Fatalf("expected 1 task for anonymous caller, got %d", n) }
+    if n := taskCount(owner.ID + 1); n != 1 { t.Fatalf("expected 1 task for non-member, got %d", n) }
+    if n := taskCount(owner.ID); n != 2 { t.Fatalf("expected 2 tasks for member, got %d", n) }
+}
+
 func TestGetUser_NotFound_And_DeleteUser(t *testing.T) {
     setupUserDB(t)
 
@@ -52,9 +79,19 @@ func TestGetUser_NotFound_And_DeleteUser(t *testing.T) {
     GetUser(c2)
     if w2.Code != http.StatusOK { t.Fatalf("expected 200, got %d", w2.Code) }
 
-    // delete
+    // delete（本人のみ）
     w3, c3 := performJSONRequest(DeleteUser, http.MethodDelete, nil)
     c3.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(u.ID))}}
     DeleteUser(c3)
+    if w3.Code != http.StatusUnauthorized { t.Fatalf("expected 401, got %d", w3.Code) }
+    w3, c3 = performJSONRequest(DeleteUser, http.MethodDelete, nil)
+    c3.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(u.ID))}}
+    c3.Set("user_id", u.ID+1)
+    DeleteUser(c3)
+    if w3.Code != http.StatusForbidden { t.Fatalf("expected 403, got %d", w3.Code) }
+    w3, c3 = performJSONRequest(DeleteUser, http.MethodDelete, nil)
+    c3.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(int(u.ID))}}
+    c3.Set("user_id", u.ID)
+    DeleteUser(c3)
     if w3.Code != http.StatusOK { t.Fatalf("expected 200, got %d", w3.Code) }
 }
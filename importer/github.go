@@ -0,0 +1,142 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"flux/models"
+)
+
+// githubTaskPattern 本文中のタスクリスト（"- [ ] 項目" / "- [x] 項目"）
+var githubTaskPattern = regexp.MustCompile(`(?m)^\s*[-*+]\s+\[([ xX])\]\s+(.+?)\s*$`)
+
+type githubIssue struct {
+	Number        int              `json:"number"`
+	Title         string           `json:"title"`
+	Body          string           `json:"body"`
+	State         string           `json:"state"`
+	Labels        []githubLabel    `json:"labels"`
+	Assignees     []githubUser     `json:"assignees"`
+	Assignee      *githubUser      `json:"assignee"`
+	Milestone     *githubMilestone `json:"milestone"`
+	URL           string           `json:"url"`
+	HTMLURL       string           `json:"html_url"`
+	RepositoryURL string           `json:"repository_url"`
+	PullRequest   json.RawMessage  `json:"pull_request"`
+}
+
+type githubUser struct {
+	Login string `json:"login"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type githubMilestone struct {
+	Title string     `json:"title"`
+	DueOn *time.Time `json:"due_on"`
+}
+
+// githubLabel は API の {"name": ...} と文字列の両方を受け付けます
+type githubLabel string
+
+func (l *githubLabel) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*l = githubLabel(name)
+		return nil
+	}
+	var obj struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	*l = githubLabel(obj.Name)
+	return nil
+}
+
+// ParseGitHub は REST API や gh issue list --json で取得した issue の JSON 配列を読み込みます。
+// ラベルとマイルストーンはラベルに、本文のタスクリストはサブタスクに、assignees は担当者になります。
+// プルリクエストは取り込みません。
+func ParseGitHub(r io.Reader) (*Result, error) {
+	var issues []githubIssue
+	if err := json.NewDecoder(r).Decode(&issues); err != nil {
+		return nil, fmt.Errorf("invalid GitHub issues export: %w", err)
+	}
+
+	result := &Result{}
+	for _, issue := range issues {
+		if len(issue.PullRequest) > 0 && string(issue.PullRequest) != "null" {
+			result.Skipped++
+			continue
+		}
+		if issue.Number == 0 {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("issue %q has no number and was skipped", issue.Title))
+			result.Skipped++
+			continue
+		}
+
+		id := "github:" + githubRepository(issue) + "#" + strconv.Itoa(issue.Number)
+		task := Task{
+			ExternalID:  id,
+			Title:       issue.Title,
+			Description: issue.Body,
+			Status:      models.StatusPending,
+		}
+		if strings.EqualFold(issue.State, "closed") {
+			task.Status = models.StatusCompleted
+		}
+		for _, l := range issue.Labels {
+			task.Labels = appendUnique(task.Labels, string(l))
+		}
+		if issue.Milestone != nil {
+			task.Labels = appendUnique(task.Labels, issue.Milestone.Title)
+			task.DueAt = issue.Milestone.DueOn
+		}
+		assignees := issue.Assignees
+		if len(assignees) == 0 && issue.Assignee != nil {
+			assignees = []githubUser{*issue.Assignee}
+		}
+		for _, u := range assignees {
+			task.Assignees = append(task.Assignees, Assignee{Login: u.Login, Name: u.Name, Email: u.Email})
+		}
+		for i, m := range githubTaskPattern.FindAllStringSubmatch(issue.Body, -1) {
+			status := models.StatusPending
+			if m[1] != " " {
+				status = models.StatusCompleted
+			}
+			task.Subtasks = append(task.Subtasks, Task{
+				ExternalID: id + ":" + strconv.Itoa(i+1),
+				Title:      m[2],
+				Status:     status,
+			})
+		}
+		result.Tasks = append(result.Tasks, task)
+	}
+	return result, nil
+}
+
+// githubRepository は issue の URL から "owner/repo" を取り出します（分からなければ空文字）
+func githubRepository(issue githubIssue) string {
+	for _, raw := range []string{issue.RepositoryURL, issue.HTMLURL, issue.URL} {
+		u, err := url.Parse(raw)
+		if err != nil || u.Host == "" {
+			continue
+		}
+		parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+		// api.github.com/repos/owner/repo[/issues/1] と github.com/owner/repo/issues/1
+		if len(parts) >= 3 && parts[0] == "repos" {
+			return parts[1] + "/" + parts[2]
+		}
+		if len(parts) >= 2 {
+			return parts[0] + "/" + parts[1]
+		}
+	}
+	return ""
+}
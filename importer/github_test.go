@@ -0,0 +1,49 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"flux/models"
+)
+
+const githubIssuesJSON = `[
+	{
+		"number": 7,
+		"title": "Crash on save",
+		"body": "Steps:\n- [x] reproduce\n- [ ] fix\n",
+		"state": "open",
+		"labels": [{"name": "bug"}],
+		"assignees": [{"login": "octocat"}],
+		"milestone": {"title": "v1.0", "due_on": "2026-07-01T07:00:00Z"},
+		"repository_url": "https://api.github.com/repos/acme/app"
+	},
+	{"number": 8, "title": "Add feature", "state": "OPEN", "pull_request": {"url": "x"}},
+	{"number": 9, "title": "Docs", "state": "CLOSED", "labels": ["docs"], "url": "https://github.com/acme/app/issues/9"}
+]`
+
+func TestParseGitHub(t *testing.T) {
+	res, err := ParseGitHub(strings.NewReader(githubIssuesJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Tasks) != 2 || res.Skipped != 1 {
+		t.Fatalf("expected 2 issues and a skipped pull request, got %d and %d", len(res.Tasks), res.Skipped)
+	}
+
+	crash := res.Tasks[0]
+	if crash.ExternalID != "github:acme/app#7" || crash.Status != models.StatusPending || crash.DueAt == nil {
+		t.Fatalf("unexpected issue: %+v", crash)
+	}
+	if strings.Join(crash.Labels, ",") != "bug,v1.0" || crash.Assignees[0].Login != "octocat" {
+		t.Fatalf("unexpected labels or assignees: %v %+v", crash.Labels, crash.Assignees)
+	}
+	if len(crash.Subtasks) != 2 || crash.Subtasks[0].Status != models.StatusCompleted || crash.Subtasks[1].Title != "fix" {
+		t.Fatalf("unexpected task list: %+v", crash.Subtasks)
+	}
+
+	docs := res.Tasks[1]
+	if docs.ExternalID != "github:acme/app#9" || docs.Status != models.StatusCompleted || docs.Labels[0] != "docs" {
+		t.Fatalf("unexpected issue: %+v", docs)
+	}
+}
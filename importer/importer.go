@@ -0,0 +1,119 @@
+package importer
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"flux/models"
+)
+
+// ErrUnknownSource 対応していないインポート元
+var ErrUnknownSource = errors.New("unknown import source")
+
+// Task は外部サービスのエクスポートから読み込んだタスクです
+type Task struct {
+	// ExternalID は "trello:<card id>" のようにサービス名を前置した ID です
+	ExternalID  string
+	Title       string
+	Description string
+	Status      string
+	Priority    models.Priority // 0 は指定なし
+	DueAt       *time.Time
+	Labels      []string
+	Assignees   []Assignee
+	// Subtasks はチェックリストの項目やインデントされたタスクです
+	Subtasks []Task
+}
+
+// Assignee はインポート元の担当者です（分かっている項目だけが入ります）
+type Assignee struct {
+	Login string
+	Name  string
+	Email string
+}
+
+// String は担当者を表示用の文字列にします
+func (a Assignee) String() string {
+	for _, v := range []string{a.Login, a.Name, a.Email} {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// Result は読み込み結果です
+type Result struct {
+	Tasks []Task
+	// Skipped はアーカイブ済みのカードやプルリクエストなど、取り込まなかった項目の数です
+	Skipped  int
+	Warnings []string
+}
+
+// ParseFunc はエクスポートファイルを読み込みます
+type ParseFunc func(r io.Reader) (*Result, error)
+
+var parsers = map[string]ParseFunc{
+	"trello":  ParseTrello,
+	"todoist": ParseTodoist,
+	"github":  ParseGitHub,
+}
+
+// Parse は source の形式で r を読み込みます
+func Parse(source string, r io.Reader) (*Result, error) {
+	parse, ok := parsers[source]
+	if !ok {
+		return nil, ErrUnknownSource
+	}
+	return parse(r)
+}
+
+// Sources は対応しているインポート元の名前を返します
+func Sources() []string {
+	names := make([]string, 0, len(parsers))
+	for name := range parsers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// statusForList はリストや列の名前からステータスを推測します
+func statusForList(name string) string {
+	name = strings.ToLower(name)
+	for _, word := range []string{"done", "complete", "closed", "finished", "完了"} {
+		if strings.Contains(name, word) {
+			return models.StatusCompleted
+		}
+	}
+	for _, word := range []string{"doing", "progress", "wip", "review", "作業中", "進行中"} {
+		if strings.Contains(name, word) {
+			return models.StatusInProgress
+		}
+	}
+	return models.StatusPending
+}
+
+// appendUnique は空でない重複しない値だけを追加します
+func appendUnique(list []string, values ...string) []string {
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		dup := false
+		for _, existing := range list {
+			if existing == v {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			list = append(list, v)
+		}
+	}
+	return list
+}
@@ -0,0 +1,182 @@
+package importer
+
+import (
+	"crypto/sha1"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"flux/models"
+)
+
+// todoistLabelPattern 内容に埋め込まれた @ラベル
+var todoistLabelPattern = regexp.MustCompile(`(?:^|\s)@([\p{L}\p{N}_\-]+)`)
+
+// todoistResponsiblePattern 担当者の末尾に付くユーザー ID（"Jane Doe (12345)"）
+var todoistResponsiblePattern = regexp.MustCompile(`\s*\(\d+\)$`)
+
+// todoistDateLayouts DATE 列で解釈できる日付の形式（それ以外の自然文は警告にする）
+var todoistDateLayouts = []string{
+	time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04", "2006-01-02",
+	"Jan 2 2006 15:04", "Jan 2 2006", "2 Jan 2006 15:04", "2 Jan 2006",
+}
+
+// todoistPriorities Todoist の優先度（画面表示と同じく 1 が最も高い）
+var todoistPriorities = map[string]models.Priority{
+	"1": models.PriorityUrgent,
+	"2": models.PriorityHigh,
+	"3": models.PriorityMedium,
+	"4": models.PriorityLow,
+}
+
+// todoistItem 読み込んだタスクとインデントの深さ
+type todoistItem struct {
+	task   Task
+	indent int
+}
+
+// ParseTodoist はプロジェクトの CSV バックアップを読み込みます。
+// セクションと @ラベルはラベルに、インデントされたタスクはサブタスクに、RESPONSIBLE は担当者になります。
+// CSV には ID が無いため、セクション・親・内容から外部 ID を作ります。
+func ParseTodoist(r io.Reader) (*Result, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid Todoist backup: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToUpper(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	if _, ok := columns["TYPE"]; !ok {
+		return nil, errors.New("invalid Todoist backup: TYPE column is required")
+	}
+	if _, ok := columns["CONTENT"]; !ok {
+		return nil, errors.New("invalid Todoist backup: CONTENT column is required")
+	}
+
+	result := &Result{}
+	var items []todoistItem
+	var section string
+	// 直前のタスクのインデント（セクションが変わると 0 に戻る）
+	prevIndent := 0
+	// 同じ階層の同じ内容のタスクを区別するための出現回数
+	seen := map[string]int{}
+	// 各インデントの直近のタスクの内容（外部 ID の計算に使う）
+	var path []string
+
+	for row := 2; ; row++ {
+		cells, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid Todoist backup: %w", err)
+		}
+		cell := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(cells) {
+				return strings.TrimSpace(cells[i])
+			}
+			return ""
+		}
+
+		switch strings.ToLower(cell("TYPE")) {
+		case "section":
+			section = cell("CONTENT")
+			path, prevIndent = nil, 0
+		case "note":
+			// コメントは直前のタスクの説明に追記する
+			if len(items) > 0 && cell("CONTENT") != "" {
+				last := &items[len(items)-1].task
+				last.Description = strings.TrimSpace(last.Description + "\n\n" + cell("CONTENT"))
+			}
+		case "task":
+			indent, err := strconv.Atoi(cell("INDENT"))
+			if err != nil || indent < 1 {
+				indent = 1
+			}
+			// 親の無いインデントは直前のタスクの1段下として扱う
+			if indent > prevIndent+1 {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("row %d: indent %d has no parent task", row, indent))
+				indent = prevIndent + 1
+			}
+			prevIndent = indent
+
+			content := cell("CONTENT")
+			var labels []string
+			for _, m := range todoistLabelPattern.FindAllStringSubmatch(content, -1) {
+				labels = appendUnique(labels, m[1])
+			}
+			title := strings.Join(strings.Fields(todoistLabelPattern.ReplaceAllString(content, " ")), " ")
+
+			if len(path) >= indent {
+				path = path[:indent-1]
+			}
+			path = append(path, title)
+			key := section + "\x00" + strings.Join(path, "\x00")
+			seen[key]++
+			if seen[key] > 1 {
+				key += "\x00" + strconv.Itoa(seen[key])
+			}
+			sum := sha1.Sum([]byte(key))
+
+			task := Task{
+				ExternalID:  "todoist:" + hex.EncodeToString(sum[:8]),
+				Title:       title,
+				Description: cell("DESCRIPTION"),
+				Status:      models.StatusPending,
+				Priority:    todoistPriorities[cell("PRIORITY")],
+				Labels:      appendUnique(labels, section),
+			}
+			if due := cell("DATE"); due != "" {
+				if t, ok := parseTodoistDate(due, cell("TIMEZONE")); ok {
+					task.DueAt = &t
+				} else {
+					result.Warnings = append(result.Warnings, fmt.Sprintf("row %d: could not parse date %q", row, due))
+				}
+			}
+			if name := todoistResponsiblePattern.ReplaceAllString(cell("RESPONSIBLE"), ""); name != "" {
+				task.Assignees = []Assignee{{Name: name}}
+			}
+			items = append(items, todoistItem{task: task, indent: indent})
+		}
+	}
+
+	result.Tasks, _ = nestTodoistItems(items, 0, 1)
+	return result, nil
+}
+
+// nestTodoistItems は items[start:] のうち indent の深さのタスクを、より深いタスクを
+// サブタスクにしてまとめます。処理した位置の次を返します。
+func nestTodoistItems(items []todoistItem, start, indent int) ([]Task, int) {
+	var tasks []Task
+	i := start
+	for i < len(items) && items[i].indent >= indent {
+		task := items[i].task
+		task.Subtasks, i = nestTodoistItems(items, i+1, indent+1)
+		tasks = append(tasks, task)
+	}
+	return tasks, i
+}
+
+func parseTodoistDate(v, tz string) (time.Time, bool) {
+	loc := time.UTC
+	if tz != "" {
+		if l, err := time.LoadLocation(tz); err == nil {
+			loc = l
+		}
+	}
+	for _, layout := range todoistDateLayouts {
+		if t, err := time.ParseInLocation(layout, v, loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,53 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"flux/models"
+)
+
+const todoistCSV = "TYPE,CONTENT,DESCRIPTION,PRIORITY,INDENT,AUTHOR,RESPONSIBLE,DATE,DATE_LANG,TIMEZONE\n" +
+	"task,Plan release @work,,1,1,Ann (1),Bob Lee (42),2026-06-01,en,UTC\n" +
+	"task,Draft notes,,4,2,Ann (1),,,en,UTC\n" +
+	"note,Remember the changelog,,,,Ann (1),,,en,UTC\n" +
+	",,,,,,,,,\n" +
+	"section,Later,,,,,,,,\n" +
+	"task,Plan release,,3,1,Ann (1),,every monday,en,UTC\n" +
+	"task,Orphan,,3,3,Ann (1),,,en,UTC\n"
+
+func TestParseTodoist(t *testing.T) {
+	res, err := ParseTodoist(strings.NewReader(todoistCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Tasks) != 2 {
+		t.Fatalf("expected 2 top-level tasks, got %+v", res.Tasks)
+	}
+
+	plan := res.Tasks[0]
+	if plan.Title != "Plan release" || plan.Priority != models.PriorityUrgent || plan.DueAt == nil || plan.DueAt.Day() != 1 {
+		t.Fatalf("unexpected task: %+v", plan)
+	}
+	if strings.Join(plan.Labels, ",") != "work" || len(plan.Assignees) != 1 || plan.Assignees[0].Name != "Bob Lee" {
+		t.Fatalf("unexpected labels or assignees: %v %+v", plan.Labels, plan.Assignees)
+	}
+	if len(plan.Subtasks) != 1 || plan.Subtasks[0].Description != "Remember the changelog" || plan.Subtasks[0].Priority != models.PriorityLow {
+		t.Fatalf("unexpected subtasks: %+v", plan.Subtasks)
+	}
+
+	// 別セクションの同名タスクは別の外部 ID になり、親の無いインデントは子にする
+	later := res.Tasks[1]
+	if later.ExternalID == plan.ExternalID || strings.Join(later.Labels, ",") != "Later" || len(later.Subtasks) != 1 {
+		t.Fatalf("unexpected task in section: %+v", later)
+	}
+	if len(res.Warnings) != 2 {
+		t.Fatalf("expected warnings for the recurring date and the orphan indent, got %v", res.Warnings)
+	}
+
+	// 同じファイルからは同じ外部 ID が作られる
+	again, _ := ParseTodoist(strings.NewReader(todoistCSV))
+	if again.Tasks[0].Subtasks[0].ExternalID != plan.Subtasks[0].ExternalID {
+		t.Fatal("expected stable external IDs")
+	}
+}
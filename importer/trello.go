@@ -0,0 +1,156 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"flux/models"
+)
+
+type trelloBoard struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Lists []struct {
+		ID     string  `json:"id"`
+		Name   string  `json:"name"`
+		Closed bool    `json:"closed"`
+		Pos    float64 `json:"pos"`
+	} `json:"lists"`
+	Labels []struct {
+		ID    string `json:"id"`
+		Name  string `json:"name"`
+		Color string `json:"color"`
+	} `json:"labels"`
+	Members []struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+		FullName string `json:"fullName"`
+	} `json:"members"`
+	Checklists []struct {
+		ID         string  `json:"id"`
+		IDCard     string  `json:"idCard"`
+		Pos        float64 `json:"pos"`
+		CheckItems []struct {
+			ID    string  `json:"id"`
+			Name  string  `json:"name"`
+			State string  `json:"state"`
+			Pos   float64 `json:"pos"`
+		} `json:"checkItems"`
+	} `json:"checklists"`
+	Cards []struct {
+		ID          string     `json:"id"`
+		Name        string     `json:"name"`
+		Desc        string     `json:"desc"`
+		IDList      string     `json:"idList"`
+		IDLabels    []string   `json:"idLabels"`
+		IDMembers   []string   `json:"idMembers"`
+		Due         *time.Time `json:"due"`
+		DueComplete bool       `json:"dueComplete"`
+		Closed      bool       `json:"closed"`
+		Pos         float64    `json:"pos"`
+	} `json:"cards"`
+}
+
+// ParseTrello はボードの JSON エクスポートを読み込みます。
+// リストはステータスとラベルに、チェックリストの項目はサブタスクに、メンバーは担当者になります。
+// アーカイブ済みのカードとリストは取り込みません。
+func ParseTrello(r io.Reader) (*Result, error) {
+	var board trelloBoard
+	if err := json.NewDecoder(r).Decode(&board); err != nil {
+		return nil, fmt.Errorf("invalid Trello export: %w", err)
+	}
+	if board.ID == "" || board.Cards == nil {
+		return nil, errors.New("invalid Trello export: board id and cards are required")
+	}
+
+	type listInfo struct {
+		name   string
+		closed bool
+		index  int
+	}
+	sort.SliceStable(board.Lists, func(i, j int) bool { return board.Lists[i].Pos < board.Lists[j].Pos })
+	lists := make(map[string]listInfo, len(board.Lists))
+	for i, l := range board.Lists {
+		lists[l.ID] = listInfo{name: l.Name, closed: l.Closed, index: i}
+	}
+	labels := make(map[string]string, len(board.Labels))
+	for _, l := range board.Labels {
+		// 名前の無いラベルは色で表す
+		name := l.Name
+		if name == "" {
+			name = l.Color
+		}
+		labels[l.ID] = name
+	}
+	members := make(map[string]Assignee, len(board.Members))
+	for _, m := range board.Members {
+		members[m.ID] = Assignee{Login: m.Username, Name: m.FullName}
+	}
+
+	// チェックリストはカードごとに並び順どおりにまとめる
+	sort.SliceStable(board.Checklists, func(i, j int) bool { return board.Checklists[i].Pos < board.Checklists[j].Pos })
+	subtasks := make(map[string][]Task)
+	for _, cl := range board.Checklists {
+		items := cl.CheckItems
+		sort.SliceStable(items, func(i, j int) bool { return items[i].Pos < items[j].Pos })
+		for _, item := range items {
+			status := models.StatusPending
+			if item.State == "complete" {
+				status = models.StatusCompleted
+			}
+			subtasks[cl.IDCard] = append(subtasks[cl.IDCard], Task{
+				ExternalID: "trello:" + cl.IDCard + ":" + item.ID,
+				Title:      item.Name,
+				Status:     status,
+			})
+		}
+	}
+
+	cards := board.Cards
+	sort.SliceStable(cards, func(i, j int) bool {
+		li, lj := lists[cards[i].IDList].index, lists[cards[j].IDList].index
+		if li != lj {
+			return li < lj
+		}
+		return cards[i].Pos < cards[j].Pos
+	})
+
+	result := &Result{}
+	for _, card := range cards {
+		list, ok := lists[card.IDList]
+		if card.Closed || list.closed {
+			result.Skipped++
+			continue
+		}
+		if !ok {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("card %q: list %s not found", card.Name, card.IDList))
+		}
+
+		task := Task{
+			ExternalID:  "trello:" + card.ID,
+			Title:       card.Name,
+			Description: card.Desc,
+			Status:      statusForList(list.name),
+			DueAt:       card.Due,
+			Labels:      appendUnique(nil, list.name),
+			Subtasks:    subtasks[card.ID],
+		}
+		if card.DueComplete {
+			task.Status = models.StatusCompleted
+		}
+		for _, id := range card.IDLabels {
+			task.Labels = appendUnique(task.Labels, labels[id])
+		}
+		for _, id := range card.IDMembers {
+			if m, ok := members[id]; ok {
+				task.Assignees = append(task.Assignees, m)
+			}
+		}
+		result.Tasks = append(result.Tasks, task)
+	}
+	return result, nil
+}
@@ -0,0 +1,62 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"flux/models"
+)
+
+const trelloBoardJSON = `{
+	"id": "board1",
+	"name": "Sprint",
+	"lists": [
+		{"id": "l2", "name": "Done", "pos": 2},
+		{"id": "l1", "name": "To Do", "pos": 1},
+		{"id": "l3", "name": "Old", "closed": true, "pos": 3}
+	],
+	"labels": [{"id": "lb1", "name": "bug", "color": "red"}, {"id": "lb2", "name": "", "color": "green"}],
+	"members": [{"id": "m1", "username": "alice", "fullName": "Alice Smith"}],
+	"checklists": [{"id": "cl1", "idCard": "c1", "checkItems": [
+		{"id": "i2", "name": "Deploy", "state": "incomplete", "pos": 2},
+		{"id": "i1", "name": "Write tests", "state": "complete", "pos": 1}
+	]}],
+	"cards": [
+		{"id": "c2", "name": "Ship it", "idList": "l2", "pos": 1},
+		{"id": "c1", "name": "Fix login", "desc": "details", "idList": "l1", "idLabels": ["lb1", "lb2"], "idMembers": ["m1"], "due": "2026-05-01T12:00:00.000Z", "pos": 1},
+		{"id": "c3", "name": "Archived", "idList": "l1", "closed": true},
+		{"id": "c4", "name": "In old list", "idList": "l3"}
+	]
+}`
+
+func TestParseTrello(t *testing.T) {
+	res, err := ParseTrello(strings.NewReader(trelloBoardJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Tasks) != 2 || res.Skipped != 2 {
+		t.Fatalf("expected 2 tasks and 2 skipped, got %d and %d", len(res.Tasks), res.Skipped)
+	}
+
+	// リストの並び順どおりに並ぶ
+	fix, ship := res.Tasks[0], res.Tasks[1]
+	if fix.ExternalID != "trello:c1" || fix.Status != models.StatusPending || fix.DueAt == nil {
+		t.Fatalf("unexpected card: %+v", fix)
+	}
+	if strings.Join(fix.Labels, ",") != "To Do,bug,green" {
+		t.Fatalf("unexpected labels: %v", fix.Labels)
+	}
+	if len(fix.Assignees) != 1 || fix.Assignees[0].Login != "alice" || fix.Assignees[0].Name != "Alice Smith" {
+		t.Fatalf("unexpected assignees: %+v", fix.Assignees)
+	}
+	if len(fix.Subtasks) != 2 || fix.Subtasks[0].Title != "Write tests" || fix.Subtasks[0].Status != models.StatusCompleted || fix.Subtasks[1].ExternalID != "trello:c1:i2" {
+		t.Fatalf("unexpected checklist: %+v", fix.Subtasks)
+	}
+	if ship.Status != models.StatusCompleted {
+		t.Fatalf("expected card in Done to be completed, got %s", ship.Status)
+	}
+
+	if _, err := ParseTrello(strings.NewReader(`{"name": "not a board"}`)); err == nil {
+		t.Fatal("expected an error for a file that is not a board export")
+	}
+}
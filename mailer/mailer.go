@@ -9,6 +9,7 @@ import (
 // Mailer はメール送信のインターフェースを定義します
 type Mailer interface {
     SendPasswordReset(email, username, token string) error
+    SendMentionNotification(email, username, actorName, taskTitle string, taskID uint) error
 }
 
 // DevMailer は開発用のメール送信をシミュレートします
@@ -25,6 +26,12 @@ func (m *DevMailer) SendPasswordReset(email, username, token string) error {
     return nil
 }
 
+func (m *DevMailer) SendMentionNotification(email, username, actorName, taskTitle string, taskID uint) error {
+    log.Printf("[DEV] %s さんが「%s」であなたをメンションしました: %s\n", actorName, taskTitle, generateTaskURL(taskID))
+    log.Printf("[DEV] 受信者: %s\n", email)
+    return nil
+}
+
 // ProdMailer は本番環境用のメール送信を行います
 type ProdMailer struct {
     from     string
@@ -49,10 +56,23 @@ func (m *ProdMailer) SendPasswordReset(email, username, token string) error {
     return nil
 }
 
+func (m *ProdMailer) SendMentionNotification(email, username, actorName, taskTitle string, taskID uint) error {
+    log.Printf("[PROD] メールを送信しました: %s\n", email)
+    log.Printf("[PROD] タスクURL: %s\n", generateTaskURL(taskID))
+    return nil
+}
+
 func generateResetURL(token string) string {
-    frontendURL := os.Getenv("FRONTEND_URL")
-    if frontendURL == "" {
-        frontendURL = "http://localhost:3000"
+    return fmt.Sprintf("%s/reset-password?token=%s", frontendURL(), token)
+}
+
+func generateTaskURL(taskID uint) string {
+    return fmt.Sprintf("%s/tasks/%d", frontendURL(), taskID)
+}
+
+func frontendURL() string {
+    if u := os.Getenv("FRONTEND_URL"); u != "" {
+        return u
     }
-    return fmt.Sprintf("%s/reset-password?token=%s", frontendURL, token)
+    return "http://localhost:3000"
 }
\ No newline at end of file
@@ -1,15 +1,18 @@
 package main
 
 import (
+    "context"
     "log"
     "os"
 
     "flux/config"
     "flux/database"
+    "flux/handlers"
     "flux/mailer"
     "flux/middleware"
     "flux/models"
     "flux/routes"
+    "flux/storage"
 
     "github.com/gin-gonic/gin"
     "github.com/joho/godotenv"
@@ -23,6 +26,7 @@ func main() {
 
     // 設定の読み込み
     _ = config.Load()
+    models.LoadStatusTransitions()
 
     // データベース接続
     db, err := database.Connect()
@@ -32,10 +36,7 @@ func main() {
 
     // マイグレーション
     if len(os.Args) > 1 && os.Args[1] == "migrate" {
-        if err := db.AutoMigrate(&models.User{}, &models.Task{}, &models.PasswordReset{}); err != nil {
-            log.Fatalf("Failed to migrate database: %v", err)
-        }
-        log.Println("Migration completed successfully")
+        database.Migrate()
         return
     }
 
@@ -58,8 +59,18 @@ func main() {
 		mailerInstance = mailer.NewDevMailer()
 	}
 
+	// 添付ファイルの保存先（S3 などは storage.Storage を実装して差し替える）
+	storageDir := os.Getenv("STORAGE_DIR")
+	if storageDir == "" {
+		storageDir = "uploads"
+	}
+	store := storage.NewFSStorage(storageDir)
+
 	// ルートの設定
-	routes.SetupRoutes(r, db, mailerInstance)
+	routes.SetupRoutes(r, db, mailerInstance, store)
+
+	// 保持期間を過ぎたゴミ箱の中身を定期的に完全削除する
+	handlers.StartTrashPurger(context.Background())
 
     // サーバー起動
     port := os.Getenv("PORT")
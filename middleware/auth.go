@@ -32,6 +32,19 @@ func AuthMiddleware() gin.HandlerFunc {
 	}
 }
 
+// OptionalAuthMiddleware 任意認証ミドルウェア（有効なトークンがあればユーザー情報を保存し、無くても通す）
+func OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token := utils.GetTokenFromRequest(c); token != "" {
+			if claims, err := utils.ParseToken(token); err == nil {
+				c.Set("user_id", claims.UserID)
+				c.Set("user_email", claims.Email)
+			}
+		}
+		c.Next()
+	}
+}
+
 // GetUserID コンテキストからユーザーIDを取得
 func GetUserID(c *gin.Context) (uint, bool) {
 	userID, exists := c.Get("user_id")
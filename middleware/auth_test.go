@@ -68,3 +68,30 @@ func TestAuthMiddleware_Success(t *testing.T) {
     if res["id"].(float64) != 42 { t.Fatalf("unexpected id: %v", res["id"]) }
     if res["email"].(string) != "user@example.com" { t.Fatalf("unexpected email: %v", res["email"]) }
 }
+
+func TestOptionalAuthMiddleware(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    r := gin.New()
+    r.Use(OptionalAuthMiddleware())
+    r.GET("/public", func(c *gin.Context) {
+        id, ok := GetUserID(c)
+        c.JSON(http.StatusOK, gin.H{"user_id": id, "authenticated": ok})
+    })
+
+    token, _ := utils.GenerateToken(7, "opt@example.com")
+    for _, tc := range []struct {
+        header string
+        want   bool
+    }{{"", false}, {"Bearer invalid", false}, {"Bearer " + token, true}} {
+        w := httptest.NewRecorder()
+        req, _ := http.NewRequest(http.MethodGet, "/public", nil)
+        if tc.header != "" { req.Header.Set("Authorization", tc.header) }
+        r.ServeHTTP(w, req)
+
+        var body map[string]interface{}
+        _ = json.Unmarshal(w.Body.Bytes(), &body)
+        if w.Code != http.StatusOK || body["authenticated"] != tc.want {
+            t.Fatalf("header %q: expected authenticated=%v, got %d %v", tc.header, tc.want, w.Code, body)
+        }
+    }
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// Attachment タスクに添付されたファイル
+type Attachment struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	TaskID      uint      `gorm:"not null;index" json:"task_id"`
+	UserID      uint      `gorm:"not null" json:"user_id"` // アップロードしたユーザー
+	FileName    string    `gorm:"size:255;not null" json:"file_name"`
+	ContentType string    `gorm:"size:100;not null" json:"content_type"` // 内容から判定した MIME タイプ
+	Size        int64     `gorm:"not null" json:"size"`
+	StorageKey  string    `gorm:"size:255;not null;uniqueIndex" json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+}
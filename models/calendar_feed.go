@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+// CalendarFeed ユーザーごとの ICS フィードの URL に埋め込む秘密のトークン
+type CalendarFeed struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	UserID    uint      `gorm:"not null;uniqueIndex" json:"-"`
+	Token     string    `gorm:"size:64;not null;uniqueIndex" json:"-"` // 再発行すると古い URL は使えなくなる
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
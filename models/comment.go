@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Comment タスクに付けるコメント
+type Comment struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	TaskID    uint           `gorm:"not null;index" json:"task_id"`
+	UserID    uint           `gorm:"not null;index" json:"user_id"` // 投稿者
+	User      User           `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Body      string         `gorm:"type:text;not null" json:"body" binding:"required,max=10000"`
+	EditedAt  *time.Time     `json:"edited_at"` // 本文を最後に編集した日時
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
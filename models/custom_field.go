@@ -0,0 +1,221 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// カスタムフィールドの型
+const (
+	FieldTypeText         = "text"
+	FieldTypeNumber       = "number"
+	FieldTypeDate         = "date"
+	FieldTypeSingleSelect = "single_select"
+	FieldTypeMultiSelect  = "multi_select"
+	FieldTypeUser         = "user"
+)
+
+// FieldDateLayout 日付型の値の形式
+const FieldDateLayout = "2006-01-02"
+
+// maxFieldTextLength テキスト型の値の最大文字数
+const maxFieldTextLength = 1000
+
+var fieldTypes = map[string]bool{
+	FieldTypeText:         true,
+	FieldTypeNumber:       true,
+	FieldTypeDate:         true,
+	FieldTypeSingleSelect: true,
+	FieldTypeMultiSelect:  true,
+	FieldTypeUser:         true,
+}
+
+// ErrFieldOptionsRequired 選択型のフィールドに選択肢が無い
+var ErrFieldOptionsRequired = errors.New("options are required for select fields")
+
+// ErrFieldOptionsNotAllowed 選択型以外のフィールドに選択肢が指定された
+var ErrFieldOptionsNotAllowed = errors.New("options are only allowed for select fields")
+
+// ErrInvalidFieldOption 選択肢が空、重複、またはカンマを含んでいる
+var ErrInvalidFieldOption = errors.New("options must be unique, non-empty and must not contain commas")
+
+// CustomField プロジェクトごとに定義するタスクの追加項目
+type CustomField struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ProjectID uint      `gorm:"not null;index" json:"project_id"`
+	Name      string    `gorm:"size:100;not null" json:"name"`
+	Type      string    `gorm:"size:20;not null" json:"type"`
+	Options   []string  `gorm:"type:text;serializer:json" json:"options,omitempty"` // 選択肢（選択型のみ、この順に並べる）
+	Position  int       `gorm:"not null;default:0" json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// FieldValues フィールド ID（文字列）ごとのカスタムフィールドの値
+type FieldValues map[string]interface{}
+
+// TaskFieldValue タスクに設定したカスタムフィールドの値。絞り込みと並べ替えのため型ごとの
+// カラムに保存し、複数選択は選択肢ごとに1行にする
+type TaskFieldValue struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	TaskID      uint       `gorm:"not null;index" json:"task_id"`
+	FieldID     uint       `gorm:"not null;index" json:"field_id"`
+	TextValue   *string    `gorm:"size:1000" json:"text_value"` // テキスト・単一選択・複数選択
+	NumberValue *float64   `json:"number_value"`
+	DateValue   *time.Time `json:"date_value"`
+	UserValue   *uint      `gorm:"index" json:"user_value"`
+}
+
+// IsSelect 選択肢を持つ型かどうか
+func (f *CustomField) IsSelect() bool {
+	return f.Type == FieldTypeSingleSelect || f.Type == FieldTypeMultiSelect
+}
+
+// Validate 型と選択肢の整合性を検証し、選択肢の前後の空白を取り除く
+func (f *CustomField) Validate() error {
+	if !fieldTypes[f.Type] {
+		return fmt.Errorf("invalid type: %s (must be text, number, date, single_select, multi_select or user)", f.Type)
+	}
+	if !f.IsSelect() {
+		if len(f.Options) > 0 {
+			return ErrFieldOptionsNotAllowed
+		}
+		return nil
+	}
+	if len(f.Options) == 0 {
+		return ErrFieldOptionsRequired
+	}
+	seen := make(map[string]bool, len(f.Options))
+	for i, o := range f.Options {
+		o = strings.TrimSpace(o)
+		// 絞り込みではカンマ区切りで複数の選択肢を指定するため、カンマは使えない
+		if o == "" || seen[o] || strings.Contains(o, ",") {
+			return ErrInvalidFieldOption
+		}
+		seen[o] = true
+		f.Options[i] = o
+	}
+	return nil
+}
+
+// HasOption 選択肢に含まれるかどうか
+func (f *CustomField) HasOption(option string) bool {
+	for _, o := range f.Options {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseValue リクエストの値を型に合わせて検証し、保存する行に変換する。
+// null や空の値は値の削除として空のスライスを返す。ユーザーの存在確認は呼び出し側で行う
+func (f *CustomField) ParseValue(raw json.RawMessage) ([]TaskFieldValue, error) {
+	if len(raw) == 0 || bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return nil, nil
+	}
+	invalid := func(want string) error {
+		return fmt.Errorf("custom field %q must be %s", f.Name, want)
+	}
+
+	switch f.Type {
+	case FieldTypeText, FieldTypeSingleSelect:
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, invalid("a string")
+		}
+		if s = strings.TrimSpace(s); s == "" {
+			return nil, nil
+		}
+		if f.Type == FieldTypeText && len([]rune(s)) > maxFieldTextLength {
+			return nil, fmt.Errorf("custom field %q must be at most %d characters", f.Name, maxFieldTextLength)
+		}
+		if f.Type == FieldTypeSingleSelect && !f.HasOption(s) {
+			return nil, invalid("one of the options")
+		}
+		return []TaskFieldValue{{TextValue: &s}}, nil
+	case FieldTypeMultiSelect:
+		var selected []string
+		if err := json.Unmarshal(raw, &selected); err != nil {
+			return nil, invalid("an array of strings")
+		}
+		chosen := make(map[string]bool, len(selected))
+		for _, s := range selected {
+			if !f.HasOption(s) {
+				return nil, invalid("a list of the options")
+			}
+			chosen[s] = true
+		}
+		// 選択肢の定義順に保存する
+		var rows []TaskFieldValue
+		for _, o := range f.Options {
+			if chosen[o] {
+				rows = append(rows, TaskFieldValue{TextValue: &o})
+			}
+		}
+		return rows, nil
+	case FieldTypeNumber:
+		var n float64
+		if err := json.Unmarshal(raw, &n); err != nil {
+			return nil, invalid("a number")
+		}
+		return []TaskFieldValue{{NumberValue: &n}}, nil
+	case FieldTypeDate:
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, invalid("a date (YYYY-MM-DD)")
+		}
+		d, err := time.Parse(FieldDateLayout, s)
+		if err != nil {
+			return nil, invalid("a date (YYYY-MM-DD)")
+		}
+		return []TaskFieldValue{{DateValue: &d}}, nil
+	case FieldTypeUser:
+		var id uint
+		if err := json.Unmarshal(raw, &id); err != nil || id == 0 {
+			return nil, invalid("a user ID")
+		}
+		return []TaskFieldValue{{UserValue: &id}}, nil
+	}
+	return nil, fmt.Errorf("unknown custom field type: %s", f.Type)
+}
+
+// Value 保存した行を API で返す値に戻す（値が無ければ nil）
+func (f *CustomField) Value(rows []TaskFieldValue) interface{} {
+	if len(rows) == 0 {
+		return nil
+	}
+	if f.Type == FieldTypeMultiSelect {
+		// 選択肢を並べ替えた後も定義順で返す
+		chosen := make(map[string]bool, len(rows))
+		for _, r := range rows {
+			if r.TextValue != nil {
+				chosen[*r.TextValue] = true
+			}
+		}
+		selected := make([]string, 0, len(rows))
+		for _, o := range f.Options {
+			if chosen[o] {
+				selected = append(selected, o)
+			}
+		}
+		return selected
+	}
+
+	r := rows[0]
+	switch {
+	case f.Type == FieldTypeNumber && r.NumberValue != nil:
+		return *r.NumberValue
+	case f.Type == FieldTypeDate && r.DateValue != nil:
+		return r.DateValue.UTC().Format(FieldDateLayout)
+	case f.Type == FieldTypeUser && r.UserValue != nil:
+		return *r.UserValue
+	case r.TextValue != nil:
+		return *r.TextValue
+	}
+	return nil
+}
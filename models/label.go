@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// Label タスクに付けるラベル。個人のラベルはユーザーごと、組織のラベルは組織ごとに名前が一意
+type Label struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	Name           string    `gorm:"size:50;not null;uniqueIndex:idx_labels_personal_name,where:organization_id IS NULL;uniqueIndex:idx_labels_org_name,where:organization_id IS NOT NULL" json:"name" binding:"required,max=50"`
+	Color          string    `gorm:"size:7" json:"color" binding:"omitempty,hexcolor"`
+	UserID         uint      `gorm:"not null;index;uniqueIndex:idx_labels_personal_name" json:"user_id"` // 作成したユーザー
+	OrganizationID *uint     `gorm:"uniqueIndex:idx_labels_org_name" json:"organization_id"`             // nil は個人のラベル
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+// NotificationMention メンションされたときの通知種別
+const NotificationMention = "mention"
+
+// Mention タスクの説明やコメント中の @メンション
+type Mention struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	TaskID    uint      `gorm:"not null;index" json:"task_id"`
+	CommentID *uint     `gorm:"index" json:"comment_id"`       // nil ならタスクの説明でのメンション
+	UserID    uint      `gorm:"not null;index" json:"user_id"` // メンションされたユーザー
+	AuthorID  uint      `gorm:"not null" json:"author_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Notification ユーザーへの通知
+type Notification struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"` // 受信者
+	Type      string     `gorm:"size:30;not null" json:"type"`
+	ActorID   uint       `gorm:"not null" json:"actor_id"`
+	Actor     User       `gorm:"foreignKey:ActorID" json:"actor,omitempty"`
+	TaskID    uint       `gorm:"not null;index" json:"task_id"`
+	CommentID *uint      `json:"comment_id"`
+	ReadAt    *time.Time `gorm:"index" json:"read_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
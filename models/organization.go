@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 組織内のロール
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+	RoleViewer = "viewer"
+)
+
+// Organization タスクを共有するチーム・組織
+type Organization struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	Name      string         `gorm:"size:100;not null" json:"name" binding:"required,max=100"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// OrganizationMember 組織のメンバーとロール
+type OrganizationMember struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	OrganizationID uint      `gorm:"not null;uniqueIndex:idx_org_members_org_user" json:"organization_id"`
+	UserID         uint      `gorm:"not null;uniqueIndex:idx_org_members_org_user;index" json:"user_id"`
+	Role           string    `gorm:"size:20;not null" json:"role"`
+	User           User      `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// IsValidRole 定義済みのロールかどうか
+func IsValidRole(role string) bool {
+	switch role {
+	case RoleOwner, RoleAdmin, RoleMember, RoleViewer:
+		return true
+	}
+	return false
+}
+
+// CanManage メンバーや組織設定を管理できるか
+func (m *OrganizationMember) CanManage() bool {
+	return m.Role == RoleOwner || m.Role == RoleAdmin
+}
+
+// CanEdit タスクを作成・編集できるか（viewer は閲覧のみ）
+func (m *OrganizationMember) CanEdit() bool {
+	return m.CanManage() || m.Role == RoleMember
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Project タスクをまとめるプロジェクト
+type Project struct {
+	ID          uint           `gorm:"primaryKey" json:"id"`
+	Name        string         `gorm:"size:100;not null" json:"name" binding:"required,max=100"`
+	Description string         `gorm:"type:text" json:"description"`
+	OwnerID     uint           `gorm:"not null;index" json:"owner_id"`
+	Archived    bool           `gorm:"default:false;index" json:"archived"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
@@ -1,19 +1,178 @@
 package models
 
 import (
+	"encoding/json"
+	"errors"
+	"flux/utils"
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// ErrStartAfterDue 開始日時が期限より後になっている
+var ErrStartAfterDue = errors.New("start_at must be before due_at")
+
+// ErrRecurrenceWithoutDue 繰り返しルールには期限が必要
+var ErrRecurrenceWithoutDue = errors.New("recurrence requires due_at")
+
+// ErrInvalidTimeZone タイムゾーンが IANA のタイムゾーン名ではない
+var ErrInvalidTimeZone = errors.New("time_zone must be an IANA time zone name such as Asia/Tokyo")
+
+// ErrNegativeEstimate 見積もり時間が負の値になっている
+var ErrNegativeEstimate = errors.New("estimated_minutes must not be negative")
+
+// Priority タスクの優先度（DBには数値で保存し、JSONでは名前で表現）
+type Priority int
+
+const (
+	PriorityLow Priority = iota + 1
+	PriorityMedium
+	PriorityHigh
+	PriorityUrgent
+)
+
+var priorityNames = map[Priority]string{
+	PriorityLow:    "low",
+	PriorityMedium: "medium",
+	PriorityHigh:   "high",
+	PriorityUrgent: "urgent",
+}
+
+// ParsePriority 優先度名を Priority に変換
+func ParsePriority(name string) (Priority, error) {
+	for p, n := range priorityNames {
+		if n == name {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid priority: %s (must be low, medium, high or urgent)", name)
+}
+
+func (p Priority) String() string {
+	return priorityNames[p]
+}
+
+func (p Priority) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.String())
+}
+
+func (p *Priority) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	// 空文字は未指定として扱う
+	if name == "" {
+		*p = 0
+		return nil
+	}
+	parsed, err := ParsePriority(name)
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
+
+// TaskProgress サブタスクの進捗（直下の子タスクを集計）
+type TaskProgress struct {
+	Completed int64 `json:"completed"`
+	Total     int64 `json:"total"`
+}
+
 type Task struct {
-	ID          uint           `gorm:"primaryKey" json:"id"`
-	Title       string         `gorm:"size:200;not null" json:"title"`
-	Description string         `gorm:"type:text" json:"description"`
-	Status      string         `gorm:"size:20;default:'pending'" json:"status"` // pending, in_progress, completed
-	UserID      uint           `gorm:"not null" json:"user_id"`
-	User        User           `gorm:"foreignKey:UserID" json:"user,omitempty"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	ID               uint           `gorm:"primaryKey" json:"id"`
+	Title            string         `gorm:"size:200;not null" json:"title"`
+	Description      string         `gorm:"type:text" json:"description"`
+	Status           string         `gorm:"size:20;default:'pending'" json:"status"` // pending, in_progress, completed
+	Priority         Priority       `gorm:"default:2;index" json:"priority"`
+	Rank             string         `gorm:"size:255;index" json:"rank"` // ステータス列内での並び順（辞書順で比較）
+	Version          int            `gorm:"not null;default:1" json:"version"` // 楽観的排他制御用（更新のたびに増える）
+	StartAt          *time.Time     `gorm:"index" json:"start_at"`
+	DueAt            *time.Time     `gorm:"index" json:"due_at"`
+	Recurrence       string         `gorm:"size:255" json:"recurrence"` // RRULE（FREQ=DAILY/WEEKLY/MONTHLY のサブセット）
+	TimeZone         string         `gorm:"size:64" json:"time_zone"`   // 繰り返しを展開するタイムゾーン（空は UTC）
+	SeriesID         *uint          `gorm:"index" json:"series_id"`     // 繰り返しシリーズの最初のタスク
+	OccurrenceIndex  int            `json:"occurrence_index"`           // シリーズ内での発生回数（1始まり）
+	EstimatedMinutes *int           `json:"estimated_minutes"`          // 見積もり時間（nil は未見積もり）
+	LoggedMinutes    int64          `gorm:"-" json:"logged_minutes"`    // 記録済みの作業時間の合計
+	PendingAt        *time.Time     `json:"pending_at"`                 // 各ステータスに最後に入った日時
+	InProgressAt     *time.Time     `json:"in_progress_at"`
+	CompletedAt      *time.Time     `json:"completed_at"`
+	Overdue          bool           `gorm:"-" json:"overdue"`
+	IsBlocked        bool           `gorm:"-" json:"is_blocked"`
+	CommentCount     int64          `gorm:"-" json:"comment_count"`
+	ParentID         *uint          `gorm:"index" json:"parent_id"`
+	ProjectID        *uint          `gorm:"index" json:"project_id"`
+	Progress         *TaskProgress  `gorm:"-" json:"progress,omitempty"`
+	CustomFields     FieldValues    `gorm:"-" json:"custom_fields,omitempty"` // プロジェクトのカスタムフィールドの値
+	OrganizationID   *uint          `gorm:"index" json:"organization_id"`
+	UserID           uint           `gorm:"not null" json:"user_id"`
+	User             User           `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	CreatorID        uint           `gorm:"index" json:"creator_id"` // タスクを作成したユーザー（削除権限の判定に使う）
+	ExternalID       string         `gorm:"size:255;index" json:"external_id"` // インポート元での ID（所有者ごとに upsert のキーになる）
+	Labels           []Label        `gorm:"many2many:task_labels;" json:"labels,omitempty"`
+	Assignees        []User         `gorm:"many2many:task_assignees;" json:"assignees,omitempty"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// Validate タスクの日付と繰り返しルールの整合性を検証
+func (t *Task) Validate() error {
+	if t.StartAt != nil && t.DueAt != nil && !t.StartAt.Before(*t.DueAt) {
+		return ErrStartAfterDue
+	}
+	if t.EstimatedMinutes != nil && *t.EstimatedMinutes < 0 {
+		return ErrNegativeEstimate
+	}
+	if _, err := time.LoadLocation(t.TimeZone); err != nil {
+		return ErrInvalidTimeZone
+	}
+	if t.Recurrence != "" {
+		rule, err := utils.ParseRRule(t.Recurrence)
+		if err != nil {
+			return err
+		}
+		if t.DueAt == nil {
+			return ErrRecurrenceWithoutDue
+		}
+		t.Recurrence = rule.String()
+	}
+	return nil
+}
+
+// IsOverdue 期限を過ぎていて未完了かどうか
+func (t *Task) IsOverdue(now time.Time) bool {
+	return t.DueAt != nil && t.DueAt.Before(now) && t.Status != StatusCompleted
+}
+
+// BeforeCreate 優先度が未指定の場合は medium、発生回数とバージョンは1、作成者は所有者にする
+func (t *Task) BeforeCreate(tx *gorm.DB) error {
+	if t.CreatorID == 0 {
+		t.CreatorID = t.UserID
+	}
+	if t.Priority == 0 {
+		t.Priority = PriorityMedium
+	}
+	if t.OccurrenceIndex == 0 {
+		t.OccurrenceIndex = 1
+	}
+	if t.Version == 0 {
+		t.Version = 1
+	}
+	return nil
+}
+
+// AfterFind 取得後に期限切れフラグを設定
+func (t *Task) AfterFind(tx *gorm.DB) error {
+	t.Overdue = t.IsOverdue(time.Now())
+	return nil
+}
+
+// AfterSave 保存後に期限切れフラグを設定
+func (t *Task) AfterSave(tx *gorm.DB) error {
+	t.Overdue = t.IsOverdue(time.Now())
+	return nil
 }
@@ -0,0 +1,13 @@
+package models
+
+import (
+	"time"
+)
+
+// TaskDependency タスク間の依存関係（TaskID のタスクは BlockedByID のタスクの完了待ち）
+type TaskDependency struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	TaskID      uint      `gorm:"not null;uniqueIndex:idx_task_dependencies_pair" json:"task_id"`
+	BlockedByID uint      `gorm:"not null;uniqueIndex:idx_task_dependencies_pair;index" json:"blocked_by_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+)
+
+// タスクの履歴イベントの種別
+const (
+	EventCreated    = "created"
+	EventUpdated    = "updated"
+	EventDeleted    = "deleted"
+	EventRestored   = "restored"
+	EventAssigned   = "assigned"
+	EventUnassigned = "unassigned"
+	EventCommented  = "commented"
+)
+
+// FieldChange 項目の変更前後の値
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// TaskEvent タスクに対する操作の履歴
+type TaskEvent struct {
+	ID        uint                   `gorm:"primaryKey" json:"id"`
+	TaskID    uint                   `gorm:"not null;index" json:"task_id"`
+	ActorID   *uint                  `gorm:"index" json:"actor_id"` // nil は他の操作に伴う自動的な変更
+	Actor     *User                  `gorm:"foreignKey:ActorID" json:"actor,omitempty"`
+	Type      string                 `gorm:"size:20;not null" json:"type"`
+	Changes   map[string]FieldChange `gorm:"type:text;serializer:json" json:"changes,omitempty"`
+	CommentID *uint                  `json:"comment_id,omitempty"`
+	CreatedAt time.Time              `gorm:"index" json:"created_at"`
+}
+
+// DiffTasks 履歴に残す項目について変更前後の差分を返す（変更がなければ空）
+func DiffTasks(before, after *Task) map[string]FieldChange {
+	changes := make(map[string]FieldChange)
+	add := func(field string, from, to interface{}) {
+		if from != to {
+			changes[field] = FieldChange{From: from, To: to}
+		}
+	}
+	add("title", before.Title, after.Title)
+	add("description", before.Description, after.Description)
+	add("status", before.Status, after.Status)
+	add("priority", before.Priority, after.Priority)
+	add("start_at", timeValue(before.StartAt), timeValue(after.StartAt))
+	add("due_at", timeValue(before.DueAt), timeValue(after.DueAt))
+	add("recurrence", before.Recurrence, after.Recurrence)
+	add("time_zone", before.TimeZone, after.TimeZone)
+	add("parent_id", idValue(before.ParentID), idValue(after.ParentID))
+	add("project_id", idValue(before.ProjectID), idValue(after.ProjectID))
+	add("estimated_minutes", intValue(before.EstimatedMinutes), intValue(after.EstimatedMinutes))
+	return changes
+}
+
+// timeValue 比較できるように日時を UTC の文字列にする（nil はそのまま）
+func timeValue(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func idValue(id *uint) interface{} {
+	if id == nil {
+		return nil
+	}
+	return *id
+}
+
+func intValue(n *int) interface{} {
+	if n == nil {
+		return nil
+	}
+	return *n
+}
@@ -0,0 +1,152 @@
+package models
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// タスクのステータス
+const (
+	StatusPending    = "pending"
+	StatusInProgress = "in_progress"
+	StatusCompleted  = "completed"
+)
+
+// TaskStatuses 有効なステータスの一覧
+var TaskStatuses = []string{StatusPending, StatusInProgress, StatusCompleted}
+
+// defaultStatusTransitions デフォルトの遷移グラフ（完了後は進行中への差し戻しのみ）
+var defaultStatusTransitions = map[string][]string{
+	StatusPending:    {StatusInProgress, StatusCompleted},
+	StatusInProgress: {StatusPending, StatusCompleted},
+	StatusCompleted:  {StatusInProgress},
+}
+
+// StatusTransitionError 許可されていないステータス、またはステータス遷移
+type StatusTransitionError struct {
+	From    string
+	To      string
+	Allowed []string
+}
+
+func (e *StatusTransitionError) Error() string {
+	if e.From == "" {
+		return fmt.Sprintf("invalid status: %s", e.To)
+	}
+	return fmt.Sprintf("cannot change status from %s to %s", e.From, e.To)
+}
+
+// IsValidStatus 定義済みのステータスかどうか
+func IsValidStatus(status string) bool {
+	for _, s := range TaskStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseStatusTransitions "pending:in_progress,completed;completed:in_progress" 形式の遷移グラフを解釈
+func ParseStatusTransitions(spec string) (map[string][]string, error) {
+	graph := make(map[string][]string)
+	for _, rule := range strings.Split(spec, ";") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		from, targets, ok := strings.Cut(rule, ":")
+		from = strings.TrimSpace(from)
+		if !ok || !IsValidStatus(from) {
+			return nil, fmt.Errorf("invalid transition rule: %s", rule)
+		}
+		graph[from] = []string{}
+		for _, to := range strings.Split(targets, ",") {
+			to = strings.TrimSpace(to)
+			if to == "" {
+				continue
+			}
+			if !IsValidStatus(to) {
+				return nil, fmt.Errorf("invalid transition rule: %s", rule)
+			}
+			graph[from] = append(graph[from], to)
+		}
+	}
+	return graph, nil
+}
+
+// statusTransitions 起動時に読み込んだ遷移グラフ
+var statusTransitions = defaultStatusTransitions
+
+// LoadStatusTransitions TASK_STATUS_TRANSITIONS から遷移グラフを読み込む（起動時に一度だけ呼ぶ）
+// 未設定または不正な場合はデフォルトの遷移グラフを使う
+func LoadStatusTransitions() {
+	statusTransitions = defaultStatusTransitions
+	spec := os.Getenv("TASK_STATUS_TRANSITIONS")
+	if spec == "" {
+		return
+	}
+	graph, err := ParseStatusTransitions(spec)
+	if err != nil {
+		log.Printf("TASK_STATUS_TRANSITIONS is invalid, using defaults: %v", err)
+		return
+	}
+	statusTransitions = graph
+}
+
+// StatusTransitions 現在の遷移グラフを返す
+func StatusTransitions() map[string][]string {
+	return statusTransitions
+}
+
+// CheckStatusTransition from から to への遷移が許可されているか検証
+// from が空の場合は新規作成として任意の有効なステータスを許可する。
+// 遷移グラフに無いステータスからは、取り残されないよう初期ステータス（pending）へ戻せる
+func CheckStatusTransition(from, to string) error {
+	if from == "" {
+		if !IsValidStatus(to) {
+			return &StatusTransitionError{To: to, Allowed: TaskStatuses}
+		}
+		return nil
+	}
+	if from == to {
+		return nil
+	}
+	allowed, ok := StatusTransitions()[from]
+	if !ok {
+		allowed = []string{StatusPending}
+	}
+	for _, s := range allowed {
+		if s == to {
+			return nil
+		}
+	}
+	if allowed == nil {
+		allowed = []string{}
+	}
+	return &StatusTransitionError{From: from, To: to, Allowed: allowed}
+}
+
+// SetStatus ステータスを遷移させ、そのステータスに入った日時を記録
+// 現在のステータスが空の場合は新規作成として扱う
+func (t *Task) SetStatus(status string, now time.Time) error {
+	from := t.Status
+	if err := CheckStatusTransition(from, status); err != nil {
+		return err
+	}
+	if from == status {
+		return nil
+	}
+	t.Status = status
+	switch status {
+	case StatusPending:
+		t.PendingAt = &now
+	case StatusInProgress:
+		t.InProgressAt = &now
+	case StatusCompleted:
+		t.CompletedAt = &now
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+    "testing"
+    "time"
+)
+
+func TestTaskValidate_StartBeforeDue(t *testing.T) {
+    now := time.Now()
+    later := now.Add(time.Hour)
+
+    if err := (&Task{StartAt: &now, DueAt: &later}).Validate(); err != nil { t.Fatalf("unexpected error: %v", err) }
+    if err := (&Task{StartAt: &later, DueAt: &now}).Validate(); err != ErrStartAfterDue { t.Fatalf("expected ErrStartAfterDue, got %v", err) }
+    if err := (&Task{DueAt: &now}).Validate(); err != nil { t.Fatalf("unexpected error: %v", err) }
+}
+
+func TestTaskIsOverdue(t *testing.T) {
+    now := time.Now()
+    past := now.Add(-time.Hour)
+
+    if !(&Task{DueAt: &past, Status: "pending"}).IsOverdue(now) { t.Fatal("expected overdue") }
+    if (&Task{DueAt: &past, Status: "completed"}).IsOverdue(now) { t.Fatal("completed task should not be overdue") }
+    if (&Task{Status: "pending"}).IsOverdue(now) { t.Fatal("task without due date should not be overdue") }
+}
+
+func TestDiffTasks(t *testing.T) {
+    due := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+    sameDue := due.In(time.FixedZone("JST", 9*60*60))
+    project := uint(3)
+
+    before := Task{Title: "Old", Status: StatusPending, Priority: PriorityLow, DueAt: &due}
+    after := Task{Title: "New", Status: StatusPending, Priority: PriorityHigh, DueAt: &sameDue, ProjectID: &project}
+
+    changes := DiffTasks(&before, &after)
+    if len(changes) != 3 { t.Fatalf("expected 3 changes, got %+v", changes) }
+    if c := changes["title"]; c.From != "Old" || c.To != "New" { t.Fatalf("unexpected title change: %+v", c) }
+    if c := changes["priority"]; c.From != PriorityLow || c.To != PriorityHigh { t.Fatalf("unexpected priority change: %+v", c) }
+    if c := changes["project_id"]; c.From != nil || c.To != uint(3) { t.Fatalf("unexpected project change: %+v", c) }
+    // 同じ時刻をタイムゾーン違いで表しただけなら変更とみなさない
+    if _, ok := changes["due_at"]; ok { t.Fatal("expected due_at to be unchanged") }
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// ErrEntryEndsBeforeStart 作業時間の終了が開始より前になっている
+var ErrEntryEndsBeforeStart = errors.New("ended_at must not be before started_at")
+
+// TimeEntry タスクに記録した作業時間
+type TimeEntry struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	TaskID    uint       `gorm:"not null;index" json:"task_id"`
+	UserID    uint       `gorm:"not null;index;uniqueIndex:idx_time_entries_running,where:ended_at IS NULL" json:"user_id"` // 計測中のタイマーはユーザーごとに1つまで
+	User      *User      `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	StartedAt time.Time  `gorm:"not null;index" json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at"`                          // nil はタイマーで計測中
+	Minutes   int        `gorm:"not null;default:0" json:"minutes"` // 終了時に確定する
+	Note      string     `gorm:"size:500" json:"note"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Running タイマーで計測中かどうか
+func (e *TimeEntry) Running() bool {
+	return e.EndedAt == nil
+}
+
+// Stop 終了日時を記録し、作業時間を分単位（四捨五入）で確定する
+func (e *TimeEntry) Stop(at time.Time) error {
+	if at.Before(e.StartedAt) {
+		return ErrEntryEndsBeforeStart
+	}
+	e.EndedAt = &at
+	e.Minutes = int(math.Round(at.Sub(e.StartedAt).Minutes()))
+	return nil
+}
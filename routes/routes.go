@@ -4,17 +4,23 @@ import (
     "flux/handlers"
     "flux/mailer"
     "flux/middleware"
+    "flux/storage"
 
     "github.com/gin-gonic/gin"
     "gorm.io/gorm"
 )
 
-func SetupRoutes(r *gin.Engine, db *gorm.DB, mailer mailer.Mailer) {
+func SetupRoutes(r *gin.Engine, db *gorm.DB, mailer mailer.Mailer, store storage.Storage) {
     // ヘルスチェック
     r.GET("/health", func(c *gin.Context) {
         c.JSON(200, gin.H{"status": "ok"})
     })
 
+    // メンション通知メールの送信に使う
+    handlers.SetMailer(mailer)
+    // 添付ファイルの保存先
+    handlers.SetStorage(store)
+
     v1 := r.Group("/api/v1")
     {
         // 認証関連のルート
@@ -32,18 +38,120 @@ func SetupRoutes(r *gin.Engine, db *gorm.DB, mailer mailer.Mailer) {
         }
 
         // tasks
-        v1.GET("/tasks", handlers.GetTasks)
-        v1.GET("/tasks/:id", handlers.GetTask)
+        // 組織のタスクはメンバーにのみ見えるため、トークンがあれば読み取る
+        v1.GET("/tasks", middleware.OptionalAuthMiddleware(), handlers.GetTasks)
+        v1.GET("/tasks/assigned", middleware.AuthMiddleware(), handlers.GetAssignedTasks)
+        v1.GET("/tasks/trash", middleware.AuthMiddleware(), handlers.GetTrashedTasks)
+        v1.GET("/tasks/export", middleware.AuthMiddleware(), handlers.ExportTasks)
+        v1.GET("/tasks/:id", middleware.OptionalAuthMiddleware(), handlers.GetTask)
+        v1.GET("/tasks/:id/subtasks", middleware.OptionalAuthMiddleware(), handlers.GetSubtasks)
+        v1.GET("/tasks/:id/dependencies", middleware.OptionalAuthMiddleware(), handlers.GetTaskDependencies)
+        v1.GET("/tasks/:id/comments", middleware.OptionalAuthMiddleware(), handlers.GetTaskComments)
+        v1.GET("/tasks/:id/history", middleware.OptionalAuthMiddleware(), handlers.GetTaskHistory)
+        v1.GET("/tasks/:id/attachments", middleware.OptionalAuthMiddleware(), handlers.GetTaskAttachments)
+        v1.GET("/tasks/:id/attachments/:attachment_id/download", middleware.OptionalAuthMiddleware(), handlers.DownloadTaskAttachment)
+        v1.GET("/tasks/:id/fields", middleware.OptionalAuthMiddleware(), handlers.GetTaskFields)
         v1.POST("/tasks", middleware.AuthMiddleware(), handlers.CreateTask)
+        v1.POST("/tasks/bulk", middleware.AuthMiddleware(), handlers.BulkTasks)
+        v1.POST("/tasks/import", middleware.AuthMiddleware(), handlers.ImportTasks)
+        v1.POST("/tasks/import/:source", middleware.AuthMiddleware(), handlers.ImportFromSource)
         v1.PUT("/tasks/:id", middleware.AuthMiddleware(), handlers.UpdateTask)
+        v1.PATCH("/tasks/:id", middleware.AuthMiddleware(), handlers.PatchTask)
         v1.DELETE("/tasks/:id", middleware.AuthMiddleware(), handlers.DeleteTask)
+        v1.POST("/tasks/:id/restore", middleware.AuthMiddleware(), handlers.RestoreTask)
+        v1.DELETE("/tasks/:id/purge", middleware.AuthMiddleware(), handlers.PurgeTask)
+        v1.POST("/tasks/:id/move", middleware.AuthMiddleware(), handlers.MoveTask)
+        v1.POST("/tasks/:id/labels", middleware.AuthMiddleware(), handlers.AttachTaskLabels)
+        v1.DELETE("/tasks/:id/labels/:label_id", middleware.AuthMiddleware(), handlers.DetachTaskLabel)
+        v1.POST("/tasks/:id/assignees", middleware.AuthMiddleware(), handlers.AssignTask)
+        v1.DELETE("/tasks/:id/assignees/:user_id", middleware.AuthMiddleware(), handlers.UnassignTask)
+        v1.POST("/tasks/:id/comments", middleware.AuthMiddleware(), handlers.CreateTaskComment)
+        v1.PUT("/tasks/:id/comments/:comment_id", middleware.AuthMiddleware(), handlers.UpdateTaskComment)
+        v1.DELETE("/tasks/:id/comments/:comment_id", middleware.AuthMiddleware(), handlers.DeleteTaskComment)
+        v1.POST("/tasks/:id/attachments", middleware.AuthMiddleware(), handlers.UploadTaskAttachment)
+        v1.DELETE("/tasks/:id/attachments/:attachment_id", middleware.AuthMiddleware(), handlers.DeleteTaskAttachment)
+        v1.POST("/tasks/:id/blocked-by", middleware.AuthMiddleware(), handlers.AddBlockedBy)
+        v1.DELETE("/tasks/:id/blocked-by/:other_id", middleware.AuthMiddleware(), handlers.RemoveBlockedBy)
+        v1.POST("/tasks/:id/blocks", middleware.AuthMiddleware(), handlers.AddBlocks)
+        v1.DELETE("/tasks/:id/blocks/:other_id", middleware.AuthMiddleware(), handlers.RemoveBlocks)
+        v1.GET("/tasks/:id/time-entries", middleware.AuthMiddleware(), handlers.GetTaskTimeEntries)
+        v1.POST("/tasks/:id/time-entries", middleware.AuthMiddleware(), handlers.CreateTaskTimeEntry)
+        v1.PUT("/tasks/:id/time-entries/:entry_id", middleware.AuthMiddleware(), handlers.UpdateTaskTimeEntry)
+        v1.DELETE("/tasks/:id/time-entries/:entry_id", middleware.AuthMiddleware(), handlers.DeleteTaskTimeEntry)
+        v1.POST("/tasks/:id/timer/start", middleware.AuthMiddleware(), handlers.StartTaskTimer)
+        v1.POST("/tasks/:id/timer/stop", middleware.AuthMiddleware(), handlers.StopTaskTimer)
+        v1.PATCH("/tasks/:id/fields", middleware.AuthMiddleware(), handlers.UpdateTaskFields)
+
+        // time tracking
+        v1.GET("/timer", middleware.AuthMiddleware(), handlers.GetRunningTimer)
+        v1.POST("/timer/stop", middleware.AuthMiddleware(), handlers.StopRunningTimer)
+        v1.DELETE("/timer", middleware.AuthMiddleware(), handlers.DiscardRunningTimer)
+        v1.GET("/time-entries/report", middleware.AuthMiddleware(), handlers.GetTimeReport)
+
+        // projects
+        projects := v1.Group("/projects", middleware.AuthMiddleware())
+        {
+            projects.GET("", handlers.GetProjects)
+            projects.GET("/:id", handlers.GetProject)
+            projects.POST("", handlers.CreateProject)
+            projects.PUT("/:id", handlers.UpdateProject)
+            projects.DELETE("/:id", handlers.DeleteProject)
+            projects.GET("/:id/tasks", handlers.GetProjectTasks)
+            projects.GET("/:id/fields", handlers.GetProjectFields)
+            projects.POST("/:id/fields", handlers.CreateProjectField)
+            projects.PUT("/:id/fields/:field_id", handlers.UpdateProjectField)
+            projects.DELETE("/:id/fields/:field_id", handlers.DeleteProjectField)
+        }
+
+        // organizations
+        organizations := v1.Group("/organizations", middleware.AuthMiddleware())
+        {
+            organizations.GET("", handlers.GetOrganizations)
+            organizations.GET("/:id", handlers.GetOrganization)
+            organizations.POST("", handlers.CreateOrganization)
+            organizations.PUT("/:id", handlers.UpdateOrganization)
+            organizations.DELETE("/:id", handlers.DeleteOrganization)
+            organizations.GET("/:id/members", handlers.GetOrganizationMembers)
+            organizations.POST("/:id/members", handlers.AddOrganizationMember)
+            organizations.PUT("/:id/members/:user_id", handlers.UpdateOrganizationMember)
+            organizations.DELETE("/:id/members/:user_id", handlers.RemoveOrganizationMember)
+            organizations.GET("/:id/tasks", handlers.GetOrganizationTasks)
+        }
+
+        // notifications
+        notifications := v1.Group("/notifications", middleware.AuthMiddleware())
+        {
+            notifications.GET("", handlers.GetNotifications)
+            notifications.POST("/:id/read", handlers.MarkNotificationRead)
+            notifications.POST("/read-all", handlers.MarkAllNotificationsRead)
+        }
+
+        // labels
+        labels := v1.Group("/labels", middleware.AuthMiddleware())
+        {
+            labels.GET("", handlers.GetLabels)
+            labels.POST("", handlers.CreateLabel)
+            labels.PUT("/:id", handlers.UpdateLabel)
+            labels.DELETE("/:id", handlers.DeleteLabel)
+        }
+
+        // calendar
+        v1.GET("/calendar/feed", middleware.AuthMiddleware(), handlers.GetCalendarFeed)
+        v1.POST("/calendar/feed/regenerate", middleware.AuthMiddleware(), handlers.RegenerateCalendarFeed)
+        // フィードはカレンダーアプリから購読するため、URL のトークンだけで認証する
+        v1.GET("/calendar/:token", handlers.ServeCalendarFeed)
 
         // users
         v1.GET("/users", handlers.GetUsers)
-        v1.GET("/users/:id", handlers.GetUser)
+        v1.GET("/users/trash", middleware.AuthMiddleware(), handlers.GetTrashedUsers)
+        v1.GET("/users/:id", middleware.OptionalAuthMiddleware(), handlers.GetUser)
         v1.POST("/users", handlers.CreateUser)
-        v1.PUT("/users/:id", handlers.UpdateUser)
-        v1.DELETE("/users/:id", handlers.DeleteUser)
+        // 変更・削除・復元・完全削除は本人のアカウントに限る
+        v1.PUT("/users/:id", middleware.AuthMiddleware(), handlers.UpdateUser)
+        v1.PATCH("/users/:id", middleware.AuthMiddleware(), handlers.PatchUser)
+        v1.DELETE("/users/:id", middleware.AuthMiddleware(), handlers.DeleteUser)
+        v1.POST("/users/:id/restore", middleware.AuthMiddleware(), handlers.RestoreUser)
+        v1.DELETE("/users/:id/purge", middleware.AuthMiddleware(), handlers.PurgeUser)
         v1.GET("/users/:id/tasks", handlers.GetTasksByUser)
     }
 }
\ No newline at end of file
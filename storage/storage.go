@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrInvalidKey キーが保存先の外を指している
+var ErrInvalidKey = errors.New("invalid storage key")
+
+// ErrNotFound キーに対応するファイルが存在しない
+var ErrNotFound = errors.New("file not found")
+
+// Storage は添付ファイルの保存先のインターフェースを定義します
+// キーは "tasks/1/abc" のようなスラッシュ区切りの相対パスです
+type Storage interface {
+	Save(key string, r io.Reader) (int64, error)
+	Open(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+// FSStorage はローカルファイルシステムに保存します
+type FSStorage struct {
+	root string
+}
+
+func NewFSStorage(root string) *FSStorage {
+	return &FSStorage{root: root}
+}
+
+func (s *FSStorage) Save(key string, r io.Reader) (int64, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return 0, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		// 書き込み途中のファイルを残さない
+		os.Remove(path)
+		return 0, err
+	}
+	return n, nil
+}
+
+func (s *FSStorage) Open(key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+func (s *FSStorage) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path キーを保存先ディレクトリ配下のパスに変換する
+func (s *FSStorage) path(key string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(key))
+	if key == "" || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(s.root, clean), nil
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestFSStorage_SaveOpenDelete(t *testing.T) {
+	s := NewFSStorage(t.TempDir())
+
+	n, err := s.Save("tasks/1/file", strings.NewReader("hello"))
+	if err != nil || n != 5 {
+		t.Fatalf("Save = %d, %v", n, err)
+	}
+	if _, err := s.Save("tasks/1/file", strings.NewReader("again")); err == nil {
+		t.Fatal("expected existing key not to be overwritten")
+	}
+
+	r, err := s.Open("tasks/1/file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(r)
+	r.Close()
+	if string(data) != "hello" {
+		t.Fatalf("unexpected content %q", data)
+	}
+
+	if err := s.Delete("tasks/1/file"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Open("tasks/1/file"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	// 存在しないキーの削除はエラーにしない
+	if err := s.Delete("tasks/1/file"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFSStorage_RejectsKeysOutsideRoot(t *testing.T) {
+	s := NewFSStorage(t.TempDir())
+	for _, key := range []string{"", "../escape", "/etc/passwd", "a/../../b"} {
+		if _, err := s.Save(key, strings.NewReader("x")); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Save(%q) = %v, want ErrInvalidKey", key, err)
+		}
+	}
+}
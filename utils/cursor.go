@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+)
+
+// ErrInvalidCursor 改ざんされた、または不正な形式のカーソル
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// EncodeCursor ページングカーソルを署名付きの不透明な文字列にエンコード
+func EncodeCursor(payload interface{}) (string, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	body := base64.RawURLEncoding.EncodeToString(data)
+	return body + "." + signCursor(body), nil
+}
+
+// DecodeCursor カーソルの署名を検証し、payload にデコード
+func DecodeCursor(cursor string, payload interface{}) error {
+	body, sig, ok := strings.Cut(cursor, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(signCursor(body))) {
+		return ErrInvalidCursor
+	}
+	data, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, payload); err != nil {
+		return ErrInvalidCursor
+	}
+	return nil
+}
+
+func signCursor(body string) string {
+	mac := hmac.New(sha256.New, getCursorSecret())
+	mac.Write([]byte(body))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// getCursorSecret カーソル署名用のシークレットを取得（未設定時はJWTシークレットを流用）
+func getCursorSecret() []byte {
+	if secret := os.Getenv("CURSOR_SECRET"); secret != "" {
+		return []byte(secret)
+	}
+	return []byte(getJWTSecret())
+}
@@ -0,0 +1,36 @@
+package utils
+
+import (
+    "strings"
+    "testing"
+)
+
+type testCursor struct {
+    Value string `json:"v"`
+    ID    uint   `json:"id"`
+}
+
+func TestEncodeAndDecodeCursor(t *testing.T) {
+    cur, err := EncodeCursor(testCursor{Value: "2025-01-01", ID: 42})
+    if err != nil || cur == "" { t.Fatalf("failed to encode cursor: %v", err) }
+
+    var got testCursor
+    if err := DecodeCursor(cur, &got); err != nil { t.Fatalf("failed to decode cursor: %v", err) }
+    if got.Value != "2025-01-01" || got.ID != 42 { t.Fatalf("unexpected payload: %+v", got) }
+}
+
+func TestDecodeCursor_Tampered(t *testing.T) {
+    cur, _ := EncodeCursor(testCursor{ID: 1})
+    other, _ := EncodeCursor(testCursor{ID: 2})
+
+    // 別カーソルの本文と署名を組み合わせる
+    body, _, _ := strings.Cut(other, ".")
+    _, sig, _ := strings.Cut(cur, ".")
+    tampered := body + "." + sig
+    var got testCursor
+    for _, c := range []string{"", "garbage", "abc.def", tampered} {
+        if err := DecodeCursor(c, &got); err != ErrInvalidCursor {
+            t.Fatalf("expected ErrInvalidCursor for %q, got %v", c, err)
+        }
+    }
+}
@@ -0,0 +1,6 @@
+package utils
+
+// GetEnvInt 正の整数の環境変数を取得（未設定・不正な場合はデフォルト値）
+func GetEnvInt(name string, def int) int {
+	return getEnvInt(name, def)
+}
@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// icalLineLimit 折り返す前の1行の最大オクテット数（RFC 5545 3.1）
+const icalLineLimit = 75
+
+// icalTextEscaper TEXT 型の値でエスケープが必要な文字
+var icalTextEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// ICalWriter は RFC 5545 の iCalendar を1行ずつ書き出します。
+// 長い行は折り返し、行末は CRLF にします。最初に起きたエラーは Err で確認できます。
+type ICalWriter struct {
+	w   io.Writer
+	err error
+}
+
+// NewICalWriter は w に書き出す ICalWriter を作成します
+func NewICalWriter(w io.Writer) *ICalWriter {
+	return &ICalWriter{w: w}
+}
+
+// Line は値をそのまま書き出します（エスケープ済みの値や RRULE などに使います）
+func (iw *ICalWriter) Line(name, value string) {
+	if iw.err != nil {
+		return
+	}
+	_, iw.err = io.WriteString(iw.w, FoldICalLine(name+":"+value))
+}
+
+// Text は TEXT 型の値をエスケープして書き出します
+func (iw *ICalWriter) Text(name, value string) {
+	iw.Line(name, EscapeICalText(value))
+}
+
+// Time は日時を UTC の DATE-TIME 形式で書き出します
+func (iw *ICalWriter) Time(name string, t time.Time) {
+	iw.Line(name, FormatICalTime(t))
+}
+
+// Err は書き出し中に起きた最初のエラーを返します
+func (iw *ICalWriter) Err() error {
+	return iw.err
+}
+
+// EscapeICalText は TEXT 型の値のバックスラッシュ・セミコロン・カンマ・改行をエスケープします
+func EscapeICalText(s string) string {
+	return icalTextEscaper.Replace(s)
+}
+
+// FormatICalTime は日時を UTC の DATE-TIME 形式（20060102T150405Z）にします
+func FormatICalTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// FoldICalLine は75オクテットを超える行を折り返し、CRLF を付けて返します。
+// マルチバイト文字の途中では折り返しません。
+func FoldICalLine(line string) string {
+	var b strings.Builder
+	limit := icalLineLimit
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// 続きの行は先頭の空白の分だけ短くする
+		limit = icalLineLimit - 1
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+	return b.String()
+}
@@ -0,0 +1,41 @@
+package utils
+
+import (
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestEscapeICalText(t *testing.T) {
+    got := EscapeICalText("a\\b; c, d\r\ne\nf")
+    if got != `a\\b\; c\, d\ne\nf` { t.Fatalf("unexpected: %s", got) }
+}
+
+func TestFoldICalLine(t *testing.T) {
+    line := "SUMMARY:" + strings.Repeat("a", 100)
+    folded := FoldICalLine(line)
+    parts := strings.Split(strings.TrimSuffix(folded, "\r\n"), "\r\n")
+    if len(parts) != 2 || len(parts[0]) != 75 || !strings.HasPrefix(parts[1], " ") || len(parts[1]) > 75 { t.Fatalf("unexpected fold: %q", folded) }
+    if strings.ReplaceAll(strings.TrimSuffix(folded, "\r\n"), "\r\n ", "") != line { t.Fatalf("unfolded mismatch: %q", folded) }
+
+    // マルチバイト文字の途中で折り返さない
+    jp := "SUMMARY:" + strings.Repeat("あ", 40)
+    for _, part := range strings.Split(strings.TrimSuffix(FoldICalLine(jp), "\r\n"), "\r\n") {
+        if len(part) > 75 { t.Fatalf("line too long: %d", len(part)) }
+        if !strings.HasPrefix(part, "SUMMARY:") && !strings.HasPrefix(part, " あ") { t.Fatalf("split inside a rune: %q", part) }
+    }
+
+    if got := FoldICalLine("VERSION:2.0"); got != "VERSION:2.0\r\n" { t.Fatalf("unexpected: %q", got) }
+}
+
+func TestICalWriter(t *testing.T) {
+    var b strings.Builder
+    iw := NewICalWriter(&b)
+    iw.Line("BEGIN", "VTODO")
+    iw.Text("SUMMARY", "Pay rent, utilities")
+    iw.Time("DUE", time.Date(2026, 3, 1, 18, 0, 0, 0, time.FixedZone("JST", 9*3600)))
+    iw.Line("END", "VTODO")
+    if iw.Err() != nil { t.Fatal(iw.Err()) }
+    want := "BEGIN:VTODO\r\nSUMMARY:Pay rent\\, utilities\r\nDUE:20260301T090000Z\r\nEND:VTODO\r\n"
+    if b.String() != want { t.Fatalf("unexpected output: %q", b.String()) }
+}
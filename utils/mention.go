@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// mentionPattern 行頭または空白・記号の直後の @handle（@name または @user@example.com）
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@.])@([\w.+-]+(?:@[\w-]+(?:\.[\w-]+)+)?)`)
+
+// ExtractMentions は本文中の @メンションを出現順・重複なし・小文字で返します
+func ExtractMentions(text string) []string {
+	var handles []string
+	seen := make(map[string]bool)
+	for _, m := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		// 文末の句読点はメンションに含めない
+		handle := strings.ToLower(strings.TrimRight(m[1], ".-"))
+		if handle == "" || seen[handle] {
+			continue
+		}
+		seen[handle] = true
+		handles = append(handles, handle)
+	}
+	return handles
+}
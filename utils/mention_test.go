@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractMentions(t *testing.T) {
+	cases := []struct {
+		text string
+		want []string
+	}{
+		{"@alice please review", []string{"alice"}},
+		{"thanks @Bob.", []string{"bob"}},
+		{"cc @alice, @bob and @alice again", []string{"alice", "bob"}},
+		{"ping @carol@example.com", []string{"carol@example.com"}},
+		{"mail bob@example.com directly", nil},
+		{"(@dave)", []string{"dave"}},
+		{"no mentions here", nil},
+	}
+	for _, tc := range cases {
+		if got := ExtractMentions(tc.text); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("ExtractMentions(%q) = %v, want %v", tc.text, got, tc.want)
+		}
+	}
+}
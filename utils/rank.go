@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+)
+
+// rankDigits ランクに使う文字（ASCII順とDBの照合順序が一致するよう数字と小文字のみ）
+const rankDigits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+// ErrInvalidRank 不正なランク、または前後関係が逆のランク
+var ErrInvalidRank = errors.New("invalid rank")
+
+// rankWidth 末尾への追加と振り直しで使う固定長ランクの桁数
+const rankWidth = 6
+
+// rankStep 末尾に追加するときの間隔（間に挿入できる余地を残す）
+const rankStep = 36 * 36 * 36
+
+// rankSpace 固定長ランクで表せる値の数
+const rankSpace = 36 * 36 * 36 * 36 * 36 * 36
+
+// ErrRankExhausted 固定長のランクでは末尾に追加する余地が無い（列の振り直しが必要）
+var ErrRankExhausted = errors.New("no room for another rank")
+
+// RankAfter a の後ろに並ぶランク文字列を生成します（a が空の場合は最初のランク）
+// 中間を取り続けると桁が増えるため、a の先頭の固定長部分に一定の間隔を足す
+func RankAfter(a string) (string, error) {
+	if !validRank(a) {
+		return "", ErrInvalidRank
+	}
+	n := rankValue(a) + rankStep
+	if n >= rankSpace {
+		return "", ErrRankExhausted
+	}
+	return formatRank(n), nil
+}
+
+// RankSequence 等間隔に並んだ count 個の固定長ランクを生成します（列の振り直しに使う）
+func RankSequence(count int) []string {
+	step := rankSpace / (count + 1)
+	if step < 1 {
+		step = 1
+	}
+	ranks := make([]string, count)
+	for i := range ranks {
+		ranks[i] = formatRank((i + 1) * step)
+	}
+	return ranks
+}
+
+// rankValue ランクの先頭 rankWidth 桁を数値にする（足りない桁は最小桁とみなす）
+func rankValue(r string) int {
+	n := 0
+	for i := 0; i < rankWidth; i++ {
+		n = n*len(rankDigits) + strings.IndexByte(rankDigits, rankDigitAt(r, i))
+	}
+	return n
+}
+
+// formatRank 数値を rankWidth 桁のランクにする。末尾の最小桁は順序に影響しないため取り除く
+func formatRank(n int) string {
+	b := make([]byte, rankWidth)
+	for i := rankWidth - 1; i >= 0; i-- {
+		b[i] = rankDigits[n%len(rankDigits)]
+		n /= len(rankDigits)
+	}
+	return strings.TrimRight(string(b), rankDigits[:1])
+}
+
+// RankBetween a と b の間に並ぶランク文字列を生成します
+// a が空の場合は先頭、b が空の場合は末尾を意味します
+func RankBetween(a, b string) (string, error) {
+	if !validRank(a) || !validRank(b) || (a != "" && b != "" && a >= b) {
+		return "", ErrInvalidRank
+	}
+	return rankMidpoint(a, b), nil
+}
+
+// validRank 空文字、または末尾が最小桁でない有効な桁のみで構成されているか
+func validRank(r string) bool {
+	if r == "" {
+		return true
+	}
+	for i := 0; i < len(r); i++ {
+		if strings.IndexByte(rankDigits, r[i]) < 0 {
+			return false
+		}
+	}
+	return r[len(r)-1] != rankDigits[0]
+}
+
+func rankMidpoint(a, b string) string {
+	if b != "" {
+		// 共通の接頭辞を残して残りの部分の中間を求める
+		n := 0
+		for n < len(b) && rankDigitAt(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			rest := ""
+			if n < len(a) {
+				rest = a[n:]
+			}
+			return b[:n] + rankMidpoint(rest, b[n:])
+		}
+	}
+
+	da := 0
+	if a != "" {
+		da = strings.IndexByte(rankDigits, a[0])
+	}
+	db := len(rankDigits)
+	if b != "" {
+		db = strings.IndexByte(rankDigits, b[0])
+	}
+	if db-da > 1 {
+		return string(rankDigits[(da+db)/2])
+	}
+	// 先頭桁が隣接している場合は桁を増やす
+	if b != "" && len(b) > 1 {
+		return b[:1]
+	}
+	rest := ""
+	if a != "" {
+		rest = a[1:]
+	}
+	return string(rankDigits[da]) + rankMidpoint(rest, "")
+}
+
+func rankDigitAt(r string, i int) byte {
+	if i < len(r) {
+		return r[i]
+	}
+	return rankDigits[0]
+}
@@ -0,0 +1,61 @@
+package utils
+
+import "testing"
+
+func TestRankBetween_Order(t *testing.T) {
+    cases := [][2]string{{"", ""}, {"", "i"}, {"i", ""}, {"a", "b"}, {"a", "a1"}, {"az", "b"}, {"1", "2"}, {"zz", ""}}
+    for _, tc := range cases {
+        r, err := RankBetween(tc[0], tc[1])
+        if err != nil { t.Fatalf("RankBetween(%q, %q): %v", tc[0], tc[1], err) }
+        if (tc[0] != "" && r <= tc[0]) || (tc[1] != "" && r >= tc[1]) {
+            t.Fatalf("RankBetween(%q, %q) = %q is out of order", tc[0], tc[1], r)
+        }
+    }
+}
+
+func TestRankBetween_RepeatedInsertions(t *testing.T) {
+    // 同じ位置への挿入を繰り返しても順序が保たれる
+    lo, hi := "", ""
+    for i := 0; i < 200; i++ {
+        r, err := RankBetween(lo, hi)
+        if err != nil { t.Fatal(err) }
+        if (lo != "" && r <= lo) || (hi != "" && r >= hi) { t.Fatalf("out of order at %d: %q %q %q", i, lo, r, hi) }
+        if i%2 == 0 { hi = r } else { lo = r }
+    }
+}
+
+func TestRankBetween_Invalid(t *testing.T) {
+    for _, tc := range [][2]string{{"b", "a"}, {"a", "a"}, {"A", ""}, {"a0", ""}} {
+        if _, err := RankBetween(tc[0], tc[1]); err != ErrInvalidRank {
+            t.Fatalf("expected ErrInvalidRank for %v, got %v", tc, err)
+        }
+    }
+}
+
+func TestRankAfter_StaysShort(t *testing.T) {
+    // 末尾への追加を繰り返してもランクは固定長のまま
+    prev := ""
+    for i := 0; i < 5000; i++ {
+        r, err := RankAfter(prev)
+        if err != nil { t.Fatal(err) }
+        if r <= prev { t.Fatalf("out of order at %d: %q %q", i, prev, r) }
+        if len(r) > rankWidth { t.Fatalf("rank grew to %d characters at %d: %q", len(r), i, r) }
+        prev = r
+    }
+
+    // 中間に挿入されて長くなったランクの後ろも固定長に戻る
+    mid, _ := RankBetween("a", "a1")
+    if r, _ := RankAfter(mid); r <= mid || len(r) > rankWidth { t.Fatalf("unexpected rank after %q: %q", mid, r) }
+    // 余地が無ければ振り直しを求める
+    if _, err := RankAfter("zzzzzz"); err != ErrRankExhausted { t.Fatalf("expected ErrRankExhausted, got %v", err) }
+}
+
+func TestRankSequence_EvenlySpaced(t *testing.T) {
+    ranks := RankSequence(3000)
+    for i, r := range ranks {
+        if len(r) > rankWidth || !validRank(r) { t.Fatalf("invalid rank %q at %d", r, i) }
+        if i > 0 && r <= ranks[i-1] { t.Fatalf("out of order at %d: %q %q", i, ranks[i-1], r) }
+    }
+    // 振り直した後も間に挿入できる
+    if _, err := RankBetween(ranks[0], ranks[1]); err != nil { t.Fatal(err) }
+}
@@ -0,0 +1,278 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RRule RFC 5545 の RRULE のサブセット（FREQ=DAILY/WEEKLY/MONTHLY, INTERVAL, BYDAY, COUNT, UNTIL）
+type RRule struct {
+	Freq     string
+	Interval int
+	ByDay    []WeekdayNum
+	Count    int
+	Until    *time.Time
+}
+
+// WeekdayNum BYDAY の要素（MONTHLY では "2MO" や "-1FR" のように序数を指定できる）
+type WeekdayNum struct {
+	N       int
+	Weekday time.Weekday
+}
+
+var rruleWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// maxRRulePeriods 次回日時を探す際に調べる最大期間数
+const maxRRulePeriods = 1000
+
+// ParseRRule "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE" 形式のルールを解釈します
+func ParseRRule(s string) (*RRule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	r := &RRule{Interval: 1}
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rrule part: %s", part)
+		}
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			r.Freq = strings.ToUpper(value)
+			if r.Freq != "DAILY" && r.Freq != "WEEKLY" && r.Freq != "MONTHLY" {
+				return nil, fmt.Errorf("unsupported FREQ: %s", value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid INTERVAL: %s", value)
+			}
+			r.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid COUNT: %s", value)
+			}
+			r.Count = n
+		case "UNTIL":
+			t, err := parseRRuleTime(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid UNTIL: %s", value)
+			}
+			r.Until = &t
+		case "BYDAY":
+			for _, d := range strings.Split(value, ",") {
+				wd, err := parseWeekdayNum(strings.ToUpper(strings.TrimSpace(d)))
+				if err != nil {
+					return nil, err
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported rrule part: %s", key)
+		}
+	}
+	if r.Freq == "" {
+		return nil, errors.New("rrule requires FREQ")
+	}
+	if r.Count > 0 && r.Until != nil {
+		return nil, errors.New("rrule cannot have both COUNT and UNTIL")
+	}
+	for _, d := range r.ByDay {
+		if d.N != 0 && r.Freq != "MONTHLY" {
+			return nil, errors.New("BYDAY ordinals are only supported with FREQ=MONTHLY")
+		}
+	}
+	return r, nil
+}
+
+func parseRRuleTime(v string) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", v); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("20060102T150405", v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("20060102", v)
+	if err != nil {
+		return t, err
+	}
+	// 日付のみの場合はその日の終わりまでを含める
+	return t.Add(24*time.Hour - time.Second), nil
+}
+
+func parseWeekdayNum(v string) (WeekdayNum, error) {
+	if len(v) < 2 {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY: %s", v)
+	}
+	wd, ok := rruleWeekdays[v[len(v)-2:]]
+	if !ok {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY: %s", v)
+	}
+	n := 0
+	if prefix := v[:len(v)-2]; prefix != "" {
+		var err error
+		n, err = strconv.Atoi(prefix)
+		if err != nil || n == 0 || n > 5 || n < -5 {
+			return WeekdayNum{}, fmt.Errorf("invalid BYDAY: %s", v)
+		}
+	}
+	return WeekdayNum{N: n, Weekday: wd}, nil
+}
+
+// String ルールを RRULE 形式の文字列に戻します
+func (r *RRule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, d := range r.ByDay {
+			days[i] = strings.ToUpper(d.Weekday.String()[:2])
+			if d.N != 0 {
+				days[i] = strconv.Itoa(d.N) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next dtstart を起点とするシリーズで、after より後の最初の発生日時を返します
+// index は after の発生回数（1始まり）で、COUNT の判定に使います
+func (r *RRule) Next(dtstart, after time.Time, index int) (time.Time, bool) {
+	if r.Count > 0 && index >= r.Count {
+		return time.Time{}, false
+	}
+	// 起点が古いシリーズでも上限に達しないよう、after より前の期間は読み飛ばす
+	first := r.periodsBefore(dtstart, after)
+	for period := first; period < first+maxRRulePeriods; period++ {
+		for _, t := range r.periodOccurrences(dtstart, period) {
+			if t.Before(dtstart) || !t.After(after) {
+				continue
+			}
+			if r.Until != nil && t.After(*r.Until) {
+				return time.Time{}, false
+			}
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// periodsBefore after より前に終わっていることが確実な期間の数を返します
+func (r *RRule) periodsBefore(dtstart, after time.Time) int {
+	var n int
+	switch r.Freq {
+	case "DAILY":
+		n = int(after.Sub(dtstart).Hours()/24) / r.Interval
+	case "WEEKLY":
+		n = int(after.Sub(dtstart).Hours()/24/7) / r.Interval
+	case "MONTHLY":
+		n = ((after.Year()-dtstart.Year())*12 + int(after.Month()-dtstart.Month())) / r.Interval
+	}
+	// 夏時間などによる端数のずれに備えて1期間手前から調べる
+	if n--; n < 0 {
+		return 0
+	}
+	return n
+}
+
+// periodOccurrences period 番目の期間（日・週・月）に含まれる発生日時を昇順で返します
+func (r *RRule) periodOccurrences(dtstart time.Time, period int) []time.Time {
+	h, m, s := dtstart.Clock()
+	at := func(y int, mo time.Month, d int) time.Time {
+		return time.Date(y, mo, d, h, m, s, dtstart.Nanosecond(), dtstart.Location())
+	}
+
+	var out []time.Time
+	switch r.Freq {
+	case "DAILY":
+		t := dtstart.AddDate(0, 0, period*r.Interval)
+		if r.matchesWeekday(t.Weekday()) {
+			out = append(out, t)
+		}
+	case "WEEKLY":
+		// 週は月曜始まり（RFC 5545 の WKST=MO）
+		offset := (int(dtstart.Weekday()) + 6) % 7
+		monday := dtstart.AddDate(0, 0, -offset+7*period*r.Interval)
+		for i := 0; i < 7; i++ {
+			t := at(monday.Year(), monday.Month(), monday.Day()+i)
+			// BYDAY が無い場合は開始日と同じ曜日
+			if len(r.ByDay) == 0 && t.Weekday() != dtstart.Weekday() {
+				continue
+			}
+			if r.matchesWeekday(t.Weekday()) {
+				out = append(out, t)
+			}
+		}
+	case "MONTHLY":
+		first := time.Date(dtstart.Year(), dtstart.Month()+time.Month(period*r.Interval), 1, 0, 0, 0, 0, dtstart.Location())
+		y, mo := first.Year(), first.Month()
+		days := daysIn(y, mo, dtstart.Location())
+		if len(r.ByDay) == 0 {
+			// 月末を超える日付の月はスキップする
+			if dtstart.Day() <= days {
+				out = append(out, at(y, mo, dtstart.Day()))
+			}
+			break
+		}
+		for _, wd := range r.ByDay {
+			for _, d := range monthWeekdays(y, mo, days, wd) {
+				out = append(out, at(y, mo, d))
+			}
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].Before(out[j]) })
+	}
+	return out
+}
+
+func (r *RRule) matchesWeekday(wd time.Weekday) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, d := range r.ByDay {
+		if d.Weekday == wd {
+			return true
+		}
+	}
+	return false
+}
+
+func daysIn(y int, mo time.Month, loc *time.Location) int {
+	return time.Date(y, mo+1, 0, 0, 0, 0, 0, loc).Day()
+}
+
+// monthWeekdays 月内で wd に該当する日を返します（序数指定時は該当する1日のみ）
+func monthWeekdays(y int, mo time.Month, days int, wd WeekdayNum) []int {
+	var all []int
+	for d := 1; d <= days; d++ {
+		if time.Date(y, mo, d, 0, 0, 0, 0, time.UTC).Weekday() == wd.Weekday {
+			all = append(all, d)
+		}
+	}
+	switch {
+	case wd.N == 0:
+		return all
+	case wd.N > 0 && wd.N <= len(all):
+		return []int{all[wd.N-1]}
+	case wd.N < 0 && -wd.N <= len(all):
+		return []int{all[len(all)+wd.N]}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package utils
+
+import (
+    "testing"
+    "time"
+)
+
+func nextN(t *testing.T, rule string, dtstart time.Time, n int) []string {
+    t.Helper()
+    r, err := ParseRRule(rule)
+    if err != nil { t.Fatalf("ParseRRule(%q): %v", rule, err) }
+    var out []string
+    current := dtstart
+    for i := 1; i <= n; i++ {
+        next, ok := r.Next(dtstart, current, i)
+        if !ok { break }
+        out = append(out, next.Format("2006-01-02 Mon"))
+        current = next
+    }
+    return out
+}
+
+func TestRRule_Daily(t *testing.T) {
+    start := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+    got := nextN(t, "FREQ=DAILY;INTERVAL=2;COUNT=3", start, 5)
+    if len(got) != 2 || got[0] != "2025-01-03 Fri" || got[1] != "2025-01-05 Sun" { t.Fatalf("unexpected: %v", got) }
+}
+
+func TestRRule_WeeklyByDay(t *testing.T) {
+    // 2025-01-06 は月曜日
+    start := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+    got := nextN(t, "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH", start, 3)
+    want := []string{"2025-01-09 Thu", "2025-01-20 Mon", "2025-01-23 Thu"}
+    for i := range want {
+        if i >= len(got) || got[i] != want[i] { t.Fatalf("unexpected: %v", got) }
+    }
+}
+
+func TestRRule_MonthlyOrdinalAndUntil(t *testing.T) {
+    start := time.Date(2025, 1, 31, 9, 0, 0, 0, time.UTC)
+    // 31日の無い月はスキップ
+    got := nextN(t, "FREQ=MONTHLY;UNTIL=20250601", start, 5)
+    if len(got) != 2 || got[0] != "2025-03-31 Mon" || got[1] != "2025-05-31 Sat" { t.Fatalf("unexpected: %v", got) }
+
+    got = nextN(t, "FREQ=MONTHLY;BYDAY=-1FR", time.Date(2025, 1, 31, 9, 0, 0, 0, time.UTC), 2)
+    if len(got) != 2 || got[0] != "2025-02-28 Fri" || got[1] != "2025-03-28 Fri" { t.Fatalf("unexpected: %v", got) }
+}
+
+func TestRRule_OldSeriesAndTimeZone(t *testing.T) {
+    // 何年も前に始まったシリーズでも次回を求められる
+    r, _ := ParseRRule("FREQ=DAILY")
+    start := time.Date(2020, 1, 1, 9, 0, 0, 0, time.UTC)
+    after := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+    if next, ok := r.Next(start, after, 2000); !ok || !next.Equal(after.AddDate(0, 0, 1)) { t.Fatalf("unexpected next: %v %v", next, ok) }
+
+    // 東京の月曜 8:00 は UTC では日曜なので、ルールは起点のタイムゾーンで展開する
+    tokyo, err := time.LoadLocation("Asia/Tokyo")
+    if err != nil { t.Skip("time zone data not available") }
+    r, _ = ParseRRule("FREQ=WEEKLY;BYDAY=MO")
+    monday := time.Date(2026, 3, 2, 8, 0, 0, 0, tokyo)
+    next, ok := r.Next(monday, monday, 1)
+    if !ok || next.Weekday() != time.Monday || !next.Equal(monday.AddDate(0, 0, 7)) { t.Fatalf("unexpected next: %v", next) }
+}
+
+func TestParseRRule_Invalid(t *testing.T) {
+    for _, rule := range []string{"", "FREQ=YEARLY", "FREQ=DAILY;INTERVAL=0", "FREQ=WEEKLY;BYDAY=2MO", "FREQ=DAILY;COUNT=2;UNTIL=20250101", "FREQ=DAILY;BYHOUR=9"} {
+        if _, err := ParseRRule(rule); err == nil { t.Fatalf("expected error for %q", rule) }
+    }
+    r, err := ParseRRule("RRULE:FREQ=MONTHLY;BYDAY=1MO,-1FR;COUNT=4")
+    if err != nil { t.Fatal(err) }
+    if r.String() != "FREQ=MONTHLY;BYDAY=1MO,-1FR;COUNT=4" { t.Fatalf("unexpected String(): %s", r.String()) }
+}